@@ -0,0 +1,101 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"testing"
+
+	"github.com/enceve/crypto/chacha20"
+	"github.com/enceve/crypto/chacha20/xchacha20poly1305"
+	"github.com/enceve/crypto/cipher"
+)
+
+// The message sizes of BenchmarkAEADModes
+var aeadModeSizes = []struct {
+	name string
+	size int
+}{
+	{"64B", 64}, {"1KB", 1024}, {"16KB", 16 * 1024}, {"1MB", 1024 * 1024},
+}
+
+type aeadMode struct {
+	name string
+	aead gocipher.AEAD
+}
+
+// aeadModes returns all cipher.AEAD implementations of this repository
+// and AES-GCM of the standard library as reference.
+func aeadModes(t testing.TB) []aeadMode {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	gcm, err := gocipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create AES-128-GCM instance: %s", err)
+	}
+	eax, err := cipher.NewEAX(block, block.BlockSize())
+	if err != nil {
+		t.Fatalf("Failed to create AES-128-EAX instance: %s", err)
+	}
+	var key [32]byte
+	xchacha, err := xchacha20poly1305.New(key[:])
+	if err != nil {
+		t.Fatalf("Failed to create XChaCha20-Poly1305 instance: %s", err)
+	}
+	return []aeadMode{
+		{"AES-128-GCM", gcm},
+		{"AES-128-EAX", eax},
+		{"ChaCha20-Poly1305", chacha20.NewChaCha20Poly1305(&key)},
+		{"XChaCha20-Poly1305", xchacha},
+	}
+}
+
+func TestAEADModes(t *testing.T) {
+	data := []byte("additional data")
+	for _, m := range aeadModes(t) {
+		nonce := make([]byte, m.aead.NonceSize())
+		for _, s := range aeadModeSizes {
+			msg := make([]byte, s.size)
+			for i := range msg {
+				msg[i] = byte(i)
+			}
+			ciphertext := m.aead.Seal(nil, nonce, msg, data)
+			if len(ciphertext) != s.size+m.aead.Overhead() {
+				t.Fatalf("%s - %s: Seal returned %d bytes - but expected %d", m.name, s.name, len(ciphertext), s.size+m.aead.Overhead())
+			}
+			plaintext, err := m.aead.Open(nil, nonce, ciphertext, data)
+			if err != nil {
+				t.Fatalf("%s - %s: Open failed: %s", m.name, s.name, err)
+			}
+			if !bytes.Equal(plaintext, msg) {
+				t.Fatalf("%s - %s: Open returned a wrong plaintext", m.name, s.name)
+			}
+		}
+	}
+}
+
+// BenchmarkAEADModes compares the Seal performance of all AEADs
+// for the same message sizes - e.g.:
+//
+//	go test -run NONE -bench AEADModes
+func BenchmarkAEADModes(b *testing.B) {
+	data := make([]byte, 13)
+	for _, m := range aeadModes(b) {
+		nonce := make([]byte, m.aead.NonceSize())
+		for _, s := range aeadModeSizes {
+			msg := make([]byte, s.size)
+			dst := make([]byte, s.size+m.aead.Overhead())
+			b.Run(m.name+"/"+s.name, func(b *testing.B) {
+				b.SetBytes(int64(len(msg)))
+				for i := 0; i < b.N; i++ {
+					dst = m.aead.Seal(dst[:0], nonce, msg, data)
+				}
+			})
+		}
+	}
+}
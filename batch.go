@@ -0,0 +1,74 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto
+
+import (
+	"crypto/cipher"
+	"runtime"
+	"sync"
+)
+
+// OpenItem is one ciphertext of a batch opened by OpenBatch.
+type OpenItem struct {
+	Nonce          []byte
+	Ciphertext     []byte
+	AdditionalData []byte
+}
+
+// OpenResult is the result of opening one OpenItem. Err is the error
+// returned by the Open function of the AEAD - or nil and Plaintext holds
+// the plaintext of the item.
+type OpenResult struct {
+	Plaintext []byte
+	Err       error
+}
+
+// OpenBatch opens all items with the AEAD and returns the results in the
+// order of the items. A failing item does not affect the other items - its
+// error is returned in its OpenResult.
+// If the AEAD has a Clone method (like crypto/cipher.EAX)
+//
+//	Clone() cipher.AEAD
+//
+// the items are opened concurrently by up to GOMAXPROCS goroutines, each
+// using its own clone of the AEAD. Otherwise the items are opened one after
+// another, because a cipher.AEAD is not necessarily safe for concurrent use.
+// The AEAD must not be used by other goroutines while OpenBatch runs.
+func OpenBatch(aead cipher.AEAD, items []OpenItem) []OpenResult {
+	results := make([]OpenResult, len(items))
+
+	workers := runtime.GOMAXPROCS(0)
+	if workers > len(items) {
+		workers = len(items)
+	}
+	c, ok := aead.(interface {
+		Clone() cipher.AEAD
+	})
+	if !ok || workers < 2 {
+		openItems(aead, items, results)
+		return results
+	}
+
+	var wg sync.WaitGroup
+	chunk := (len(items) + workers - 1) / workers
+	for i := 0; i < len(items); i += chunk {
+		j := i + chunk
+		if j > len(items) {
+			j = len(items)
+		}
+		wg.Add(1)
+		go func(aead cipher.AEAD, items []OpenItem, results []OpenResult) {
+			defer wg.Done()
+			openItems(aead, items, results)
+		}(c.Clone(), items[i:j], results[i:j])
+	}
+	wg.Wait()
+	return results
+}
+
+func openItems(aead cipher.AEAD, items []OpenItem, results []OpenResult) {
+	for i, item := range items {
+		results[i].Plaintext, results[i].Err = aead.Open(nil, item.Nonce, item.Ciphertext, item.AdditionalData)
+	}
+}
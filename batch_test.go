@@ -0,0 +1,71 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"encoding/binary"
+	"runtime"
+	"testing"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/chacha20"
+	"github.com/enceve/crypto/cipher"
+)
+
+func TestOpenBatch(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	eax, err := cipher.NewEAX(block, block.BlockSize())
+	if err != nil {
+		t.Fatalf("Failed to create AES-128-EAX instance: %s", err)
+	}
+	var key [32]byte
+	defer runtime.GOMAXPROCS(runtime.GOMAXPROCS(4))
+
+	for name, aead := range map[string]gocipher.AEAD{
+		"EAX":              eax,
+		"ChaCha20Poly1305": chacha20.NewChaCha20Poly1305(&key),
+	} {
+		items := make([]crypto.OpenItem, 100)
+		for i := range items {
+			nonce := make([]byte, aead.NonceSize())
+			binary.BigEndian.PutUint32(nonce, uint32(i))
+			data := []byte{byte(i)}
+			items[i] = crypto.OpenItem{
+				Nonce:          nonce,
+				Ciphertext:     aead.Seal(nil, nonce, make([]byte, i), data),
+				AdditionalData: data,
+			}
+		}
+		items[7].Ciphertext[0] ^= 1
+		items[8].AdditionalData = nil
+		items[9].Nonce = items[9].Nonce[1:]
+
+		results := crypto.OpenBatch(aead, items)
+		if len(results) != len(items) {
+			t.Fatalf("%s: OpenBatch returned %d results - but expected %d", name, len(results), len(items))
+		}
+		for i, r := range results {
+			plaintext, err := aead.Open(nil, items[i].Nonce, items[i].Ciphertext, items[i].AdditionalData)
+			if (err == nil) != (r.Err == nil) {
+				t.Fatalf("%s: Item %d: OpenBatch returned %v - but Open returned %v", name, i, r.Err, err)
+			}
+			if !bytes.Equal(r.Plaintext, plaintext) {
+				t.Fatalf("%s: Item %d: OpenBatch returned %x - but Open returned %x", name, i, r.Plaintext, plaintext)
+			}
+			if bad := i == 7 || i == 8 || i == 9; bad != (r.Err != nil) {
+				t.Fatalf("%s: Item %d: OpenBatch returned %v", name, i, r.Err)
+			}
+		}
+
+		if results = crypto.OpenBatch(aead, nil); len(results) != 0 {
+			t.Fatalf("%s: OpenBatch returned %d results for no items", name, len(results))
+		}
+	}
+}
@@ -0,0 +1,44 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto
+
+import (
+	"crypto/cipher"
+
+	"github.com/enceve/crypto/internal/cpu"
+)
+
+// BestAEAD returns the fastest AEAD for the current CPU and its name:
+// AES-256-EAX if the CPU implements AES in hardware and ChaCha20-Poly1305
+// otherwise. The AEADs are created through the DefaultRegistry, so the
+// crypto/cipher and crypto/chacha20 packages must be imported:
+//
+//	import (
+//		_ "github.com/enceve/crypto/chacha20"
+//		_ "github.com/enceve/crypto/cipher"
+//	)
+//
+// The key must be 32 bytes long. Notice that the selected AEAD depends on
+// the CPU, so both sides of a connection must agree on the AEAD - e.g. by
+// sending the registry ID. This function returns a non-nil error if the
+// key size is invalid or the selected AEAD is not registered.
+func BestAEAD(key []byte) (cipher.AEAD, string, error) {
+	return bestAEAD(key, cpu.HasAES)
+}
+
+func bestAEAD(key []byte, hasAES bool) (cipher.AEAD, string, error) {
+	if len(key) != 32 {
+		return nil, "", KeySizeError(len(key))
+	}
+	id := ChaCha20Poly1305
+	if hasAES {
+		id = AESEAX
+	}
+	aead, err := DefaultRegistry.New(id, key)
+	if err != nil {
+		return nil, "", err
+	}
+	name, _ := DefaultRegistry.Name(id)
+	return aead, name, nil
+}
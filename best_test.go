@@ -0,0 +1,55 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/enceve/crypto"
+	_ "github.com/enceve/crypto/chacha20"
+	_ "github.com/enceve/crypto/cipher"
+)
+
+func TestBestAEAD(t *testing.T) {
+	key := make([]byte, 32)
+	aead, name, err := crypto.BestAEAD(key)
+	if err != nil {
+		t.Fatalf("BestAEAD failed: %s", err)
+	}
+	if name != "AES-EAX" && name != "ChaCha20-Poly1305" {
+		t.Fatalf("BestAEAD returned unexpected AEAD %q", name)
+	}
+	t.Logf("BestAEAD selected %s", name)
+
+	for _, v := range []struct {
+		hasAES bool
+		name   string
+	}{
+		{true, "AES-EAX"},
+		{false, "ChaCha20-Poly1305"},
+	} {
+		aead, name, err = crypto.BestAEADFor(key, v.hasAES)
+		if err != nil {
+			t.Fatalf("%s: BestAEAD failed: %s", v.name, err)
+		}
+		if name != v.name {
+			t.Fatalf("BestAEAD returned %q - but expected %q", name, v.name)
+		}
+
+		nonce, msg, data := make([]byte, aead.NonceSize()), []byte("best AEAD"), []byte("data")
+		ciphertext := aead.Seal(nil, nonce, msg, data)
+		plaintext, err := aead.Open(nil, nonce, ciphertext, data)
+		if err != nil {
+			t.Fatalf("%s: Open failed: %s", name, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("%s: Open returned %q - but expected %q", name, plaintext, msg)
+		}
+
+		if _, _, err = crypto.BestAEADFor(key[:16], v.hasAES); err == nil {
+			t.Fatalf("%s: BestAEAD accepted a 16 byte key", name)
+		}
+	}
+}
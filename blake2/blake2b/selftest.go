@@ -0,0 +1,32 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package blake2b
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+func init() {
+	crypto.RegisterSelfTest("BLAKE2b", selfTest)
+}
+
+// selfTest checks BLAKE2b-512 against the
+// test vector from RFC 7693 (appendix A).
+func selfTest() error {
+	hash, _ := hex.DecodeString("BA80A53F981C4D0D6A2797B69F12F6E94C212F14685AC4B74B12BB6FDBFFA2D1" +
+		"7D87C5392AAB792DC252D5DE4533CC9518D38AA8DBF1925AB92386EDD4009923")
+
+	sum, err := Sum([]byte("abc"), Size, nil)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sum, hash) {
+		return errors.New("unexpected hash")
+	}
+	return nil
+}
@@ -32,7 +32,7 @@ const (
 type Config struct {
 	Key      []byte // The key for MAC (length must between 0 and 32)
 	Salt     []byte // The salt (length must between 0 and 8)
-	Personal []byte // The personalization for unique hashing (length must between 0 and 16)
+	Personal []byte // The personalization for unique hashing (length must between 0 and 8)
 }
 
 // Configure takes the hash size and the BLAKE2s configuration and
@@ -0,0 +1,31 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package blake2s
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+func init() {
+	crypto.RegisterSelfTest("BLAKE2s", selfTest)
+}
+
+// selfTest checks BLAKE2s-256 against the
+// test vector from RFC 7693 (appendix B).
+func selfTest() error {
+	hash, _ := hex.DecodeString("508c5e8c327c14e2e1a72ba34eeb452f37458b209ed63a294d999b4c86675982")
+
+	sum, err := Sum([]byte("abc"), Size, nil)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sum, hash) {
+		return errors.New("unexpected hash")
+	}
+	return nil
+}
@@ -22,6 +22,13 @@ var vectors = []struct {
 	conf      *Config
 	msg, hash string
 }{
+	// Test vector from https://tools.ietf.org/html/rfc7693#appendix-B
+	{
+		hashsize: 32,
+		conf:     nil,
+		msg:      hex.EncodeToString([]byte("abc")),
+		hash:     "508c5e8c327c14e2e1a72ba34eeb452f37458b209ed63a294d999b4c86675982",
+	},
 	// Test vectors from https://blake2.net/blake2s-test.txt
 	{
 		hashsize: 32,
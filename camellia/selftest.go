@@ -0,0 +1,39 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package camellia
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+func init() {
+	crypto.RegisterSelfTest("Camellia", selfTest)
+}
+
+// selfTest checks Camellia-128 against the
+// test vector from RFC 3713 (appendix A).
+func selfTest() error {
+	key, _ := hex.DecodeString("0123456789abcdeffedcba9876543210")
+	plaintext, _ := hex.DecodeString("0123456789abcdeffedcba9876543210")
+	ciphertext, _ := hex.DecodeString("67673138549669730857065648eabe43")
+
+	c, err := NewCipher(key)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, BlockSize)
+	c.Encrypt(buf, plaintext)
+	if !bytes.Equal(buf, ciphertext) {
+		return errors.New("unexpected ciphertext")
+	}
+	c.Decrypt(buf, buf)
+	if !bytes.Equal(buf, plaintext) {
+		return errors.New("unexpected plaintext")
+	}
+	return nil
+}
@@ -0,0 +1,91 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package chacha20
+
+import (
+	"crypto/subtle"
+	"io"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/chacha20/chacha"
+	"github.com/enceve/crypto/poly1305"
+)
+
+// SealWithADReader works like the Seal function of the ChaCha20Poly1305 AEAD
+// (with a 128 bit tag) but reads the additional data from the io.Reader, so
+// large additional data is authenticated without buffering it. The additional
+// data is read until io.EOF and streamed into Poly1305 - the result is equal
+// to Seal with the same additional data as slice. (RFC 7539)
+// This function returns a non-nil error if the nonce size is invalid or
+// reading the additional data fails.
+func SealWithADReader(key *[32]byte, dst, nonce, plaintext []byte, additionalData io.Reader) ([]byte, error) {
+	if n := len(nonce); n != NonceSize {
+		return nil, crypto.NonceSizeMismatchError{Size: n, Expected: NonceSize}
+	}
+	var Nonce [NonceSize]byte
+	copy(Nonce[:], nonce)
+
+	poly, adLen, err := authenticateReader(additionalData, &Nonce, key)
+	if err != nil {
+		return nil, err
+	}
+
+	n := len(plaintext)
+	ret, out := sliceForAppend(dst, n+TagSize)
+	chacha.XORKeyStream(out, plaintext, &Nonce, key, 1, 20)
+
+	var tag [TagSize]byte
+	finishAuthentication(&tag, poly, out[:n], adLen)
+	copy(out[n:], tag[:])
+	return ret, nil
+}
+
+// OpenWithADReader works like the Open function of the ChaCha20Poly1305 AEAD
+// (with a 128 bit tag) but reads the additional data from the io.Reader.
+// See SealWithADReader for details.
+// This function returns a non-nil error if the nonce size is invalid, reading
+// the additional data fails or the authentication fails.
+func OpenWithADReader(key *[32]byte, dst, nonce, ciphertext []byte, additionalData io.Reader) ([]byte, error) {
+	if n := len(nonce); n != NonceSize {
+		return nil, crypto.NonceSizeMismatchError{Size: n, Expected: NonceSize}
+	}
+	if len(ciphertext) < TagSize {
+		return nil, crypto.AuthenticationError{Algorithm: "ChaCha20-Poly1305"}
+	}
+	var Nonce [NonceSize]byte
+	copy(Nonce[:], nonce)
+
+	poly, adLen, err := authenticateReader(additionalData, &Nonce, key)
+	if err != nil {
+		return nil, err
+	}
+
+	hash := ciphertext[len(ciphertext)-TagSize:]
+	ciphertext = ciphertext[:len(ciphertext)-TagSize]
+
+	var tag [TagSize]byte
+	finishAuthentication(&tag, poly, ciphertext, adLen)
+	if subtle.ConstantTimeCompare(tag[:], hash) != 1 {
+		return nil, crypto.AuthenticationError{Algorithm: "ChaCha20-Poly1305"}
+	}
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	chacha.XORKeyStream(out, ciphertext, &Nonce, key, 1, 20)
+	return ret, nil
+}
+
+// authenticateReader creates the poly1305 instance for the nonce and
+// writes the additional data read from r to it. It returns the number
+// of additional data bytes.
+func authenticateReader(r io.Reader, nonce *[NonceSize]byte, key *[32]byte) (*poly1305.Hash, uint64, error) {
+	var polyKey [32]byte
+	chacha.XORKeyStream(polyKey[:], polyKey[:], nonce, key, 0, 20)
+
+	poly := poly1305.New(&polyKey)
+	n, err := io.Copy(poly, r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return poly, uint64(n), nil
+}
@@ -0,0 +1,89 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package chacha20
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"testing"
+	"testing/iotest"
+
+	"github.com/enceve/crypto/internal/testvec"
+)
+
+func TestADReaderVectors(t *testing.T) {
+	for i, v := range aeadTestVectors {
+		if v.tagSize != TagSize {
+			continue
+		}
+		var key [32]byte
+		copy(key[:], testvec.MustDecodeHex(v.key))
+		nonce, msg, data := testvec.MustDecodeHex(v.nonce), testvec.MustDecodeHex(v.msg), testvec.MustDecodeHex(v.data)
+
+		ciphertext, err := SealWithADReader(&key, nil, nonce, msg, bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("Test vector %d: SealWithADReader failed: %s", i, err)
+		}
+		if !bytes.Equal(ciphertext, testvec.MustDecodeHex(v.ciphertext)) {
+			t.Fatalf("Test vector %d: SealWithADReader returned %x - but expected %s", i, ciphertext, v.ciphertext)
+		}
+		plaintext, err := OpenWithADReader(&key, nil, nonce, ciphertext, iotest.OneByteReader(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatalf("Test vector %d: OpenWithADReader failed: %s", i, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("Test vector %d: OpenWithADReader returned %x - but expected %x", i, plaintext, msg)
+		}
+	}
+}
+
+func TestADReader(t *testing.T) {
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	c := NewChaCha20Poly1305(&key)
+	nonce, msg := make([]byte, NonceSize), []byte("message")
+
+	for _, size := range []int{0, 1, 15, 16, 17, 4096, 3*1024*1024 + 7} {
+		data := make([]byte, size)
+		for i := range data {
+			data[i] = byte(i * 7)
+		}
+		expected := c.Seal(nil, nonce, msg, data)
+
+		ciphertext, err := SealWithADReader(&key, nil, nonce, msg, iotest.HalfReader(bytes.NewReader(data)))
+		if err != nil {
+			t.Fatalf("AD size %d: SealWithADReader failed: %s", size, err)
+		}
+		if !bytes.Equal(ciphertext, expected) {
+			t.Fatalf("AD size %d: SealWithADReader and Seal produce different ciphertexts", size)
+		}
+		plaintext, err := OpenWithADReader(&key, nil, nonce, expected, bytes.NewReader(data))
+		if err != nil {
+			t.Fatalf("AD size %d: OpenWithADReader failed: %s", size, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("AD size %d: OpenWithADReader returned %q - but expected %q", size, plaintext, msg)
+		}
+		if size > 0 {
+			if _, err = OpenWithADReader(&key, nil, nonce, expected, bytes.NewReader(data[:size-1])); err == nil {
+				t.Fatalf("AD size %d: OpenWithADReader accepted truncated additional data", size)
+			}
+		}
+	}
+
+	readErr := errors.New("read error")
+	r := io.MultiReader(bytes.NewReader(make([]byte, 100)), iotest.ErrReader(readErr))
+	if _, err := SealWithADReader(&key, nil, nonce, msg, r); err != readErr {
+		t.Fatalf("SealWithADReader returned %v - but expected %v", err, readErr)
+	}
+	if _, err := SealWithADReader(&key, nil, nonce[1:], msg, bytes.NewReader(nil)); err == nil {
+		t.Fatal("SealWithADReader accepted an invalid nonce")
+	}
+	if _, err := OpenWithADReader(&key, nil, nonce, make([]byte, TagSize-1), bytes.NewReader(nil)); err == nil {
+		t.Fatal("OpenWithADReader accepted a truncated tag")
+	}
+}
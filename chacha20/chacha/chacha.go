@@ -5,6 +5,13 @@
 // ChaCha cipher family.
 package chacha
 
+import (
+	"io"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/internal/alias"
+)
+
 var constants = [16]byte{
 	0x65, 0x78, 0x70, 0x61,
 	0x6e, 0x64, 0x20, 0x33,
@@ -20,6 +27,16 @@ type Cipher struct {
 	rounds       int
 }
 
+// NewCipherCounter returns a new *chacha.Cipher like NewCipher, but
+// the keystream starts at the given block counter instead of 0.
+// E.g. ChaCha20-Poly1305 encrypts the plaintext starting at counter 1,
+// because block 0 is used for the Poly1305 key.
+func NewCipherCounter(nonce *[12]byte, key *[32]byte, counter uint32, rounds int) *Cipher {
+	c := NewCipher(nonce, key, rounds)
+	c.SetCounter(counter)
+	return c
+}
+
 // Sets the counter of the cipher.
 // Notice that this function skips the unused
 // keystream of the current 64 byte block.
@@ -30,3 +47,173 @@ func (c *Cipher) SetCounter(ctr uint32) {
 	c.state[51] = byte(ctr >> 24)
 	c.off = 0
 }
+
+// Discard advances the keystream by n bytes without producing
+// any output. Whole keystream blocks are skipped by incrementing
+// the counter - only a partial block at the end is generated.
+// If n is negative this function panics.
+func (c *Cipher) Discard(n int) {
+	if n < 0 {
+		panic("chacha20/chacha: negative discard length")
+	}
+
+	if c.off > 0 {
+		r := len(c.block) - c.off
+		if n < r {
+			c.off += n
+			return
+		}
+		n -= r
+		c.off = 0
+	}
+
+	ctr := uint32(c.state[48]) | uint32(c.state[49])<<8 | uint32(c.state[50])<<16 | uint32(c.state[51])<<24
+	ctr += uint32(n / 64)
+	c.SetCounter(ctr)
+
+	if r := n % 64; r > 0 {
+		Core(&(c.block), &(c.state), c.rounds)
+		c.off = r
+	}
+}
+
+// BlockAt returns the keystream block for the given counter without
+// changing the counter or the keystream position of the cipher - e.g.
+// for random access to the keystream.
+func (c *Cipher) BlockAt(counter uint32) [64]byte {
+	state := c.state
+	state[48] = byte(counter)
+	state[49] = byte(counter >> 8)
+	state[50] = byte(counter >> 16)
+	state[51] = byte(counter >> 24)
+
+	var block [64]byte
+	Core(&block, &state, c.rounds)
+	return block
+}
+
+// Clone returns a copy of the cipher. The clone continues
+// the keystream at the current position of c, but both
+// instances can be used independently.
+func (c *Cipher) Clone() *Cipher {
+	clone := *c
+	return &clone
+}
+
+// RekeyForward replaces the key of the cipher with the first 32 bytes of
+// the next keystream block and resets the counter to 0 ("fast key erasure"
+// like the arc4random RNG). The old key and any buffered keystream are
+// overwritten, so compromising the cipher state after RekeyForward does
+// not reveal the keystream produced before (forward secrecy). The keystream
+// block used for the new key is never returned by XORKeyStream.
+// The nonce and the number of rounds are not changed.
+func (c *Cipher) RekeyForward() {
+	Core(&(c.block), &(c.state), c.rounds)
+	copy(c.state[16:48], c.block[:32])
+	c.SetCounter(0)
+	for i := range c.block {
+		c.block[i] = 0
+	}
+}
+
+// XORKeyStreamTee crypts bytes from src to dst like XORKeyStream and
+// writes the used keystream to keystreamOut, so dst = src XOR keystreamOut.
+// The keystream is generated only once. Src and dst may be the same slice
+// but keystreamOut must not overlap src or dst.
+// If len(dst) < len(src), len(keystreamOut) < len(src) or the buffers
+// overlap invalidly this function panics.
+func (c *Cipher) XORKeyStreamTee(dst, keystreamOut, src []byte) {
+	n := len(src)
+	if len(dst) < n {
+		panic("chacha20/chacha: dst buffer is to small")
+	}
+	if len(keystreamOut) < n {
+		panic("chacha20/chacha: keystream buffer is to small")
+	}
+	keystream := keystreamOut[:n]
+	if alias.AnyOverlap(keystream, src) || alias.AnyOverlap(keystream, dst[:n]) {
+		panic("chacha20/chacha: invalid buffer overlap")
+	}
+	for i := range keystream {
+		keystream[i] = 0
+	}
+	c.XORKeyStream(keystream, keystream)
+	crypto.XOR(dst, src, keystream)
+}
+
+// XORKeyStreamSmall crypts at most 64 bytes from src to dst using one
+// keystream block - a fast path for single-shot messages shorter than one
+// block. XORKeyStreamSmall always starts at the current block counter and
+// discards the unused keystream of the block, so it must NOT be used to
+// continue a message split over several calls - the next call (also of
+// XORKeyStream) starts at the next block. Buffered keystream of a previous
+// XORKeyStream call is skipped.
+// Src and dst may be the same slice but otherwise must not overlap.
+// If len(src) > 64, len(dst) < len(src) or dst and src overlap partially
+// this function panics.
+func (c *Cipher) XORKeyStreamSmall(dst, src []byte) {
+	if len(src) > 64 {
+		panic("chacha20/chacha: src is longer than one block")
+	}
+	if len(dst) < len(src) {
+		panic("chacha20/chacha: dst buffer is to small")
+	}
+	if alias.InexactOverlap(dst[:len(src)], src) {
+		panic("chacha20/chacha: invalid buffer overlap")
+	}
+	Core(&(c.block), &(c.state), c.rounds)
+	crypto.XOR(dst, src, c.block[:])
+	c.off = 0
+}
+
+// KeyStreamReader returns an io.Reader reading the raw keystream
+// of the cipher. Every Read advances the keystream of the cipher,
+// so reading n bytes is equal to XORKeyStream of n zero bytes.
+func (c *Cipher) KeyStreamReader() io.Reader { return keyStream{c} }
+
+// keyStream implements io.Reader for the keystream of a Cipher.
+type keyStream struct{ c *Cipher }
+
+func (r keyStream) Read(p []byte) (int, error) {
+	c, n := r.c, len(p)
+	if c.off > 0 {
+		k := copy(p, c.block[c.off:])
+		if k == n {
+			c.off += k
+			return n, nil
+		}
+		p = p[k:]
+		c.off = 0
+	}
+
+	m := len(p) &^ (64 - 1)
+	Blocks(p[:m], &(c.state), c.rounds)
+	if len(p) > m {
+		Core(&(c.block), &(c.state), c.rounds)
+		c.off = copy(p[m:], c.block[:])
+	}
+	return n, nil
+}
+
+// The zero source of Blocks
+var zeroBlocks [256]byte
+
+// Blocks writes the keystream of len(dst) / 64 full blocks to dst
+// using the state - like XORBlocks with a zero source, but without
+// the need to clear dst first. The blocks are generated 256 bytes
+// per XORBlocks call, so the SIMD implementations process several
+// blocks at once. A partial block at the end of dst is not written.
+// This function increments the counter of state and expects valid
+// values. (no nil ptr etc.)
+func Blocks(dst []byte, state *[64]byte, rounds int) {
+	n := len(dst) &^ (64 - 1)
+	for n > 0 {
+		k := n
+		if k > len(zeroBlocks) {
+			k = len(zeroBlocks)
+		}
+		XORBlocks(dst[:k], zeroBlocks[:k], state, rounds)
+		dst = dst[k:]
+		n -= k
+	}
+}
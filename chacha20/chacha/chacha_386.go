@@ -0,0 +1,50 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// +build 386,!gccgo,!appengine
+
+package chacha
+
+import "github.com/enceve/crypto/internal/cpu"
+
+var useSSE2 = cpu.HasSSE2
+
+// XORBlocks crypts full block ( len(src) - (len(src) mod 64) bytes ) from src to
+// dst using the state. Src and dst may be the same slice
+// but otherwise should not overlap. If len(dst) < len(src) the behavior is undefined.
+// This function increments the counter of state.
+func XORBlocks(dst, src []byte, state *[64]byte, rounds int) {
+	if useSSE2 && !useGeneric {
+		xorBlocksSSE2(dst, src, state, rounds)
+		return
+	}
+	xorBlocksGeneric(dst, src, state, rounds)
+}
+
+// Core generates 64 byte keystream from the given state performing 'rounds' rounds
+// and writes them to dst. This function expects valid values. (no nil ptr etc.)
+// Core increments the counter of the state.
+func Core(dst *[64]byte, state *[64]byte, rounds int) {
+	if useSSE2 && !useGeneric {
+		coreSSE2(dst, state, rounds)
+		return
+	}
+	coreGeneric(dst, state, rounds)
+}
+
+func permute(state *[16]uint32, rounds int) {
+	if useSSE2 && !useGeneric {
+		permuteSSE2(state, rounds)
+		return
+	}
+	permuteGeneric(state, rounds)
+}
+
+//go:noescape
+func xorBlocksSSE2(dst, src []byte, state *[64]byte, rounds int)
+
+//go:noescape
+func coreSSE2(dst *[64]byte, state *[64]byte, rounds int)
+
+//go:noescape
+func permuteSSE2(state *[16]uint32, rounds int)
@@ -0,0 +1,131 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// +build 386,!gccgo,!appengine
+
+// The 386 implementation processes one 64 byte block at a time,
+// because there are only 8 XMM registers.
+
+#define ROTL32(n, v , t) \
+ 	MOVO v, t; \
+	PSLLL $n, t; \
+	PSRLL $(32-n), v; \
+	PXOR t, v
+
+#define HALF_ROUND_64B(v0 , v1 , v2 , v3 , t0) \
+	PADDL v1, v0; \
+	PXOR v0, v3; \
+	ROTL32(16, v3, t0); \
+	PADDL v3, v2; \
+	PXOR v2, v1; \
+	ROTL32(12, v1, t0); \
+	PADDL v1, v0; \
+	PXOR v0, v3; \
+	ROTL32(8, v3, t0); \
+	PADDL v3, v2; \
+	PXOR v2, v1; \
+	ROTL32(7, v1, t0); \
+
+#define ROUND_64B(v0 , v1 , v2 , v3 , t0) \
+	HALF_ROUND_64B(v0, v1, v2, v3, t0); \
+	PSHUFL $57, v1, v1; \
+	PSHUFL $78, v2, v2; \
+	PSHUFL $147, v3, v3; \
+	HALF_ROUND_64B(v0, v1, v2, v3, t0); \
+	PSHUFL $147, v1, v1; \
+	PSHUFL $78, v2, v2; \
+	PSHUFL $57, v3, v3
+
+// ADD_STATE adds the state at s to v0 - v3 using t0 - t3.
+#define ADD_STATE(s, v0 , v1 , v2 , v3 , t0 , t1 , t2 , t3) \
+	MOVOU 0(s), t0; \
+	MOVOU 16(s), t1; \
+	MOVOU 32(s), t2; \
+	MOVOU 48(s), t3; \
+	PADDL t0, v0; \
+	PADDL t1, v1; \
+	PADDL t2, v2; \
+	PADDL t3, v3
+
+#define XOR_64B(dst, src, v0 , v1 , v2 , v3 , t0) \
+	MOVOU 0(src), t0; \
+	PXOR v0, t0; \
+	MOVOU t0, 0(dst); \
+	MOVOU 16(src), t0; \
+	PXOR v1, t0; \
+	MOVOU t0, 16(dst); \
+	MOVOU 32(src), t0; \
+	PXOR v2, t0; \
+	MOVOU t0, 32(dst); \
+	MOVOU 48(src), t0; \
+	PXOR v3, t0; \
+	MOVOU t0, 48(dst)
+
+// func coreSSE2(dst *[64]byte, state *[64]byte, rounds int)
+TEXT ·coreSSE2(SB),4,$0-12
+	MOVL state+4(FP), AX
+	MOVL dst+0(FP), BX
+	MOVL rounds+8(FP), CX
+	MOVOU 0(AX), X0
+	MOVOU 16(AX), X1
+	MOVOU 32(AX), X2
+	MOVOU 48(AX), X3
+	core_loop:
+		ROUND_64B(X0, X1, X2, X3, X4)
+		SUBL $2, CX
+		JA core_loop
+	ADD_STATE(AX, X0, X1, X2, X3, X4, X5, X6, X7)
+	MOVOU X0, 0(BX)
+	MOVOU X1, 16(BX)
+	MOVOU X2, 32(BX)
+	MOVOU X3, 48(BX)
+	ADDL $1, 48(AX)
+	RET
+
+// func xorBlocksSSE2(dst, src []byte, state *[64]byte, rounds int)
+TEXT ·xorBlocksSSE2(SB),4,$0-32
+	MOVL state+24(FP), AX
+	MOVL dst+0(FP), BX
+	MOVL src+12(FP), SI
+	MOVL src_len+16(FP), DX
+	CMPL dst_len+4(FP), DX
+	JB done
+	blocks_loop:
+		CMPL DX, $64
+		JB done
+		MOVOU 0(AX), X0
+		MOVOU 16(AX), X1
+		MOVOU 32(AX), X2
+		MOVOU 48(AX), X3
+		MOVL rounds+28(FP), CX
+		rounds_loop:
+			ROUND_64B(X0, X1, X2, X3, X4)
+			SUBL $2, CX
+			JA rounds_loop
+		ADD_STATE(AX, X0, X1, X2, X3, X4, X5, X6, X7)
+		XOR_64B(BX, SI, X0, X1, X2, X3, X4)
+		ADDL $1, 48(AX)
+		ADDL $64, SI
+		ADDL $64, BX
+		SUBL $64, DX
+		JMP blocks_loop
+	done:
+	RET
+
+// func permuteSSE2(state *[16]uint32, rounds int)
+TEXT ·permuteSSE2(SB),4,$0-8
+	MOVL state+0(FP), AX
+	MOVL rounds+4(FP), CX
+	MOVOU 0(AX), X0
+	MOVOU 16(AX), X1
+	MOVOU 32(AX), X2
+	MOVOU 48(AX), X3
+	permute_loop:
+		ROUND_64B(X0, X1, X2, X3, X4)
+		SUBL $2, CX
+		JA permute_loop
+	MOVOU X0, 0(AX)
+	MOVOU X1, 16(AX)
+	MOVOU X2, 32(AX)
+	MOVOU X3, 48(AX)
+	RET
@@ -0,0 +1,58 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// +build 386,!gccgo,!appengine
+
+package chacha
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSSE2(t *testing.T) {
+	if !useSSE2 {
+		t.Skip("CPU does not support SSE2")
+	}
+	var key [32]byte
+	var nonce [12]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	src := make([]byte, 5*64+13)
+	for i := range src {
+		src[i] = byte(i * 3)
+	}
+
+	for _, rounds := range []int{8, 12, 20} {
+		for _, counter := range []uint32{0, 1, 0xfffffffe} {
+			c0 := NewCipherCounter(&nonce, &key, counter, rounds)
+			c1 := NewCipherCounter(&nonce, &key, counter, rounds)
+
+			dst0, dst1 := make([]byte, len(src)), make([]byte, len(src))
+			xorBlocksSSE2(dst0, src, &(c0.state), rounds)
+			xorBlocksGeneric(dst1, src, &(c1.state), rounds)
+			if !bytes.Equal(dst0, dst1) || c0.state != c1.state {
+				t.Fatalf("ChaCha%d - counter %d: xorBlocksSSE2 differs from the generic implementation", rounds, counter)
+			}
+
+			var block0, block1 [64]byte
+			coreSSE2(&block0, &(c0.state), rounds)
+			coreGeneric(&block1, &(c1.state), rounds)
+			if block0 != block1 || c0.state != c1.state {
+				t.Fatalf("ChaCha%d - counter %d: coreSSE2 differs from the generic implementation", rounds, counter)
+			}
+		}
+
+		var s0, s1 [16]uint32
+		for i := range s0 {
+			s0[i] = uint32(i) * 0x01010101
+		}
+		s1 = s0
+		permuteSSE2(&s0, rounds)
+		permuteGeneric(&s1, rounds)
+		if s0 != s1 {
+			t.Fatalf("ChaCha%d: permuteSSE2 differs from the generic implementation", rounds)
+		}
+	}
+}
@@ -9,20 +9,31 @@ import (
 	"unsafe"
 
 	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/internal/alias"
 )
 
 // XORKeyStream crypts bytes from src to dst using the given key, nonce and counter.
 // The rounds argument specifies the number of rounds (must be even) performed for
 // keystream generation. (Common values are 20, 12 or 8) Src and dst may be the same
-// slice but otherwise should not overlap. If len(dst) < len(src) this function panics.
+// slice but otherwise must not overlap. If len(dst) < len(src) or dst and src
+// overlap partially this function panics.
 func XORKeyStream(dst, src []byte, nonce *[12]byte, key *[32]byte, counter uint32, rounds int) {
 	length := len(src)
 	if len(dst) < length {
 		panic("chacha20/chacha: dst buffer is to small")
 	}
+	if alias.InexactOverlap(dst[:length], src) {
+		panic("chacha20/chacha: invalid buffer overlap")
+	}
 	if rounds <= 0 || rounds%2 != 0 {
 		panic("chacha20/chacha: rounds must be a multiple of 2")
 	}
+	if nonce == nil {
+		panic("chacha20/chacha: nil nonce")
+	}
+	if key == nil {
+		panic("chacha20/chacha: nil key")
+	}
 
 	var state [64]byte
 
@@ -58,6 +69,12 @@ func NewCipher(nonce *[12]byte, key *[32]byte, rounds int) *Cipher {
 	if rounds <= 0 || rounds%2 != 0 {
 		panic("chacha20/chacha: rounds must be a multiply of 2")
 	}
+	if nonce == nil {
+		panic("chacha20/chacha: nil nonce")
+	}
+	if key == nil {
+		panic("chacha20/chacha: nil key")
+	}
 	c := new(Cipher)
 	c.rounds = rounds
 
@@ -79,12 +96,16 @@ func NewCipher(nonce *[12]byte, key *[32]byte, rounds int) *Cipher {
 }
 
 // XORKeyStream crypts bytes from src to dst. Src and dst may be the same slice
-// but otherwise should not overlap. If len(dst) < len(src) the function panics.
+// but otherwise must not overlap. If len(dst) < len(src) or dst and src overlap
+// partially the function panics.
 func (c *Cipher) XORKeyStream(dst, src []byte) {
 	length := len(src)
 	if len(dst) < length {
 		panic("chacha20/chacha: dst buffer is to small")
 	}
+	if alias.InexactOverlap(dst[:length], src) {
+		panic("chacha20/chacha: invalid buffer overlap")
+	}
 
 	if c.off > 0 {
 		n := crypto.XOR(dst, src, c.block[c.off:])
@@ -113,9 +134,51 @@ func (c *Cipher) XORKeyStream(dst, src []byte) {
 // dst using the state. Src and dst may be the same slice but otherwise should not
 // overlap. This function increments the counter of state.
 // If len(src) > len(dst), XORBlocks does nothing.
-func XORBlocks(dst, src []byte, state *[64]byte, rounds int)
+func XORBlocks(dst, src []byte, state *[64]byte, rounds int) {
+	if useGeneric {
+		if len(src) <= len(dst) {
+			xorBlocksGeneric(dst, src, state, rounds)
+		}
+		return
+	}
+	if uintptr(unsafe.Pointer(state))&15 == 0 {
+		xorBlocksSSE2(dst, src, state, rounds)
+		return
+	}
+	// xorBlocksSSE2 requires a 16 byte aligned state
+	var buf [64 + 15]byte
+	s := (*[64]byte)(unsafe.Pointer(&buf[(16-uintptr(unsafe.Pointer(&buf[0]))&15)&15]))
+	*s = *state
+	xorBlocksSSE2(dst, src, s, rounds)
+	*state = *s
+}
 
 // Core generates 64 byte keystream from the given state performing 'rounds' rounds
 // and writes them to dst. This function expects valid values. (no nil ptr etc.)
 // Core increments the counter of state.
-func Core(dst *[64]byte, state *[64]byte, rounds int)
+func Core(dst *[64]byte, state *[64]byte, rounds int) {
+	if useGeneric {
+		coreGeneric(dst, state, rounds)
+		return
+	}
+	coreSSE2(dst, state, rounds)
+}
+
+// permute applies the ChaCha permutation to the state.
+// This function expects valid values. (no nil ptr etc.)
+func permute(state *[16]uint32, rounds int) {
+	if useGeneric {
+		permuteGeneric(state, rounds)
+		return
+	}
+	permuteSSE2(state, rounds)
+}
+
+//go:noescape
+func xorBlocksSSE2(dst, src []byte, state *[64]byte, rounds int)
+
+//go:noescape
+func coreSSE2(dst *[64]byte, state *[64]byte, rounds int)
+
+//go:noescape
+func permuteSSE2(state *[16]uint32, rounds int)
@@ -1,6 +1,8 @@
 // Use of this source code is governed by a license
 // that can be found in the LICENSE file.
 
+// +build amd64,!gccgo,!appengine
+
 #define ROTL32(n, v , t) \
  	MOVO v, t; \
 	PSLLL $n, t; \
@@ -170,16 +172,16 @@
 	PXOR v3, t0; \
 	MOVOU t0, 48+off(dst)
 
-// func Core(dst *[64]byte, state *[16]uint32, rounds int)
-TEXT ·Core(SB),4,$0-24
+// func coreSSE2(dst *[64]byte, state *[64]byte, rounds int)
+TEXT ·coreSSE2(SB),4,$0-24
 	MOVQ state+8(FP), AX
 	MOVQ dst+0(FP), BX
 	MOVQ rounds+16(FP), CX
 	MOVL 48(AX), DI
-	MOVO 0(AX), X0
-	MOVO 16(AX), X1
-	MOVO 32(AX), X2
-	MOVO 48(AX), X3
+	MOVOU 0(AX), X0
+	MOVOU 16(AX), X1
+	MOVOU 32(AX), X2
+	MOVOU 48(AX), X3
 	MOVO X0, X4
 	MOVO X1, X5
 	MOVO X2, X6
@@ -192,21 +194,23 @@ TEXT ·Core(SB),4,$0-24
 	PADDL X5, X1
 	PADDL X6, X2
 	PADDL X7, X3
-	MOVO X0, 0(BX)
-	MOVO X1, 16(BX)
-	MOVO X2, 32(BX)
-	MOVO X3, 48(BX)
+	MOVOU X0, 0(BX)
+	MOVOU X1, 16(BX)
+	MOVOU X2, 32(BX)
+	MOVOU X3, 48(BX)
 	ADDL $1, DI
 	MOVL DI, 48(AX)
 	RET
 
-TEXT ·XORBlocks(SB),4,$0-64
+// func xorBlocksSSE2(dst, src []byte, state *[64]byte, rounds int)
+// The state must be 16 byte aligned.
+TEXT ·xorBlocksSSE2(SB),4,$0-64
 	MOVQ state+48(FP), AX
 	MOVQ dst+0(FP), BX
 	MOVQ src+24(FP), CX
-	MOVQ src+32(FP), DX
+	MOVQ src_len+32(FP), DX
 	MOVQ rounds+56(FP), DI
-	CMPQ dst+8(FP), DX
+	CMPQ dst_len+8(FP), DX
 	JB DONE
 	
 	MOVQ SP, SI
@@ -231,17 +235,17 @@ TEXT ·XORBlocks(SB),4,$0-64
 	MOVO X1, X5
 	MOVO X2, X6
 	MOVO X3, X7
-	PADDQ 0(SP), X7
+	PADDL 0(SP), X7
 	MOVO X0, X8
 	MOVO X1, X9
 	MOVO X2, X10
 	MOVO X7, X11
-	PADDQ 0(SP), X11
+	PADDL 0(SP), X11
 	MOVO X0, X12
 	MOVO X1, X13
 	MOVO X2, X14
 	MOVO X11, X15
-	PADDQ 0(SP), X15
+	PADDL 0(SP), X15
 	MOVQ DI, BP
 	CHACHA_LOOP_256:
 		ROUND_256B(X0, X1, X2, X3, X4, X5, X6, X7, X8, X9, X10, X11, X12, X13, X14, X15, 16(SP))
@@ -254,26 +258,26 @@ TEXT ·XORBlocks(SB),4,$0-64
 	PADDL 48(AX), X3
 	XOR_64B(BX, CX, 0, X0, X1, X2, X3, X12)
 	MOVO 48(AX), X3
-	PADDQ 0(SP), X3
+	PADDL 0(SP), X3
 	PADDL 0(AX), X4
 	PADDL 16(AX), X5
 	PADDL 32(AX), X6
 	PADDL X3, X7
 	XOR_64B(BX, CX, 64, X4, X5, X6, X7, X12)
-	PADDQ 0(SP), X3
+	PADDL 0(SP), X3
 	PADDL 0(AX), X8
 	PADDL 16(AX), X9
 	PADDL 32(AX), X10
 	PADDL X3, X11
 	XOR_64B(BX, CX, 128, X8, X9, X10, X11, X12)
-	PADDQ 0(SP), X3
+	PADDL 0(SP), X3
 	MOVO 16(SP), X12
 	PADDL 0(AX), X12
 	PADDL 16(AX), X13
 	PADDL 32(AX), X14
 	PADDL X3, X15		
 	XOR_64B(BX, CX, 192, X12, X13, X14, X15, X0)		
-	PADDQ 0(SP), X3
+	PADDL 0(SP), X3
 	MOVO X3, 48(AX)
 	ADDQ $256, CX
 	ADDQ $256, BX
@@ -298,7 +302,7 @@ TEXT ·XORBlocks(SB),4,$0-64
 	MOVO X1, X9
 	MOVO X2, X10
 	MOVO X3, X11
-	PADDQ X15, X11
+	PADDL X15, X11
 	MOVQ DI, BP
 	CHACHA_LOOP_128:
 		ROUND_128B(X4, X5, X6, X7, X8, X9, X10, X11, X12)
@@ -309,12 +313,13 @@ TEXT ·XORBlocks(SB),4,$0-64
 	PADDL X2, X6
 	PADDL X3, X7
 	XOR_64B(BX, CX, 0, X4, X5, X6, X7, X12)
-	PADDQ X15, X3
+	PADDL X15, X3
 	PADDL X0, X8
 	PADDL X1, X9
 	PADDL X2, X10
 	PADDL X3, X11
 	XOR_64B(BX, CX, 64, X8, X9, X10, X11, X12)
+	PADDL X15, X3
 	MOVO X3, 48(AX)
 	ADDQ $128, CX
 	ADDQ $128, BX
@@ -341,10 +346,28 @@ TEXT ·XORBlocks(SB),4,$0-64
 	PADDL X2, X6
 	PADDL X3, X7
 	XOR_64B(BX, CX, 0, X4, X5, X6, X7, X8)	
-	PADDQ X15, X3
+	PADDL X15, X3
 	MOVO X3, 48(AX)
 	DONE:
 	PXOR X0, X0
 	MOVO X0, 16(SP)
 	MOVQ SI, SP
 	RET
+
+// func permuteSSE2(state *[16]uint32, rounds int)
+TEXT ·permuteSSE2(SB),4,$0-16
+	MOVQ state+0(FP), AX
+	MOVQ rounds+8(FP), CX
+	MOVOU 0(AX), X0
+	MOVOU 16(AX), X1
+	MOVOU 32(AX), X2
+	MOVOU 48(AX), X3
+	permute_loop:
+		ROUND_64B(X0, X1, X2, X3, X4)
+		SUBQ $2, CX
+		JA permute_loop
+	MOVOU X0, 0(AX)
+	MOVOU X1, 16(AX)
+	MOVOU X2, 32(AX)
+	MOVOU X3, 48(AX)
+	RET
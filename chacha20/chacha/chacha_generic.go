@@ -0,0 +1,21 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// +build !amd64,!386 gccgo appengine
+
+package chacha
+
+// XORBlocks crypts full block ( len(src) - (len(src) mod 64) bytes ) from src to
+// dst using the state. Src and dst may be the same slice
+// but otherwise should not overlap. If len(dst) < len(src) the behavior is undefined.
+// This function increments the counter of state.
+func XORBlocks(dst, src []byte, state *[64]byte, rounds int) {
+	xorBlocksGeneric(dst, src, state, rounds)
+}
+
+// Core generates 64 byte keystream from the given state performing 'rounds' rounds
+// and writes them to dst. This function expects valid values. (no nil ptr etc.)
+// Core increments the counter of the state.
+func Core(dst *[64]byte, state *[64]byte, rounds int) { coreGeneric(dst, state, rounds) }
+
+func permute(state *[16]uint32, rounds int) { permuteGeneric(state, rounds) }
@@ -6,7 +6,11 @@ package chacha
 import (
 	"bytes"
 	"encoding/hex"
+	"io"
 	"testing"
+	"unsafe"
+
+	"github.com/enceve/crypto/internal/testvec"
 )
 
 var recFail = func(t *testing.T, msg string) {
@@ -50,6 +54,29 @@ func TestSetCounter(t *testing.T) {
 	}
 }
 
+func TestNewCipherCounter(t *testing.T) {
+	var key [32]byte
+	var nonce [12]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	for _, ctr := range []uint32{0, 1, 7, 0x01020304, 0xffffffff} {
+		buf0, buf1, buf2 := make([]byte, 150), make([]byte, 150), make([]byte, 150)
+
+		NewCipherCounter(&nonce, &key, ctr, 20).XORKeyStream(buf0, buf0)
+
+		c := NewCipher(&nonce, &key, 20)
+		c.SetCounter(ctr)
+		c.XORKeyStream(buf1, buf1)
+
+		XORKeyStream(buf2, buf2, &nonce, &key, ctr, 20)
+
+		if !bytes.Equal(buf0, buf1) || !bytes.Equal(buf0, buf2) {
+			t.Fatalf("Counter %d: NewCipherCounter differ from NewCipher and SetCounter", ctr)
+		}
+	}
+}
+
 func TestXORKeyStream(t *testing.T) {
 	var key [32]byte
 	var nonce [12]byte
@@ -72,6 +99,34 @@ func TestXORKeyStream(t *testing.T) {
 	}
 }
 
+func TestXORBlocksCounter(t *testing.T) {
+	var key [32]byte
+	var nonce [12]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	// the keystream computed block by block with Core
+	var state [64]byte
+	copy(state[:], constants[:])
+	copy(state[16:], key[:])
+	copy(state[52:], nonce[:])
+	keystream := make([]byte, 9*64)
+	for i := 0; i < len(keystream); i += 64 {
+		var block [64]byte
+		Core(&block, &state, 20)
+		copy(keystream[i:], block[:])
+	}
+
+	for i := 0; i <= len(keystream); i++ {
+		buf := make([]byte, i)
+		XORKeyStream(buf, buf, &nonce, &key, 0, 20)
+		if !bytes.Equal(buf, keystream[:i]) {
+			t.Fatalf("Length %d: XORKeyStream differ from the keystream of Core", i)
+		}
+	}
+}
+
 func TestXORKeyStreamPanic(t *testing.T) {
 	mustFail := func(t *testing.T, msg string, dst, src []byte, nonce *[12]byte, key *[32]byte, counter uint32, rounds int) {
 		defer recFail(t, msg)
@@ -98,3 +153,789 @@ func TestXORKeyStreamPanic(t *testing.T) {
 	mustFail2(t, "len(dst) < len(src)", dst[:len(src)-1], src)
 
 }
+
+func TestKeyStreamReader(t *testing.T) {
+	var key [32]byte
+	var nonce [12]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	buf0, buf1 := make([]byte, 300), make([]byte, 300)
+
+	c := NewCipher(&nonce, &key, 20)
+	c.XORKeyStream(buf0[:7], buf0[:7])
+	r := c.KeyStreamReader()
+	if _, err := io.ReadFull(r, buf0[7:70]); err != nil {
+		t.Fatalf("Failed to read keystream: %s", err)
+	}
+	if _, err := io.ReadFull(r, buf0[70:200]); err != nil {
+		t.Fatalf("Failed to read keystream: %s", err)
+	}
+	c.XORKeyStream(buf0[200:], buf0[200:])
+
+	XORKeyStream(buf1, buf1, &nonce, &key, 0, 20)
+
+	if !bytes.Equal(buf0, buf1) {
+		t.Fatalf("KeyStreamReader differ from chacha.XORKeyStream\n KeyStreamReader: %s \n chacha.XORKeyStream: %s", hex.EncodeToString(buf0), hex.EncodeToString(buf1))
+	}
+}
+
+func TestBlocks(t *testing.T) {
+	var key [32]byte
+	var nonce [12]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	for _, size := range []int{0, 63, 64, 200, 256, 257, 1000, 1024} {
+		c0, c1 := NewCipher(&nonce, &key, 20), NewCipher(&nonce, &key, 20)
+		blocks := make([]byte, size)
+		for i := range blocks {
+			blocks[i] = 0xff
+		}
+		Blocks(blocks, &(c0.state), c0.rounds)
+
+		n := size &^ 63
+		expected := make([]byte, n)
+		for i := 0; i < n; i += 64 {
+			var block [64]byte
+			Core(&block, &(c1.state), c1.rounds)
+			copy(expected[i:], block[:])
+		}
+		if !bytes.Equal(blocks[:n], expected) {
+			t.Fatalf("Size %d: Blocks differ from Core", size)
+		}
+		for _, v := range blocks[n:] {
+			if v != 0xff {
+				t.Fatalf("Size %d: Blocks modified the partial block", size)
+			}
+		}
+		if c0.state != c1.state {
+			t.Fatalf("Size %d: Blocks did not increment the counter", size)
+		}
+	}
+}
+
+func TestBlockAt(t *testing.T) {
+	var key [32]byte
+	var nonce [12]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	c := NewCipher(&nonce, &key, 20)
+	buf := make([]byte, 37)
+	c.XORKeyStream(buf, buf)
+	state, off := c.state, c.off
+
+	for _, counter := range []uint32{0, 1, 7, 0xffffffff} {
+		ref := NewCipherCounter(&nonce, &key, counter, 20)
+		var block [64]byte
+		Core(&block, &(ref.state), 20)
+		if b := c.BlockAt(counter); b != block {
+			t.Fatalf("Counter %d: BlockAt returned %x - but expected %x", counter, b, block)
+		}
+	}
+	if c.state != state || c.off != off {
+		t.Fatal("BlockAt modified the state of the cipher")
+	}
+}
+
+func TestClone(t *testing.T) {
+	var key [32]byte
+	var nonce [12]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	buf0, buf1 := make([]byte, 200), make([]byte, 200)
+
+	c := NewCipher(&nonce, &key, 20)
+	c.XORKeyStream(buf0[:37], buf0[:37])
+	copy(buf1, buf0[:37])
+
+	clone := c.Clone()
+	c.XORKeyStream(buf0[37:], buf0[37:])
+	clone.XORKeyStream(buf1[37:], buf1[37:])
+	if !bytes.Equal(buf0, buf1) {
+		t.Fatalf("Clone does not continue the keystream\n Cipher: %s \n Clone: %s", hex.EncodeToString(buf0), hex.EncodeToString(buf1))
+	}
+
+	// advancing the clone must not affect the original
+	clone.SetCounter(0)
+	clone.XORKeyStream(buf1, buf1)
+	ref := make([]byte, 264)
+	XORKeyStream(ref, ref, &nonce, &key, 0, 20)
+	next := make([]byte, 64)
+	c.XORKeyStream(next, next)
+	if !bytes.Equal(next, ref[200:]) {
+		t.Fatalf("Clone modified the state of the original cipher")
+	}
+}
+
+func TestDiscard(t *testing.T) {
+	var key [32]byte
+	var nonce [12]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	ref := make([]byte, 512)
+	XORKeyStream(ref, ref, &nonce, &key, 0, 20)
+
+	for _, lengths := range [][3]int{
+		{0, 0, 100}, {0, 64, 100}, {0, 100, 100}, {0, 192, 64},
+		{7, 0, 100}, {7, 57, 64}, {7, 50, 100}, {7, 300, 100},
+		{64, 1, 63}, {63, 1, 64}, {100, 200, 1},
+	} {
+		prefix, skip, m := lengths[0], lengths[1], lengths[2]
+		c := NewCipher(&nonce, &key, 20)
+
+		buf := make([]byte, prefix+m)
+		c.XORKeyStream(buf[:prefix], buf[:prefix])
+		c.Discard(skip)
+		c.XORKeyStream(buf[prefix:], buf[prefix:])
+
+		expected := append(append([]byte{}, ref[:prefix]...), ref[prefix+skip:prefix+skip+m]...)
+		if !bytes.Equal(buf, expected) {
+			t.Fatalf("Prefix %d - Discard(%d): XORKeyStream differ from the keystream tail", prefix, skip)
+		}
+	}
+}
+
+func TestXORKeyStreamTee(t *testing.T) {
+	var key [32]byte
+	var nonce [12]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	src := make([]byte, 300)
+	for i := range src {
+		src[i] = byte(i)
+	}
+	ref := make([]byte, len(src))
+	XORKeyStream(ref, src, &nonce, &key, 0, 20)
+
+	c := NewCipher(&nonce, &key, 20)
+	dst, keystream := make([]byte, len(src)), make([]byte, len(src))
+	for _, n := range []int{0, 7, 64, 100, 1} {
+		c.XORKeyStreamTee(dst[:n], keystream[:n], src[:n])
+		for i := range src[:n] {
+			if dst[i] != src[i]^keystream[i] {
+				t.Fatalf("Length %d: dst != src XOR keystream at byte %d", n, i)
+			}
+		}
+		if !bytes.Equal(dst[:n], ref[:n]) {
+			t.Fatalf("Length %d: XORKeyStreamTee differ from chacha.XORKeyStream", n)
+		}
+		src, ref = src[n:], ref[n:]
+	}
+
+	// the counter advances once - the next bytes continue the keystream
+	c.XORKeyStream(dst[:len(src)], src)
+	if !bytes.Equal(dst[:len(src)], ref) {
+		t.Fatal("XORKeyStream does not continue the keystream of XORKeyStreamTee")
+	}
+
+	mustFail := func(msg string, dst, keystream, src []byte) {
+		defer recFail(t, msg)
+		c.XORKeyStreamTee(dst, keystream, src)
+	}
+	mustFail("len(dst) < len(src)", dst[:9], keystream[:10], src[:10])
+	mustFail("len(keystreamOut) < len(src)", dst[:10], keystream[:9], src[:10])
+}
+
+func TestBufferOverlap(t *testing.T) {
+	var key [32]byte
+	var nonce [12]byte
+	buf := make([]byte, 200)
+
+	mustFail := func(msg string, f func()) {
+		defer recFail(t, msg)
+		f()
+	}
+
+	// identical and disjoint buffers are allowed
+	XORKeyStream(buf[:100], buf[:100], &nonce, &key, 0, 20)
+	XORKeyStream(buf[:100], buf[100:], &nonce, &key, 0, 20)
+	XORKeyStream(buf[:150], buf[:100], &nonce, &key, 0, 20)
+	c := NewCipher(&nonce, &key, 20)
+	c.XORKeyStream(buf[:100], buf[:100])
+	c.XORKeyStream(buf[100:], buf[:100])
+	c.XORKeyStreamTee(buf[:50], buf[50:100], buf[:50])
+
+	mustFail("dst and src overlap partially", func() { XORKeyStream(buf[1:101], buf[:100], &nonce, &key, 0, 20) })
+	mustFail("dst and src overlap partially", func() { XORKeyStream(buf[:100], buf[99:199], &nonce, &key, 0, 20) })
+	mustFail("dst and src overlap partially", func() { c.XORKeyStream(buf[1:101], buf[:100]) })
+	mustFail("dst and src overlap partially", func() { c.XORKeyStream(buf[:100], buf[64:164]) })
+	mustFail("keystreamOut overlaps src", func() { c.XORKeyStreamTee(buf[:50], buf[49:99], buf[:50]) })
+	mustFail("keystreamOut overlaps dst", func() { c.XORKeyStreamTee(buf[:50], buf[40:90], buf[100:150]) })
+}
+
+// Test vectors for Permute. The first vector was computed by hand,
+// the second is the state after 20 rounds of the block function test
+// vector from https://tools.ietf.org/html/rfc7539#section-2.3.2
+var permuteVectors = []struct {
+	in, out [16]uint32
+	rounds  int
+}{
+	{
+		in: [16]uint32{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		out: [16]uint32{
+			0xa9295c6a, 0xb471be7a, 0x38e65ec4, 0x74348b87,
+			0xf1ad76c6, 0x7b44627b, 0x946682b0, 0xf12f4910,
+			0xd7e22f80, 0x3232ddd4, 0xb5b3b74d, 0xc0240444,
+			0xc8a5de45, 0xe417e1d3, 0x1a392478, 0x983c4ebf,
+		},
+		rounds: 2,
+	},
+	{
+		in: [16]uint32{
+			0x61707865, 0x3320646e, 0x79622d32, 0x6b206574,
+			0x03020100, 0x07060504, 0x0b0a0908, 0x0f0e0d0c,
+			0x13121110, 0x17161514, 0x1b1a1918, 0x1f1e1d1c,
+			0x00000001, 0x09000000, 0x4a000000, 0x00000000,
+		},
+		out: [16]uint32{
+			0x837778ab, 0xe238d763, 0xa67ae21e, 0x5950bb2f,
+			0xc4f2d0c7, 0xfc62bb2f, 0x8fa018fc, 0x3f5ec7b7,
+			0x335271c2, 0xf29489f3, 0xeabda8fc, 0x82e46ebd,
+			0xd19c12b4, 0xb04e16de, 0x9e83d0cb, 0x4e3c50a2,
+		},
+		rounds: 20,
+	},
+}
+
+func TestPermute(t *testing.T) {
+	for i, v := range permuteVectors {
+		state := v.in
+		Permute(&state, v.rounds)
+		if state != v.out {
+			t.Fatalf("Test vector %d: Permute returned %x - but expected %x", i, state, v.out)
+		}
+		state = v.in
+		permuteGeneric(&state, v.rounds)
+		if state != v.out {
+			t.Fatalf("Test vector %d: permuteGeneric returned %x - but expected %x", i, state, v.out)
+		}
+	}
+
+	// Permute with feed-forward must be equal to Core
+	var state [64]byte
+	for i := range state {
+		state[i] = byte(i * 7)
+	}
+	for _, rounds := range []int{8, 12, 20} {
+		var words [16]uint32
+		for i := range words {
+			words[i] = uint32(state[4*i]) | uint32(state[4*i+1])<<8 | uint32(state[4*i+2])<<16 | uint32(state[4*i+3])<<24
+		}
+		in := words
+		Permute(&words, rounds)
+
+		var block, expected [64]byte
+		for i := range words {
+			w := words[i] + in[i]
+			block[4*i], block[4*i+1], block[4*i+2], block[4*i+3] = byte(w), byte(w>>8), byte(w>>16), byte(w>>24)
+		}
+		s := state
+		Core(&expected, &s, rounds)
+		if block != expected {
+			t.Fatalf("Rounds %d: Permute with feed-forward differ from Core", rounds)
+		}
+	}
+
+	mustFail := func(msg string, rounds int) {
+		defer recFail(t, msg)
+		Permute(new([16]uint32), rounds)
+	}
+	mustFail("rounds is 0", 0)
+	mustFail("rounds is not even", 3)
+}
+
+func TestCoreWords(t *testing.T) {
+	defer UseGeneric(false)
+
+	var state [64]byte
+	for i := range state {
+		state[i] = byte(i * 13)
+	}
+	for _, generic := range []bool{false, true} {
+		UseGeneric(generic)
+		for _, rounds := range []int{2, 8, 12, 20} {
+			var in [16]uint32
+			for i := range in {
+				in[i] = uint32(state[4*i]) | uint32(state[4*i+1])<<8 | uint32(state[4*i+2])<<16 | uint32(state[4*i+3])<<24
+			}
+			original := in
+			var out [16]uint32
+			CoreWords(&out, &in, rounds)
+			if in != original {
+				t.Fatalf("Rounds %d: CoreWords modified the input", rounds)
+			}
+
+			// little-endian serialization must be equal to Core
+			var block, expected [64]byte
+			for i, w := range out {
+				block[4*i], block[4*i+1], block[4*i+2], block[4*i+3] = byte(w), byte(w>>8), byte(w>>16), byte(w>>24)
+			}
+			s := state
+			Core(&expected, &s, rounds)
+			if block != expected {
+				t.Fatalf("Generic %v, rounds %d: CoreWords differs from Core", generic, rounds)
+			}
+
+			CoreWords(&in, &in, rounds)
+			if in != out {
+				t.Fatalf("Rounds %d: CoreWords with out == in returned %x - but expected %x", rounds, in, out)
+			}
+		}
+	}
+
+	mustFail := func(msg string, rounds int) {
+		defer recFail(t, msg)
+		CoreWords(new([16]uint32), new([16]uint32), rounds)
+	}
+	mustFail("rounds is 0", 0)
+	mustFail("rounds is not even", 7)
+}
+
+// Test vectors for the state layout of ChaCha20 (counter in word 12,
+// nonce in words 13 - 15). The first vector is the block function test
+// vector from https://tools.ietf.org/html/rfc7539#section-2.3.2
+var stateVectors = []struct {
+	nonce, keystream string
+	ctr              uint32
+}{
+	{
+		nonce: "000000090000004a00000000",
+		keystream: "10f1e7e4d13b5915500fdd1fa32071c4" +
+			"c7d1f4c733c068030422aa9ac3d46c4e" +
+			"d2826446079faa0914c2d705d98b02a2" +
+			"b5129cd1de164eb9cbd083e8a2503c4e",
+		ctr: 1,
+	},
+	{
+		nonce: "000000090000004a00000000",
+		keystream: "cc671187669bbfc8b3a329e9e50fc255" +
+			"0a26f184be44e7c4d8d43667e6cf7b8c" +
+			"bf06efdce229c731ab68915fc5c37e7a" +
+			"44c3b5a12074fc317791bbdc7534e69d",
+		ctr: 0x01020304,
+	},
+}
+
+func TestStateLayout(t *testing.T) {
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	for i, v := range stateVectors {
+		var nonce [12]byte
+		copy(nonce[:], testvec.MustDecodeHex(v.nonce))
+		keystream := testvec.MustDecodeHex(v.keystream)
+
+		buf := make([]byte, 64)
+		XORKeyStream(buf, buf, &nonce, &key, v.ctr, 20)
+		if !bytes.Equal(buf, keystream) {
+			t.Fatalf("Test vector %d: XORKeyStream produces unexpected keystream:\nFound:    %s\nExpected: %s", i, hex.EncodeToString(buf), v.keystream)
+		}
+
+		buf = make([]byte, 64)
+		c := NewCipher(&nonce, &key, 20)
+		c.SetCounter(v.ctr)
+		c.XORKeyStream(buf, buf)
+		if !bytes.Equal(buf, keystream) {
+			t.Fatalf("Test vector %d: Cipher produces unexpected keystream:\nFound:    %s\nExpected: %s", i, hex.EncodeToString(buf), v.keystream)
+		}
+	}
+}
+
+func TestNilKeyNonce(t *testing.T) {
+	mustPanic := func(t *testing.T, expected string, f func()) {
+		defer func() {
+			if err := recover(); err != expected {
+				t.Fatalf("Expected panic %q - but found: %v", expected, err)
+			}
+		}()
+		f()
+	}
+
+	key := new([32]byte)
+	nonce := new([12]byte)
+	buf := make([]byte, 64)
+
+	mustPanic(t, "chacha20/chacha: nil nonce", func() { XORKeyStream(buf, buf, nil, key, 0, 20) })
+	mustPanic(t, "chacha20/chacha: nil key", func() { XORKeyStream(buf, buf, nonce, nil, 0, 20) })
+	mustPanic(t, "chacha20/chacha: nil nonce", func() { NewCipher(nil, key, 20) })
+	mustPanic(t, "chacha20/chacha: nil key", func() { NewCipher(nonce, nil, 20) })
+}
+
+// Test vectors for HChaCha20. The first vector is from
+// https://tools.ietf.org/html/draft-irtf-cfrg-xchacha-03#section-2.2.1
+var hChaChaVectors = []struct {
+	key, nonce, subKey string
+}{
+	{
+		key:    "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		nonce:  "000000090000004a0000000031415927",
+		subKey: "82413b4227b27bfed30e42508a877d73a0f9e4d58a74a853c12ec41326d3ecdc",
+	},
+}
+
+func TestHChaCha(t *testing.T) {
+	for i, v := range hChaChaVectors {
+		var key, subKey [32]byte
+		var nonce [16]byte
+		copy(key[:], testvec.MustDecodeHex(v.key))
+		copy(nonce[:], testvec.MustDecodeHex(v.nonce))
+
+		HChaCha(&subKey, &nonce, &key, 20)
+		if s := hex.EncodeToString(subKey[:]); s != v.subKey {
+			t.Fatalf("Test vector %d: HChaCha returned %s - but expected %s", i, s, v.subKey)
+		}
+		HChaCha(&key, &nonce, &key, 20)
+		if key != subKey {
+			t.Fatalf("Test vector %d: HChaCha with out = key returned %x - but expected %x", i, key, subKey)
+		}
+	}
+
+	mustFail := func(msg string, rounds int) {
+		defer recFail(t, msg)
+		HChaCha(new([32]byte), new([16]byte), new([32]byte), rounds)
+	}
+	mustFail("rounds is 0", 0)
+	mustFail("rounds is not even", 7)
+}
+
+// Internal test vectors for XXChaCha20 - XXChaCha is not standardized.
+var xxChaChaVectors = []struct {
+	key, nonce, keystream string
+}{
+	{
+		key:   "0000000000000000000000000000000000000000000000000000000000000000",
+		nonce: "0000000000000000000000000000000000000000000000000000000000000000",
+		keystream: "b1893cd66509609e7da7a12c0b68e81fd99db3ed1fb35064ff022f6a01fb426e" +
+			"588f0a75b4ffa7ca16ab47bd81e558e02be422452dc1821a2645897048045aee",
+	},
+	{
+		key:   "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		nonce: "404142434445464748494a4b4c4d4e4f505152535455565758595a5b5c5d5e5f",
+		keystream: "c06774bae9cfa7296d602655688116a61476e7352c1a1457e73f5052401c8273" +
+			"9dd93c8a2ca94de747b404ee91c3c4ef3e81d9846f7cda4bb4572fe23e417b35" +
+			"e0bb9ceee8084568298223ac3a0503a5032a707c91bdc640b5f6497a85fc8a36" +
+			"fd07dd408c9e601fc1d2993179ec9ad9034547f9dad01d8f730c2816504f9ee2",
+	},
+}
+
+func TestXXNewCipher(t *testing.T) {
+	for i, v := range xxChaChaVectors {
+		var key [32]byte
+		var nonce [32]byte
+		copy(key[:], testvec.MustDecodeHex(v.key))
+		copy(nonce[:], testvec.MustDecodeHex(v.nonce))
+		keystream := testvec.MustDecodeHex(v.keystream)
+
+		buf := make([]byte, len(keystream))
+		XXNewCipher(&nonce, &key, 20).XORKeyStream(buf, buf)
+		if !bytes.Equal(buf, keystream) {
+			t.Fatalf("Test vector %d: XXNewCipher produces unexpected keystream:\nFound:    %s\nExpected: %s", i, hex.EncodeToString(buf), v.keystream)
+		}
+
+		// every byte of the nonce must change the keystream
+		for j := range nonce {
+			nonce[j] ^= 1
+			XXNewCipher(&nonce, &key, 20).XORKeyStream(buf[:32], make([]byte, 32))
+			if bytes.Equal(buf[:32], keystream[:32]) {
+				t.Fatalf("Test vector %d: Keystream does not depend on nonce byte %d", i, j)
+			}
+			nonce[j] ^= 1
+		}
+	}
+
+	mustFail := func(msg string, nonce *[32]byte, key *[32]byte, rounds int) {
+		defer recFail(t, msg)
+		XXNewCipher(nonce, key, rounds)
+	}
+	mustFail("nonce is nil", nil, new([32]byte), 20)
+	mustFail("key is nil", new([32]byte), nil, 20)
+	mustFail("rounds is not even", new([32]byte), new([32]byte), 5)
+}
+
+func TestStateWords(t *testing.T) {
+	// The ChaCha state at the end of the ChaCha20 block function
+	// from https://tools.ietf.org/html/rfc7539#section-2.3.2
+	expected := [16]uint32{
+		0xe4e7f110, 0x15593bd1, 0x1fdd0f50, 0xc47120a3,
+		0xc7f4d1c7, 0x0368c033, 0x9aaa2204, 0x4e6cd4c3,
+		0x466482d2, 0x09aa9f07, 0x05d7c214, 0xa2028bd9,
+		0xd19c12b5, 0xb94e16de, 0xe883d0cb, 0x4e3c50a2,
+	}
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	nonce := [12]byte{0x00, 0x00, 0x00, 0x09, 0x00, 0x00, 0x00, 0x4a}
+
+	if words := StateWords(&nonce, &key, 1, 20); words != expected {
+		t.Fatalf("StateWords returned %08x - but expected %08x", words, expected)
+	}
+
+	// the serialized words must be equal to the keystream
+	for _, rounds := range []int{8, 12, 20} {
+		words := StateWords(&nonce, &key, 7, rounds)
+		var block, keystream [64]byte
+		for i, w := range words {
+			putUint32(block[4*i:], w)
+		}
+		XORKeyStream(keystream[:], keystream[:], &nonce, &key, 7, rounds)
+		if block != keystream {
+			t.Fatalf("Rounds %d: StateWords differ from the keystream", rounds)
+		}
+	}
+
+	mustFail := func(msg string, rounds int) {
+		defer recFail(t, msg)
+		StateWords(&nonce, &key, 0, rounds)
+	}
+	mustFail("rounds is 0", 0)
+	mustFail("rounds is not even", 9)
+}
+
+func TestRekeyForward(t *testing.T) {
+	var key [32]byte
+	var nonce [12]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	for _, prefix := range []int{0, 1, 64, 100} {
+		c := NewCipher(&nonce, &key, 20)
+		before := make([]byte, prefix)
+		c.XORKeyStream(before, before)
+		c.RekeyForward()
+
+		// the new key is the next unused keystream block of the old key
+		next := (prefix + 63) / 64
+		ref := make([]byte, (next+1)*64)
+		XORKeyStream(ref, ref, &nonce, &key, 0, 20)
+		var newKey [32]byte
+		copy(newKey[:], ref[next*64:])
+
+		for i := range c.block {
+			if c.block[i] != 0 {
+				t.Fatalf("Prefix %d: RekeyForward did not wipe the buffered keystream", prefix)
+			}
+		}
+		if !bytes.Equal(c.state[16:48], newKey[:]) {
+			t.Fatalf("Prefix %d: RekeyForward set the key %x - but expected %x", prefix, c.state[16:48], newKey)
+		}
+		if bytes.Contains(c.state[:], key[:]) {
+			t.Fatalf("Prefix %d: RekeyForward did not overwrite the old key", prefix)
+		}
+
+		after := make([]byte, 128)
+		c.XORKeyStream(after, after)
+		expected := make([]byte, len(after))
+		XORKeyStream(expected, expected, &nonce, &newKey, 0, 20)
+		if !bytes.Equal(after, expected) {
+			t.Fatalf("Prefix %d: Keystream after RekeyForward differ from the keystream of the new key", prefix)
+		}
+		if bytes.Contains(ref, after[:32]) {
+			t.Fatalf("Prefix %d: Keystream after RekeyForward repeats the old keystream", prefix)
+		}
+	}
+}
+
+func TestRoundConstructors(t *testing.T) {
+	var key [32]byte
+	var nonce [12]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	for i := range nonce {
+		nonce[i] = byte(i * 7)
+	}
+	src := make([]byte, 200)
+	for i := range src {
+		src[i] = byte(i)
+	}
+
+	for _, v := range []struct {
+		rounds    int
+		newCipher func(*[12]byte, *[32]byte) *Cipher
+		xor       func(dst, src []byte, nonce *[12]byte, key *[32]byte, counter uint32)
+	}{
+		{20, NewChaCha20, XORKeyStream20},
+		{12, NewChaCha12, XORKeyStream12},
+		{8, NewChaCha8, XORKeyStream8},
+	} {
+		expected, dst := make([]byte, len(src)), make([]byte, len(src))
+
+		NewCipher(&nonce, &key, v.rounds).XORKeyStream(expected, src)
+		v.newCipher(&nonce, &key).XORKeyStream(dst, src)
+		if !bytes.Equal(dst, expected) {
+			t.Fatalf("ChaCha%d: Cipher differs from NewCipher with %d rounds", v.rounds, v.rounds)
+		}
+
+		XORKeyStream(expected, src, &nonce, &key, 3, v.rounds)
+		v.xor(dst, src, &nonce, &key, 3)
+		if !bytes.Equal(dst, expected) {
+			t.Fatalf("ChaCha%d: XORKeyStream%d differs from XORKeyStream with %d rounds", v.rounds, v.rounds, v.rounds)
+		}
+	}
+}
+
+func TestXORKeyStreamSmall(t *testing.T) {
+	var key [32]byte
+	var nonce [12]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	ref := make([]byte, 4*64)
+	XORKeyStream(ref, ref, &nonce, &key, 0, 20)
+
+	// every call uses the next block - even if the
+	// keystream of the previous block is buffered
+	c := NewCipher(&nonce, &key, 20)
+	c.XORKeyStream(make([]byte, 5), make([]byte, 5))
+	for i, size := range []int{0, 32, 64} {
+		buf := make([]byte, size)
+		c.XORKeyStreamSmall(buf, buf)
+		if block := ref[(i+1)*64:]; !bytes.Equal(buf, block[:size]) {
+			t.Fatalf("Size %d: XORKeyStreamSmall returned %x - but expected %x", size, buf, block[:size])
+		}
+	}
+
+	defer func() {
+		if err := recover(); err == nil {
+			t.Fatal("Expected error: src is longer than one block")
+		}
+	}()
+	c.XORKeyStreamSmall(make([]byte, 65), make([]byte, 65))
+}
+
+func BenchmarkXORKeyStream32(b *testing.B) {
+	var key [32]byte
+	var nonce [12]byte
+	c := NewCipher(&nonce, &key, 20)
+	buf := make([]byte, 32)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.SetCounter(0)
+		c.XORKeyStream(buf, buf)
+	}
+}
+
+func BenchmarkXORKeyStreamSmall32(b *testing.B) {
+	var key [32]byte
+	var nonce [12]byte
+	c := NewCipher(&nonce, &key, 20)
+	buf := make([]byte, 32)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.SetCounter(0)
+		c.XORKeyStreamSmall(buf, buf)
+	}
+}
+
+func BenchmarkKeyStreamReader1M(b *testing.B) {
+	var key [32]byte
+	var nonce [12]byte
+	r := NewCipher(&nonce, &key, 20).KeyStreamReader()
+	buf := make([]byte, 1024*1024)
+	b.SetBytes(int64(len(buf)))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		r.Read(buf)
+	}
+}
+
+func TestUseGeneric(t *testing.T) {
+	defer UseGeneric(false)
+
+	var key [32]byte
+	var nonce [12]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	src := make([]byte, 7*64+13)
+	for i := range src {
+		src[i] = byte(i * 3)
+	}
+
+	for _, rounds := range []int{8, 12, 20} {
+		var dst [2][]byte
+		var perm [2][16]uint32
+		for i, generic := range []bool{false, true} {
+			UseGeneric(generic)
+			dst[i] = make([]byte, len(src))
+			c := NewCipherCounter(&nonce, &key, 0xfffffffd, rounds)
+			c.XORKeyStream(dst[i][:100], src[:100])
+			c.XORKeyStream(dst[i][100:], src[100:])
+
+			for j := range perm[i] {
+				perm[i][j] = uint32(j) * 0x01010101
+			}
+			Permute(&perm[i], rounds)
+		}
+		if !bytes.Equal(dst[0], dst[1]) {
+			t.Fatalf("ChaCha%d: the generic implementation differs from the default one", rounds)
+		}
+		if perm[0] != perm[1] {
+			t.Fatalf("ChaCha%d: the generic permutation differs from the default one", rounds)
+		}
+	}
+}
+
+func TestXORBlocksUnaligned(t *testing.T) {
+	var key [32]byte
+	var nonce [12]byte
+	c := NewCipher(&nonce, &key, 20)
+	src := make([]byte, 9*64)
+	want := make([]byte, len(src))
+	XORKeyStream(want, src, &nonce, &key, 0, 20)
+
+	var buf [64 + 16]byte
+	for off := 0; off < 16; off++ {
+		state := (*[64]byte)(unsafe.Pointer(&buf[off]))
+		*state = c.state
+		dst := make([]byte, len(src))
+		XORBlocks(dst, src, state, 20)
+		if !bytes.Equal(dst, want) {
+			t.Fatalf("Offset %d: XORBlocks returned a wrong keystream", off)
+		}
+		if state[48] != 9 {
+			t.Fatalf("Offset %d: XORBlocks set the counter to %d - but expected 9", off, state[48])
+		}
+	}
+}
+
+func TestCoreRounds(t *testing.T) {
+	var state [64]byte
+	for i := range state {
+		state[i] = byte(i*7 + 1)
+	}
+	// many blocks with the counter crossing 2^32
+	state[48], state[49], state[50], state[51] = 0xfe, 0xff, 0xff, 0xff
+
+	for rounds := 2; rounds <= 40; rounds += 2 {
+		s0, s1 := state, state
+		for i := 0; i < 4; i++ {
+			var b0, b1 [64]byte
+			Core(&b0, &s0, rounds)
+			CoreRounds(&b1, &s1, rounds)
+			if b0 != b1 {
+				t.Fatalf("Rounds %d, block %d: Core differs from CoreRounds", rounds, i)
+			}
+			if s0 != s1 {
+				t.Fatalf("Rounds %d, block %d: Core and CoreRounds update the state differently", rounds, i)
+			}
+		}
+	}
+
+	for _, rounds := range []int{0, -2, 7} {
+		func() {
+			defer recFail(t, "rounds is not a positive multiple of 2")
+			var dst [64]byte
+			CoreRounds(&dst, &state, rounds)
+		}()
+	}
+}
@@ -0,0 +1,82 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package chacha
+
+// HChaCha derives a 32 byte subkey from the key and the 16 byte nonce
+// using the given number of rounds (must be even) and writes it to out.
+// HChaCha is the key derivation function of XChaCha (e.g. HChaCha20 for
+// 20 rounds). The subkey consists of the words 0 - 3 and 12 - 15 of the
+// permuted state - the input state is not added.
+// If rounds is not a positive multiple of 2 or one of the arguments
+// is nil this function panics.
+func HChaCha(out *[32]byte, nonce *[16]byte, key *[32]byte, rounds int) {
+	if rounds <= 0 || rounds%2 != 0 {
+		panic("chacha20/chacha: rounds must be a multiple of 2")
+	}
+	if nonce == nil {
+		panic("chacha20/chacha: nil nonce")
+	}
+	if key == nil {
+		panic("chacha20/chacha: nil key")
+	}
+	if out == nil {
+		panic("chacha20/chacha: nil output")
+	}
+
+	var state [16]uint32
+	for i := 0; i < 4; i++ {
+		state[i] = getUint32(constants[4*i:])
+	}
+	for i := 0; i < 8; i++ {
+		state[4+i] = getUint32(key[4*i:])
+	}
+	for i := 0; i < 4; i++ {
+		state[12+i] = getUint32(nonce[4*i:])
+	}
+
+	permute(&state, rounds)
+
+	for i := 0; i < 4; i++ {
+		putUint32(out[4*i:], state[i])
+		putUint32(out[16+4*i:], state[12+i])
+	}
+}
+
+// XXNewCipher returns a new *chacha.Cipher implementing the ChaCha/X
+// (X = even number of rounds) stream cipher with a 32 byte nonce.
+//
+// EXPERIMENTAL: XXChaCha is NOT a standardized construction. It extends
+// XChaCha by a second HChaCha pass: The first 16 bytes of the nonce and
+// the key derive a subkey. The last 16 bytes of the nonce and the subkey
+// derive the key of the returned cipher, which uses an all-zero nonce:
+//
+//	subkey     = HChaCha(key, nonce[0:16])
+//	sub-subkey = HChaCha(subkey, nonce[16:32])
+//	cipher     = ChaCha(sub-subkey, 0)
+//
+// This allows random 256 bit nonces. Use XChaCha or ChaCha if interoperability
+// is required. The nonce must be unique for one key for all time.
+func XXNewCipher(nonce *[32]byte, key *[32]byte, rounds int) *Cipher {
+	if nonce == nil {
+		panic("chacha20/chacha: nil nonce")
+	}
+	var n [16]byte
+	var subKey [32]byte
+
+	copy(n[:], nonce[:16])
+	HChaCha(&subKey, &n, key, rounds)
+
+	copy(n[:], nonce[16:])
+	HChaCha(&subKey, &n, &subKey, rounds)
+
+	return NewCipher(new([12]byte), &subKey, rounds)
+}
+
+func getUint32(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
+
+func putUint32(b []byte, v uint32) {
+	b[0], b[1], b[2], b[3] = byte(v), byte(v>>8), byte(v>>16), byte(v>>24)
+}
@@ -0,0 +1,76 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package chacha
+
+// Permute applies the ChaCha permutation with the given number of
+// rounds (must be even) to the 16 word state. In contrast to Core
+// neither the constants, key and nonce are set nor the input state
+// is added to the output (feed-forward) - so Permute alone is NOT a
+// secure keystream generator.
+// Permute is a low-level primitive for building other constructions
+// on top of the ChaCha permutation. Use XORKeyStream or NewCipher for
+// encryption.
+// If rounds is not a positive multiple of 2 this function panics.
+func Permute(state *[16]uint32, rounds int) {
+	if rounds <= 0 || rounds%2 != 0 {
+		panic("chacha20/chacha: rounds must be a multiple of 2")
+	}
+	permute(state, rounds)
+}
+
+// CoreWords computes the ChaCha block function on words: it applies the
+// ChaCha permutation with the given number of rounds (must be even) to in
+// and adds in to the result (feed-forward). Unlike Core it neither reads
+// nor writes bytes and does not increment the counter (word 12) - so the
+// caller controls the whole state and can e.g. replace the feed-forward.
+// Core is CoreWords on the words of the state decoded as 32 bit
+// little-endian values, with the output words encoded as 32 bit
+// little-endian values: the word i is the byte slice [4*i, 4*i+4) of
+// the 64 byte block. out and in may be the same array.
+// CoreWords uses the assembly of Permute if available.
+// If rounds is not a positive multiple of 2 this function panics.
+func CoreWords(out *[16]uint32, in *[16]uint32, rounds int) {
+	if rounds <= 0 || rounds%2 != 0 {
+		panic("chacha20/chacha: rounds must be a multiple of 2")
+	}
+	state := *in
+	permute(&state, rounds)
+	for i := range state {
+		out[i] = state[i] + in[i]
+	}
+}
+
+// permuteGeneric is the generic implementation of Permute.
+func permuteGeneric(s *[16]uint32, rounds int) {
+	for i := 0; i < rounds; i += 2 {
+		// column round
+		s[0], s[4], s[8], s[12] = quarterRound(s[0], s[4], s[8], s[12])
+		s[1], s[5], s[9], s[13] = quarterRound(s[1], s[5], s[9], s[13])
+		s[2], s[6], s[10], s[14] = quarterRound(s[2], s[6], s[10], s[14])
+		s[3], s[7], s[11], s[15] = quarterRound(s[3], s[7], s[11], s[15])
+
+		// diagonal round
+		s[0], s[5], s[10], s[15] = quarterRound(s[0], s[5], s[10], s[15])
+		s[1], s[6], s[11], s[12] = quarterRound(s[1], s[6], s[11], s[12])
+		s[2], s[7], s[8], s[13] = quarterRound(s[2], s[7], s[8], s[13])
+		s[3], s[4], s[9], s[14] = quarterRound(s[3], s[4], s[9], s[14])
+	}
+}
+
+// quarterRound is the ChaCha quarter round.
+func quarterRound(a, b, c, d uint32) (uint32, uint32, uint32, uint32) {
+	a += b
+	d ^= a
+	d = (d << 16) | (d >> 16)
+	c += d
+	b ^= c
+	b = (b << 12) | (b >> 20)
+	a += b
+	d ^= a
+	d = (d << 8) | (d >> 24)
+	c += d
+	b ^= c
+	b = (b << 7) | (b >> 25)
+	return a, b, c, d
+}
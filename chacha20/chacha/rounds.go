@@ -0,0 +1,37 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package chacha
+
+// NewChaCha20 returns a new *chacha.Cipher implementing the ChaCha20 stream
+// cipher - like NewCipher with 20 rounds. The nonce must be unique for one
+// key for all time.
+func NewChaCha20(nonce *[12]byte, key *[32]byte) *Cipher { return NewCipher(nonce, key, 20) }
+
+// NewChaCha12 returns a new *chacha.Cipher implementing the ChaCha12 stream
+// cipher - like NewCipher with 12 rounds. The nonce must be unique for one
+// key for all time.
+func NewChaCha12(nonce *[12]byte, key *[32]byte) *Cipher { return NewCipher(nonce, key, 12) }
+
+// NewChaCha8 returns a new *chacha.Cipher implementing the ChaCha8 stream
+// cipher - like NewCipher with 8 rounds. The nonce must be unique for one
+// key for all time.
+func NewChaCha8(nonce *[12]byte, key *[32]byte) *Cipher { return NewCipher(nonce, key, 8) }
+
+// XORKeyStream20 crypts bytes from src to dst using ChaCha20 - like
+// XORKeyStream with 20 rounds.
+func XORKeyStream20(dst, src []byte, nonce *[12]byte, key *[32]byte, counter uint32) {
+	XORKeyStream(dst, src, nonce, key, counter, 20)
+}
+
+// XORKeyStream12 crypts bytes from src to dst using ChaCha12 - like
+// XORKeyStream with 12 rounds.
+func XORKeyStream12(dst, src []byte, nonce *[12]byte, key *[32]byte, counter uint32) {
+	XORKeyStream(dst, src, nonce, key, counter, 12)
+}
+
+// XORKeyStream8 crypts bytes from src to dst using ChaCha8 - like
+// XORKeyStream with 8 rounds.
+func XORKeyStream8(dst, src []byte, nonce *[12]byte, key *[32]byte, counter uint32) {
+	XORKeyStream(dst, src, nonce, key, counter, 8)
+}
@@ -0,0 +1,39 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package chacha
+
+// StateWords returns the ChaCha/X (X = rounds) state after the block
+// function for the given key, nonce and counter - the 16 words after
+// the rounds and the addition of the input state, before they are
+// serialized (little-endian) to the 64 byte keystream block.
+// StateWords exposes an internal value for test and interoperability
+// tooling (e.g. comparing with the intermediate values of RFC 7539).
+// Use XORKeyStream or NewCipher for encryption.
+// If rounds is not a positive multiple of 2 or the nonce or the key
+// is nil this function panics.
+func StateWords(nonce *[12]byte, key *[32]byte, counter uint32, rounds int) [16]uint32 {
+	if rounds <= 0 || rounds%2 != 0 {
+		panic("chacha20/chacha: rounds must be a multiple of 2")
+	}
+	if nonce == nil {
+		panic("chacha20/chacha: nil nonce")
+	}
+	if key == nil {
+		panic("chacha20/chacha: nil key")
+	}
+
+	var state, block [64]byte
+	copy(state[:], constants[:])
+	copy(state[16:], key[:])
+	putUint32(state[48:], counter)
+	copy(state[52:], nonce[:])
+
+	Core(&block, &state, rounds)
+
+	var words [16]uint32
+	for i := range words {
+		words[i] = getUint32(block[4*i:])
+	}
+	return words
+}
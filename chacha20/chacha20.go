@@ -20,8 +20,8 @@ import (
 const NonceSize = 12
 
 // XORKeyStream crypts bytes from src to dst using the given key, nonce and counter. Src
-// and dst may be the same slice but otherwise should not overlap. If len(dst) < len(src)
-// this function panics.
+// and dst may be the same slice but otherwise must not overlap. If len(dst) < len(src)
+// or dst and src overlap partially this function panics.
 func XORKeyStream(dst, src []byte, nonce *[NonceSize]byte, key *[32]byte, counter uint32) {
 	chacha.XORKeyStream(dst, src, nonce, key, counter, 20)
 }
@@ -16,9 +16,28 @@ import (
 // The max. size of the auth. tag for the ChaCha20Poly1305 AEAD cipher in bytes.
 const TagSize = poly1305.TagSize
 
+// MaxPlaintextSize is the max. number of plaintext bytes, which can be
+// encrypted with one nonce by the ChaCha20Poly1305 AEAD.
+const MaxPlaintextSize = (1<<32 - 1) * 64
+
+func init() {
+	crypto.DefaultRegistry.Register(crypto.ChaCha20Poly1305, "ChaCha20-Poly1305", func(key []byte) (cipher.AEAD, error) {
+		if len(key) != 32 {
+			return nil, crypto.KeySizeError(len(key))
+		}
+		var Key [32]byte
+		copy(Key[:], key)
+		return NewChaCha20Poly1305(&Key), nil
+	})
+}
+
 // NewChaCha20Poly1305 returns a cipher.AEAD implementing the
 // ChaCha20Poly1305 construction specified in RFC 7539 with a
 // 128 bit auth. tag.
+//
+// Seal and Open append to dst like every crypto/cipher.AEAD. Earlier
+// versions wrote the result to dst[0:] and panicked if dst was too short -
+// callers passing a pre-sized dst must pass dst[:0] to get the same output.
 func NewChaCha20Poly1305(key *[32]byte) cipher.AEAD {
 	c := &aead{tagsize: TagSize}
 	c.key = *key
@@ -47,12 +66,17 @@ func (c *aead) Overhead() int { return c.tagsize }
 
 func (c *aead) NonceSize() int { return NonceSize }
 
+// MaxPlaintext returns the max. number of plaintext bytes, which can be
+// encrypted with one nonce. The plaintext is encrypted starting at the
+// block counter 1, so at most 2^32 - 1 blocks of 64 bytes (RFC 7539).
+func (c *aead) MaxPlaintext() int64 { return MaxPlaintextSize }
+
+// Seal encrypts and authenticates the plaintext and the additional data
+// and appends the result to dst. To reuse the storage of the plaintext
+// for the ciphertext use plaintext[:0] as dst.
 func (c *aead) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
 	if n := len(nonce); n != NonceSize {
-		panic(crypto.NonceSizeError(n))
-	}
-	if len(dst) < len(plaintext)+c.tagsize {
-		panic("dst buffer to small")
+		panic(crypto.NonceSizeMismatchError{Size: n, Expected: NonceSize})
 	}
 	var Nonce [12]byte
 	copy(Nonce[:], nonce)
@@ -63,23 +87,25 @@ func (c *aead) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
 
 	// encrypt the plaintext
 	n := len(plaintext)
-	chacha.XORKeyStream(dst, plaintext, &Nonce, &(c.key), 1, 20)
+	ret, out := sliceForAppend(dst, n+c.tagsize)
+	chacha.XORKeyStream(out, plaintext, &Nonce, &(c.key), 1, 20)
 
 	// authenticate the ciphertext
 	var tag [poly1305.TagSize]byte
-	authenticate(&tag, dst[:n], additionalData, &polyKey)
-	return append(dst[:n], tag[:c.tagsize]...)
+	authenticate(&tag, out[:n], additionalData, &polyKey)
+	copy(out[n:], tag[:c.tagsize])
+	return ret
 }
 
+// Open decrypts and authenticates the ciphertext and the additional data
+// and appends the plaintext to dst. To reuse the storage of the ciphertext
+// for the plaintext use ciphertext[:0] as dst.
 func (c *aead) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
 	if n := len(nonce); n != NonceSize {
-		return nil, crypto.NonceSizeError(n)
+		return nil, crypto.NonceSizeMismatchError{Size: n, Expected: NonceSize}
 	}
 	if len(ciphertext) < c.tagsize {
-		return nil, crypto.AuthenticationError{}
-	}
-	if len(dst) < len(ciphertext)-c.tagsize {
-		panic("dst buffer to small")
+		return nil, crypto.AuthenticationError{Algorithm: "ChaCha20-Poly1305"}
 	}
 	var Nonce [12]byte
 
@@ -96,19 +122,28 @@ func (c *aead) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, erro
 	var tag [poly1305.TagSize]byte
 	authenticate(&tag, ciphertext, additionalData, &polyKey)
 	if subtle.ConstantTimeCompare(tag[:c.tagsize], hash[:c.tagsize]) != 1 {
-		return nil, crypto.AuthenticationError{}
+		return nil, crypto.AuthenticationError{Algorithm: "ChaCha20-Poly1305"}
 	}
 
 	// decrypt ciphertext
-	chacha.XORKeyStream(dst, ciphertext, &Nonce, &(c.key), 1, 20)
-	return dst[:len(ciphertext)], nil
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	chacha.XORKeyStream(out, ciphertext, &Nonce, &(c.key), 1, 20)
+	return ret, nil
 }
 
 // authenticate calculates the poly1305 tag from
 // the given ciphertext and additional data.
 func authenticate(out *[TagSize]byte, ciphertext, additionalData []byte, key *[32]byte) {
+	poly := poly1305.New(key)
+	poly.Write(additionalData)
+	finishAuthentication(out, poly, ciphertext, uint64(len(additionalData)))
+}
+
+// finishAuthentication pads the additional data of adLen bytes,
+// which is already written to poly, and calculates the poly1305
+// tag of the additional data and the given ciphertext.
+func finishAuthentication(out *[TagSize]byte, poly *poly1305.Hash, ciphertext []byte, adLen uint64) {
 	ctLen := uint64(len(ciphertext))
-	adLen := uint64(len(additionalData))
 	padAD, padCT := adLen%16, ctLen%16
 
 	var buf [16]byte
@@ -129,15 +164,28 @@ func authenticate(out *[TagSize]byte, ciphertext, additionalData []byte, key *[3
 	buf[14] = byte(ctLen >> 48)
 	buf[15] = byte(ctLen >> 56)
 
-	poly := poly1305.New(key)
-	poly.Write(additionalData)
+	var zeros [16]byte
 	if padAD > 0 {
-		poly.Write(make([]byte, 16-padAD))
+		poly.Write(zeros[:16-padAD])
 	}
 	poly.Write(ciphertext)
 	if padCT > 0 {
-		poly.Write(make([]byte, 16-padCT))
+		poly.Write(zeros[:16-padCT])
 	}
 	poly.Write(buf[:])
 	poly.Sum(out)
 }
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns a
+// slice with the contents of the given slice followed by that many bytes and
+// a second slice that aliases into it and contains only the extra bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -3,7 +3,13 @@
 
 package chacha20
 
-import "testing"
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/enceve/crypto"
+)
 
 var recFunc = func(t *testing.T, msg string) {
 	if recover() == nil {
@@ -65,8 +71,44 @@ func TestSeal(t *testing.T) {
 	}
 
 	mustFail("nonce size is invalid", dst[:], nonce[:NonceSize-1], src[:])
+}
 
-	mustFail("dst length invalid", dst[:len(dst)-2], nonce[:], src[:])
+func TestSealOpenAppend(t *testing.T) {
+	var key [32]byte
+	c := NewChaCha20Poly1305(&key)
+	var nonce [NonceSize]byte
+	msg, data := []byte("appended to dst"), []byte("data")
+	expected := c.Seal(nil, nonce[:], msg, data)
+
+	// a non-empty dst is kept - also if it is long enough for the result
+	for _, prefix := range [][]byte{[]byte("prefix"), make([]byte, len(msg)+TagSize)} {
+		dst := append([]byte{}, prefix...)
+		ciphertext := c.Seal(dst, nonce[:], msg, data)
+		if !bytes.Equal(ciphertext[:len(prefix)], prefix) || !bytes.Equal(ciphertext[len(prefix):], expected) {
+			t.Fatalf("Seal(%d byte dst) returned %x - but expected the dst followed by %x", len(prefix), ciphertext, expected)
+		}
+		plaintext, err := c.Open(append([]byte{}, prefix...), nonce[:], expected, data)
+		if err != nil {
+			t.Fatalf("Open(%d byte dst) failed: %s", len(prefix), err)
+		}
+		if !bytes.Equal(plaintext[:len(prefix)], prefix) || !bytes.Equal(plaintext[len(prefix):], msg) {
+			t.Fatalf("Open(%d byte dst) returned %x - but expected the dst followed by %x", len(prefix), plaintext, msg)
+		}
+	}
+
+	// in place
+	buf := append(make([]byte, 0, len(msg)+TagSize), msg...)
+	ciphertext := c.Seal(buf[:0], nonce[:], buf, data)
+	if !bytes.Equal(ciphertext, expected) || &ciphertext[0] != &buf[0] {
+		t.Fatalf("In-place Seal returned %x - but expected %x in the plaintext buffer", ciphertext, expected)
+	}
+	plaintext, err := c.Open(ciphertext[:0], nonce[:], ciphertext, data)
+	if err != nil {
+		t.Fatalf("In-place Open failed: %s", err)
+	}
+	if !bytes.Equal(plaintext, msg) || &plaintext[0] != &buf[0] {
+		t.Fatalf("In-place Open returned %q - but expected %q in the ciphertext buffer", plaintext, msg)
+	}
 }
 
 func TestOpen(t *testing.T) {
@@ -80,8 +122,8 @@ func TestOpen(t *testing.T) {
 	)
 
 	_, err := c.Open(dst[:], nonce[:NonceSize-1], src[:], nil)
-	if err == nil {
-		t.Fatal("Open() accepted invalid nonce size")
+	if err != (crypto.NonceSizeMismatchError{Size: NonceSize - 1, Expected: NonceSize}) {
+		t.Fatalf("Open() returned %v for an invalid nonce size", err)
 	}
 
 	_, err = c.Open(dst[:], nonce[:], src[:TagSize-1], nil)
@@ -89,20 +131,14 @@ func TestOpen(t *testing.T) {
 		t.Fatal("Open() accepted invalid ciphertext length")
 	}
 
-	mustFail := func(msg string, dst, nonce, src []byte) {
-		defer recFunc(t, msg)
-		c.Open(dst, nonce, src, nil)
-	}
-
-	mustFail("dst length invalid", dst[:len(src)-TagSize-1], nonce[:], src[:])
-
 	// Check tag verification
-	c.Seal(dst[:], nonce[:], src[:], nil)
+	c.Seal(dst[:0], nonce[:], src[:], nil)
 	dst[len(src)+1] += 1 // modify tag
 
-	_, err = c.Open(src[:], nonce[:], dst[:], nil)
-	if err == nil {
-		t.Fatal("Open() accepted invalid auth. tag")
+	_, err = c.Open(src[:0], nonce[:], dst[:], nil)
+	var authErr crypto.AuthenticationError
+	if !errors.As(err, &authErr) || authErr.Algorithm != "ChaCha20-Poly1305" {
+		t.Fatalf("Open() returned %v for an invalid auth. tag", err)
 	}
 }
 
@@ -117,7 +153,7 @@ func BenchmarkSeal64B(b *testing.B) {
 
 	b.SetBytes(int64(len(msg)))
 	for i := 0; i < b.N; i++ {
-		dst = c.Seal(dst, nonce[:], msg, data)
+		dst = c.Seal(dst[:0], nonce[:], msg, data)
 	}
 }
 
@@ -132,7 +168,7 @@ func BenchmarkSeal1K(b *testing.B) {
 
 	b.SetBytes(int64(len(msg)))
 	for i := 0; i < b.N; i++ {
-		dst = c.Seal(dst, nonce[:], msg, data)
+		dst = c.Seal(dst[:0], nonce[:], msg, data)
 	}
 }
 
@@ -147,7 +183,7 @@ func BenchmarkSeal64K(b *testing.B) {
 
 	b.SetBytes(int64(len(msg)))
 	for i := 0; i < b.N; i++ {
-		dst = c.Seal(dst, nonce[:], msg, data)
+		dst = c.Seal(dst[:0], nonce[:], msg, data)
 	}
 }
 
@@ -160,11 +196,11 @@ func BenchmarkOpen64B(b *testing.B) {
 	dst := make([]byte, len(msg))
 	ciphertext := make([]byte, len(msg)+TagSize)
 	data := make([]byte, 32)
-	ciphertext = c.Seal(ciphertext, nonce[:], msg, data)
+	ciphertext = c.Seal(ciphertext[:0], nonce[:], msg, data)
 
 	b.SetBytes(int64(len(msg)))
 	for i := 0; i < b.N; i++ {
-		dst, _ = c.Open(dst, nonce[:], ciphertext, data)
+		dst, _ = c.Open(dst[:0], nonce[:], ciphertext, data)
 	}
 }
 
@@ -177,11 +213,11 @@ func BenchmarkOpen1K(b *testing.B) {
 	dst := make([]byte, len(msg))
 	ciphertext := make([]byte, len(msg)+TagSize)
 	data := make([]byte, 32)
-	ciphertext = c.Seal(ciphertext, nonce[:], msg, data)
+	ciphertext = c.Seal(ciphertext[:0], nonce[:], msg, data)
 
 	b.SetBytes(int64(len(msg)))
 	for i := 0; i < b.N; i++ {
-		dst, _ = c.Open(dst, nonce[:], ciphertext, data)
+		dst, _ = c.Open(dst[:0], nonce[:], ciphertext, data)
 	}
 }
 
@@ -194,10 +230,10 @@ func BenchmarkOpen64K(b *testing.B) {
 	dst := make([]byte, len(msg))
 	ciphertext := make([]byte, len(msg)+TagSize)
 	data := make([]byte, 32)
-	ciphertext = c.Seal(ciphertext, nonce[:], msg, data)
+	ciphertext = c.Seal(ciphertext[:0], nonce[:], msg, data)
 
 	b.SetBytes(int64(len(msg)))
 	for i := 0; i < b.N; i++ {
-		dst, _ = c.Open(dst, nonce[:], ciphertext, data)
+		dst, _ = c.Open(dst[:0], nonce[:], ciphertext, data)
 	}
 }
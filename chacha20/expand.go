@@ -0,0 +1,66 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package chacha20
+
+import (
+	"encoding/binary"
+
+	"github.com/enceve/crypto/chacha20/chacha"
+)
+
+// The maximum number of bytes Expand can produce -
+// 2^32 keystream blocks of 64 bytes.
+const maxExpandLen = (1 << 32) * 64
+
+// The first HChaCha20 input block of Expand - the domain
+// separation of Expand from other uses of HChaCha20.
+var expandLabel = [16]byte{'C', 'h', 'a', 'C', 'h', 'a', '2', '0', '-', 'E', 'x', 'p', 'a', 'n', 'd', 0}
+
+// Expand derives outLen bytes from the (uniformly random) key and the info
+// using only the ChaCha20 core - e.g. to derive several keys from one master
+// key on devices without AES. Expand is NOT HKDF and its output differs from
+// every standard KDF. It does not extract - so the key must already be a
+// uniformly random key and not e.g. a Diffie-Hellman shared secret.
+//
+// The info is absorbed by a cascade of HChaCha20 calls, which computes
+// a subkey starting with k = key:
+//
+//	k = HChaCha20(k, "ChaCha20-Expand" || 0x00)
+//	k = HChaCha20(k, len(info) (64 bit little-endian) || 8 zero bytes)
+//	k = HChaCha20(k, b) for every 16 byte block b of info (the last block is zero padded)
+//
+// The output is the ChaCha20 keystream of the subkey with a zero nonce
+// starting at the counter 0. Encoding the length of the info before the
+// info makes the encoding prefix-free - so different infos produce
+// independent outputs, while a shorter output with the same info is a
+// prefix of a longer one.
+// The key should not be used for anything else than Expand: the label only
+// separates Expand from other uses of HChaCha20 under the same key if
+// these never use the label as (X)ChaCha20 nonce prefix.
+// If outLen is negative or greater than 2^32 * 64 this function panics.
+func Expand(key *[32]byte, info []byte, outLen int) []byte {
+	if outLen < 0 || uint64(outLen) > maxExpandLen {
+		panic("chacha20: invalid output length")
+	}
+
+	var subKey [32]byte
+	block := expandLabel
+	chacha.HChaCha(&subKey, &block, key, 20)
+
+	block = [16]byte{}
+	binary.LittleEndian.PutUint64(block[:], uint64(len(info)))
+	chacha.HChaCha(&subKey, &block, &subKey, 20)
+
+	for len(info) > 0 {
+		block = [16]byte{}
+		n := copy(block[:], info)
+		chacha.HChaCha(&subKey, &block, &subKey, 20)
+		info = info[n:]
+	}
+
+	var nonce [NonceSize]byte
+	out := make([]byte, outLen)
+	XORKeyStream(out, out, &nonce, &subKey, 0)
+	return out
+}
@@ -0,0 +1,79 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package chacha20
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+)
+
+// The vectors are computed with the HChaCha20 and ChaCha20
+// implementations of golang.org/x/crypto.
+var expandVectors = []struct {
+	zeroKey bool
+	info    string
+	outLen  int
+	out     string
+}{
+	{true, "", 32, "a2c80dfc2e2fb0b1e8d141eb871d9ef9a6b639978231dca0a227398f626b6c69"},
+	{false, "", 0, ""},
+	{false, "encryption key", 32, "742eef32ada6b847b490878ac6867e58f454d6b849e003abf2a5d23daff5efbb"},
+	{false, "a 16 byte info!!", 16, "aaf2264adc04e2848006018c3d35e954"},
+	{
+		false, "an info longer than one 16 byte block", 100,
+		"debc6b5ca5da30e0ef8d7b79372dd4aac52101a2d017cfd276f894db37cf8871011680ac5288bbffdaa348be95764555608e82" +
+			"4e67411186735bc77e3c74c801a277ea2387d946a7e1e43f244052b202aa21317fc8fab13bc02cda377c6020821d7d6658",
+	},
+}
+
+func TestExpandVectors(t *testing.T) {
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	for i, v := range expandVectors {
+		k := key
+		if v.zeroKey {
+			k = [32]byte{}
+		}
+		out := Expand(&k, []byte(v.info), v.outLen)
+		if s := hex.EncodeToString(out); s != v.out {
+			t.Fatalf("Test vector %d: Expand returned %s - but expected %s", i, s, v.out)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	var key [32]byte
+	info := []byte("info")
+	long := Expand(&key, info, 300)
+	for _, n := range []int{0, 1, 63, 64, 65, 300} {
+		out := Expand(&key, info, n)
+		if len(out) != n {
+			t.Fatalf("Expand returned %d bytes - but expected %d", len(out), n)
+		}
+		if !bytes.Equal(out, long[:n]) {
+			t.Fatalf("Expand of %d bytes is not a prefix of the longer output", n)
+		}
+	}
+
+	// the length of the info is encoded - zero padding must not collide
+	for _, other := range [][]byte{nil, []byte("info\x00"), []byte("inf")} {
+		if bytes.Equal(Expand(&key, other, 32), long[:32]) {
+			t.Fatalf("Expand returned the same output for the info %q and %q", info, other)
+		}
+	}
+	key[0] = 1
+	if bytes.Equal(Expand(&key, info, 32), long[:32]) {
+		t.Fatal("Expand returned the same output for different keys")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expand accepted a negative output length")
+		}
+	}()
+	Expand(&key, info, -1)
+}
@@ -0,0 +1,73 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package chacha20
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+func init() {
+	crypto.RegisterSelfTest("ChaCha20", chacha20SelfTest)
+	crypto.RegisterSelfTest("ChaCha20-Poly1305", aeadSelfTest)
+}
+
+// chacha20SelfTest checks the ChaCha20 keystream against
+// the test vector from RFC 7539 (appendix A.1 #1).
+func chacha20SelfTest() error {
+	keystream, _ := hex.DecodeString("76b8e0ada0f13d90405d6ae55386bd28" +
+		"bdd219b8a08ded1aa836efcc8b770dc7" +
+		"da41597c5157488d7724e03fb8d84a37" +
+		"6a43b8f41518a11cc387b669b2ee6586")
+
+	var key [32]byte
+	var nonce [NonceSize]byte
+	buf := make([]byte, len(keystream))
+	XORKeyStream(buf, buf, &nonce, &key, 0)
+	if !bytes.Equal(buf, keystream) {
+		return errors.New("unexpected keystream")
+	}
+	return nil
+}
+
+// aeadSelfTest checks ChaCha20-Poly1305 against the
+// test vector from RFC 7539 (section 2.8.2).
+func aeadSelfTest() error {
+	key, _ := hex.DecodeString("808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f")
+	nonce, _ := hex.DecodeString("070000004041424344454647")
+	data, _ := hex.DecodeString("50515253c0c1c2c3c4c5c6c7")
+	msg := []byte("Ladies and Gentlemen of the class of '99: If I could offer you " +
+		"only one tip for the future, sunscreen would be it.")
+	ciphertext, _ := hex.DecodeString("d31a8d34648e60db7b86afbc53ef7ec2" +
+		"a4aded51296e08fea9e2b5a736ee62d6" +
+		"3dbea45e8ca9671282fafb69da92728b" +
+		"1a71de0a9e060b2905d6a5b67ecd3b36" +
+		"92ddbd7f2d778b8c9803aee328091b58" +
+		"fab324e4fad675945585808b4831d7bc" +
+		"3ff4def08e4b7a9de576d26586cec64b" +
+		"6116" +
+		"1ae10b594f09e26a7e902ecbd0600691")
+
+	var k [32]byte
+	copy(k[:], key)
+	c := NewChaCha20Poly1305(&k)
+	if !bytes.Equal(c.Seal(nil, nonce, msg, data), ciphertext) {
+		return errors.New("unexpected ciphertext")
+	}
+	plaintext, err := c.Open(nil, nonce, ciphertext, data)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(plaintext, msg) {
+		return errors.New("unexpected plaintext")
+	}
+	ciphertext[len(ciphertext)-1] ^= 1
+	if _, err = c.Open(nil, nonce, ciphertext, data); err == nil {
+		return errors.New("modified ciphertext accepted")
+	}
+	return nil
+}
@@ -7,15 +7,10 @@ import (
 	"bytes"
 	"encoding/hex"
 	"testing"
-)
 
-func fromHex(s string) []byte {
-	b, err := hex.DecodeString(s)
-	if err != nil {
-		panic(err)
-	}
-	return b
-}
+	"github.com/enceve/crypto/chacha20/chacha"
+	"github.com/enceve/crypto/internal/testvec"
+)
 
 // Test vector from:
 // https://tools.ietf.org/html/rfc7539#section-2.8.1
@@ -113,10 +108,10 @@ var chacha20TestVectors = []struct {
 
 func TestVectors(t *testing.T) {
 	for i, v := range chacha20TestVectors {
-		key := fromHex(v.key)
-		nonce := fromHex(v.nonce)
-		msg := fromHex(v.msg)
-		ciphertext := fromHex(v.ciphertext)
+		key := testvec.MustDecodeHex(v.key)
+		nonce := testvec.MustDecodeHex(v.nonce)
+		msg := testvec.MustDecodeHex(v.msg)
+		ciphertext := testvec.MustDecodeHex(v.ciphertext)
 
 		var (
 			Key   [32]byte
@@ -194,11 +189,11 @@ var aeadTestVectors = []struct {
 
 func TestAEADVectors(t *testing.T) {
 	for i, v := range aeadTestVectors {
-		key := fromHex(v.key)
-		nonce := fromHex(v.nonce)
-		msg := fromHex(v.msg)
-		data := fromHex(v.data)
-		ciphertext := fromHex(v.ciphertext)
+		key := testvec.MustDecodeHex(v.key)
+		nonce := testvec.MustDecodeHex(v.nonce)
+		msg := testvec.MustDecodeHex(v.msg)
+		data := testvec.MustDecodeHex(v.data)
+		ciphertext := testvec.MustDecodeHex(v.ciphertext)
 
 		var Key [32]byte
 		copy(Key[:], key)
@@ -207,14 +202,13 @@ func TestAEADVectors(t *testing.T) {
 			t.Fatalf("Test vector %d: Failed to create AEAD instance: %s", i, err)
 		}
 
-		buf := make([]byte, len(ciphertext))
-		c.Seal(buf, nonce, msg, data)
+		buf := c.Seal(nil, nonce, msg, data)
 
 		if !bytes.Equal(buf, ciphertext) {
 			t.Fatalf("TestVector %d Seal failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
 		}
 
-		buf, err = c.Open(buf, nonce, buf, data)
+		buf, err = c.Open(buf[:0], nonce, buf, data)
 
 		if err != nil {
 			t.Fatalf("TestVector %d: Open failed - Cause: %s", i, err)
@@ -224,3 +218,16 @@ func TestAEADVectors(t *testing.T) {
 		}
 	}
 }
+
+// TestGenericVectors runs the RFC 7539 test vectors with the generic
+// implementation, which is used on all platforms without assembly (like
+// js/wasm) and by gccgo and appengine builds. So the generic path is
+// tested on every platform - and on amd64 and 386 TestVectors also
+// confirms that the assembly produces the same output.
+func TestGenericVectors(t *testing.T) {
+	chacha.UseGeneric(true)
+	defer chacha.UseGeneric(false)
+
+	TestVectors(t)
+	TestAEADVectors(t)
+}
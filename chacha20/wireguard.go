@@ -0,0 +1,21 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package chacha20
+
+import "encoding/binary"
+
+// WireGuardNonce returns the ChaCha20-Poly1305 nonce of the WireGuard
+// transport messages: 4 zero bytes followed by the 64 bit little-endian
+// counter. A transport message with the counter c is sealed by:
+//
+//	nonce := chacha20.WireGuardNonce(c)
+//	packet := aead.Seal(dst, nonce[:], plaintext, nil)
+//
+// where aead is the ChaCha20Poly1305 of the sending key. Every counter
+// must be used only once for one key.
+func WireGuardNonce(counter uint64) [NonceSize]byte {
+	var nonce [NonceSize]byte
+	binary.LittleEndian.PutUint64(nonce[4:], counter)
+	return nonce
+}
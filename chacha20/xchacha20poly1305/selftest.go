@@ -0,0 +1,55 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package xchacha20poly1305
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+func init() {
+	crypto.RegisterSelfTest("XChaCha20-Poly1305", selfTest)
+}
+
+// selfTest checks XChaCha20-Poly1305 against the
+// test vector from draft-irtf-cfrg-xchacha-03 (A.3.1).
+func selfTest() error {
+	key, _ := hex.DecodeString("808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f")
+	nonce, _ := hex.DecodeString("404142434445464748494a4b4c4d4e4f5051525354555657")
+	data, _ := hex.DecodeString("50515253c0c1c2c3c4c5c6c7")
+	msg := []byte("Ladies and Gentlemen of the class of '99: If I could offer you " +
+		"only one tip for the future, sunscreen would be it.")
+	ciphertext, _ := hex.DecodeString("bd6d179d3e83d43b9576579493c0e939" +
+		"572a1700252bfaccbed2902c21396cbb" +
+		"731c7f1b0b4aa6440bf3a82f4eda7e39" +
+		"ae64c6708c54c216cb96b72e1213b452" +
+		"2f8c9ba40db5d945b11b69b982c1bb9e" +
+		"3f3fac2bc369488f76b2383565d3fff9" +
+		"21f9664c97637da9768812f615c68b13" +
+		"b52e" +
+		"c0875924c1c7987947deafd8780acf49")
+
+	c, err := New(key)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(c.Seal(nil, nonce, msg, data), ciphertext) {
+		return errors.New("unexpected ciphertext")
+	}
+	plaintext, err := c.Open(nil, nonce, ciphertext, data)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(plaintext, msg) {
+		return errors.New("unexpected plaintext")
+	}
+	ciphertext[len(ciphertext)-1] ^= 1
+	if _, err = c.Open(nil, nonce, ciphertext, data); err == nil {
+		return errors.New("modified ciphertext accepted")
+	}
+	return nil
+}
@@ -0,0 +1,228 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// Package xchacha20poly1305 implements the XChaCha20-Poly1305 AEAD
+// construction. XChaCha20-Poly1305 extends the nonce of ChaCha20-Poly1305
+// (RFC 7539) to 192 bit: the first 16 bytes of the nonce and the key are
+// used to derive a subkey with HChaCha20 and the remaining 8 bytes form
+// the ChaCha20-Poly1305 nonce (prefixed with 4 zero bytes). So random
+// nonces can be used safely. The construction is compatible to
+// crypto_aead_xchacha20poly1305_ietf of libsodium.
+package xchacha20poly1305
+
+import (
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/chacha20/chacha"
+	"github.com/enceve/crypto/poly1305"
+)
+
+const (
+	// KeySize is the size of the key in bytes.
+	KeySize = 32
+	// NonceSize is the size of the nonce in bytes.
+	NonceSize = 24
+	// TagSize is the size of the auth. tag in bytes.
+	TagSize = poly1305.TagSize
+)
+
+// AEAD implements cipher.AEAD for XChaCha20-Poly1305.
+// An AEAD is safe for concurrent use.
+type AEAD struct {
+	key [KeySize]byte
+}
+
+// New returns a new *AEAD implementing cipher.AEAD using the given key.
+// This function returns a non-nil error if the key is not 32 bytes long.
+func New(key []byte) (*AEAD, error) {
+	if k := len(key); k != KeySize {
+		return nil, crypto.KeySizeError(k)
+	}
+	c := new(AEAD)
+	copy(c.key[:], key)
+	return c, nil
+}
+
+// NonceSize returns the size of the nonce.
+func (c *AEAD) NonceSize() int { return NonceSize }
+
+// Overhead returns the size of the auth. tag.
+func (c *AEAD) Overhead() int { return TagSize }
+
+// Seal encrypts and authenticates the plaintext and the additional data
+// and appends the ciphertext followed by the tag to dst. To reuse the
+// storage of the plaintext for the ciphertext use plaintext[:0] as dst.
+// If the nonce is not 24 bytes long this function panics.
+func (c *AEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	ret, out := sliceForAppend(dst, len(plaintext)+TagSize)
+	c.SealDetached(out[:0], out[len(plaintext):], nonce, plaintext, additionalData)
+	return ret
+}
+
+// Open decrypts and authenticates the ciphertext (including the tag) and
+// the additional data and appends the plaintext to dst. To reuse the storage
+// of the ciphertext for the plaintext use ciphertext[:0] as dst.
+// If the nonce size is invalid or the ciphertext is not authentic Open
+// returns a non-nil error.
+func (c *AEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	body, tag, ok := crypto.SplitTag(ciphertext, TagSize)
+	if !ok {
+		return nil, crypto.AuthenticationError{Algorithm: "XChaCha20-Poly1305"}
+	}
+	return c.OpenDetached(dst, nonce, body, tag, additionalData)
+}
+
+// SealDetached works like Seal but writes the tag to tag instead of
+// appending it to the ciphertext. The ciphertext is appended to dst.
+// If the nonce is not 24 bytes long or len(tag) < TagSize this
+// function panics.
+func (c *AEAD) SealDetached(dst, tag, nonce, plaintext, additionalData []byte) []byte {
+	if len(tag) < TagSize {
+		panic("xchacha20poly1305: tag buffer is too small")
+	}
+	a := c.NewAD(nonce)
+	a.Write(additionalData)
+	ret, out := sliceForAppend(dst, len(plaintext))
+	a.seal(out, tag, plaintext)
+	return ret
+}
+
+// OpenDetached works like Open but takes the tag separately from the
+// ciphertext. See SealDetached for details.
+func (c *AEAD) OpenDetached(dst, nonce, ciphertext, tag, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != NonceSize {
+		return nil, crypto.NonceSizeMismatchError{Size: n, Expected: NonceSize}
+	}
+	a := c.NewAD(nonce)
+	a.Write(additionalData)
+	return a.open(dst, ciphertext, tag)
+}
+
+// NewAD returns an *AD authenticating the additional data of one
+// message incrementally for the given nonce.
+// If the nonce is not 24 bytes long this function panics.
+func (c *AEAD) NewAD(nonce []byte) *AD {
+	if n := len(nonce); n != NonceSize {
+		panic(crypto.NonceSizeMismatchError{Size: n, Expected: NonceSize})
+	}
+	a := new(AD)
+	var hNonce [16]byte
+	copy(hNonce[:], nonce[:16])
+	chacha.HChaCha(&(a.key), &hNonce, &(c.key), 20)
+	copy(a.nonce[4:], nonce[16:])
+
+	var polyKey [32]byte
+	chacha.XORKeyStream(polyKey[:], polyKey[:], &(a.nonce), &(a.key), 0, 20)
+	a.poly = poly1305.New(&polyKey)
+	return a
+}
+
+var errADUsed = errors.New("xchacha20poly1305: AD is already used")
+
+// AD authenticates the additional data of one message incrementally -
+// e.g. if the additional data is produced in pieces. All data written
+// to an AD is authenticated as additional data, and Seal or Open
+// completes the message. Seal(dst, plaintext) is equal to the Seal
+// function of the AEAD with the concatenation of all writes as
+// additional data. An AD can only be used for one message.
+type AD struct {
+	key   [32]byte
+	nonce [12]byte
+	poly  *poly1305.Hash
+	n     uint64
+	used  bool
+}
+
+// Write adds p to the additional data. Write returns an
+// error if the AD was already used by Seal or Open.
+func (a *AD) Write(p []byte) (int, error) {
+	if a.used {
+		return 0, errADUsed
+	}
+	a.n += uint64(len(p))
+	return a.poly.Write(p)
+}
+
+// Seal encrypts and authenticates the plaintext and the additional data
+// and appends the ciphertext followed by the tag to dst.
+// If the AD was already used this function panics.
+func (a *AD) Seal(dst, plaintext []byte) []byte {
+	ret, out := sliceForAppend(dst, len(plaintext)+TagSize)
+	a.seal(out[:len(plaintext)], out[len(plaintext):], plaintext)
+	return ret
+}
+
+// Open decrypts and authenticates the ciphertext (including the tag)
+// and the additional data and appends the plaintext to dst.
+// Open returns a non-nil error if the AD was already used or the
+// ciphertext is not authentic.
+func (a *AD) Open(dst, ciphertext []byte) ([]byte, error) {
+	body, tag, ok := crypto.SplitTag(ciphertext, TagSize)
+	if !ok {
+		return nil, crypto.AuthenticationError{Algorithm: "XChaCha20-Poly1305"}
+	}
+	return a.open(dst, body, tag)
+}
+
+func (a *AD) seal(out, tag, plaintext []byte) {
+	if a.used {
+		panic(errADUsed)
+	}
+	a.used = true
+	chacha.XORKeyStream(out, plaintext, &(a.nonce), &(a.key), 1, 20)
+
+	var sum [TagSize]byte
+	a.finish(&sum, out[:len(plaintext)])
+	copy(tag, sum[:])
+}
+
+func (a *AD) open(dst, ciphertext, tag []byte) ([]byte, error) {
+	if a.used {
+		return nil, errADUsed
+	}
+	a.used = true
+
+	var sum [TagSize]byte
+	a.finish(&sum, ciphertext)
+	if subtle.ConstantTimeCompare(sum[:], tag) != 1 {
+		return nil, crypto.AuthenticationError{Algorithm: "XChaCha20-Poly1305"}
+	}
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	chacha.XORKeyStream(out, ciphertext, &(a.nonce), &(a.key), 1, 20)
+	return ret, nil
+}
+
+// finish pads the additional data and computes the Poly1305
+// tag of the additional data and the ciphertext (RFC 7539).
+func (a *AD) finish(tag *[TagSize]byte, ciphertext []byte) {
+	var zeros [16]byte
+	if r := a.n % 16; r > 0 {
+		a.poly.Write(zeros[:16-r])
+	}
+	a.poly.Write(ciphertext)
+	if r := len(ciphertext) % 16; r > 0 {
+		a.poly.Write(zeros[:16-r])
+	}
+	var lengths [16]byte
+	binary.LittleEndian.PutUint64(lengths[:], a.n)
+	binary.LittleEndian.PutUint64(lengths[8:], uint64(len(ciphertext)))
+	a.poly.Write(lengths[:])
+	a.poly.Sum(tag)
+}
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns a
+// slice with the contents of the given slice followed by that many bytes and
+// a second slice that aliases into it and contains only the extra bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
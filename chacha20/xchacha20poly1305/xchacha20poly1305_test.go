@@ -0,0 +1,133 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package xchacha20poly1305
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/enceve/crypto/cryptotest"
+	"github.com/enceve/crypto/internal/testvec"
+)
+
+// Test vector from draft-irtf-cfrg-xchacha-03 (A.3.1), which is
+// also produced by crypto_aead_xchacha20poly1305_ietf of libsodium.
+var vector = struct {
+	key, nonce, additionalData, plaintext, ciphertext, tag string
+}{
+	key:            "808182838485868788898a8b8c8d8e8f909192939495969798999a9b9c9d9e9f",
+	nonce:          "404142434445464748494a4b4c4d4e4f5051525354555657",
+	additionalData: "50515253c0c1c2c3c4c5c6c7",
+	plaintext: "4c616469657320616e642047656e746c656d656e206f662074686520636c6173" +
+		"73206f66202739393a204966204920636f756c64206f6666657220796f75206f" +
+		"6e6c79206f6e652074697020666f7220746865206675747572652c2073756e73" +
+		"637265656e20776f756c642062652069742e",
+	ciphertext: "bd6d179d3e83d43b9576579493c0e939572a1700252bfaccbed2902c21396cbb" +
+		"731c7f1b0b4aa6440bf3a82f4eda7e39ae64c6708c54c216cb96b72e1213b452" +
+		"2f8c9ba40db5d945b11b69b982c1bb9e3f3fac2bc369488f76b2383565d3fff9" +
+		"21f9664c97637da9768812f615c68b13b52e",
+	tag: "c0875924c1c7987947deafd8780acf49",
+}
+
+func TestVector(t *testing.T) {
+	c, err := New(testvec.MustDecodeHex(vector.key))
+	if err != nil {
+		t.Fatalf("Failed to create XChaCha20-Poly1305 instance: %s", err)
+	}
+	nonce := testvec.MustDecodeHex(vector.nonce)
+	data := testvec.MustDecodeHex(vector.additionalData)
+	plaintext := testvec.MustDecodeHex(vector.plaintext)
+
+	ciphertext := c.Seal(nil, nonce, plaintext, data)
+	if !testvec.EqualHex(ciphertext, vector.ciphertext+vector.tag) {
+		t.Fatalf("Seal returned %x - but expected %s", ciphertext, vector.ciphertext+vector.tag)
+	}
+	decrypted, err := c.Open(nil, nonce, ciphertext, data)
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("Open returned %x - but expected %x", decrypted, plaintext)
+	}
+
+	// the detached decomposition
+	tag := make([]byte, TagSize)
+	body := c.SealDetached(nil, tag, nonce, plaintext, data)
+	if !testvec.EqualHex(body, vector.ciphertext) || !testvec.EqualHex(tag, vector.tag) {
+		t.Fatalf("SealDetached returned %x, %x", body, tag)
+	}
+	decrypted, err = c.OpenDetached(nil, nonce, body, tag, data)
+	if err != nil {
+		t.Fatalf("OpenDetached failed: %s", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("OpenDetached returned %x - but expected %x", decrypted, plaintext)
+	}
+	tag[0] ^= 1
+	if _, err = c.OpenDetached(nil, nonce, body, tag, data); err == nil {
+		t.Fatal("OpenDetached accepted a modified tag")
+	}
+
+	// the additional data written in pieces
+	a := c.NewAD(nonce)
+	a.Write(data[:5])
+	a.Write(nil)
+	a.Write(data[5:])
+	if sealed := a.Seal(nil, plaintext); !bytes.Equal(sealed, ciphertext) {
+		t.Fatalf("AD.Seal returned %x - but expected %x", sealed, ciphertext)
+	}
+	if _, err = a.Write(data); err == nil {
+		t.Fatal("Write succeeded after Seal")
+	}
+	a = c.NewAD(nonce)
+	a.Write(data[:7])
+	a.Write(data[7:])
+	decrypted, err = a.Open(nil, ciphertext)
+	if err != nil {
+		t.Fatalf("AD.Open failed: %s", err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("AD.Open returned %x - but expected %x", decrypted, plaintext)
+	}
+	if _, err = a.Open(nil, ciphertext); err == nil {
+		t.Fatal("AD.Open succeeded twice")
+	}
+}
+
+func TestConformance(t *testing.T) {
+	c, err := New(make([]byte, KeySize))
+	if err != nil {
+		t.Fatalf("Failed to create XChaCha20-Poly1305 instance: %s", err)
+	}
+	cryptotest.ConformanceTestAEAD(t, c)
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := New(make([]byte, 16)); err == nil {
+		t.Fatal("New accepted a 16 byte key")
+	}
+	c, _ := New(make([]byte, KeySize))
+	if _, err := c.Open(nil, make([]byte, 12), make([]byte, TagSize), nil); err == nil {
+		t.Fatal("Open accepted a 12 byte nonce")
+	}
+	if _, err := c.Open(nil, make([]byte, NonceSize), make([]byte, TagSize-1), nil); err == nil {
+		t.Fatal("Open accepted a ciphertext shorter than the tag")
+	}
+
+	mustFail := func(msg string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Expected error: %s", msg)
+			}
+		}()
+		f()
+	}
+	mustFail("nonce is 12 bytes long", func() { c.Seal(nil, make([]byte, 12), nil, nil) })
+	mustFail("tag buffer is too small", func() { c.SealDetached(nil, make([]byte, TagSize-1), make([]byte, NonceSize), nil, nil) })
+	mustFail("AD is already used", func() {
+		a := c.NewAD(make([]byte, NonceSize))
+		a.Seal(nil, nil)
+		a.Seal(nil, nil)
+	})
+}
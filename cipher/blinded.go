@@ -0,0 +1,173 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+	"io"
+
+	"github.com/enceve/crypto"
+)
+
+var errFaultDetected = errors.New("fault detected: redundant EAX computations differ")
+
+// The possible orders of the three OMAC computations of the EAX tag.
+var blindedOrders = [6][3]byte{
+	{nTag, hTag, cTag}, {nTag, cTag, hTag},
+	{hTag, nTag, cTag}, {hTag, cTag, nTag},
+	{cTag, nTag, hTag}, {cTag, hTag, nTag},
+}
+
+// NewEAXBlinded returns a cipher.AEAD implementing EAX like NewEAX, which is
+// hardened against fault attacks on the block cipher. The ciphertexts are
+// equal to the ones of NewEAX and both can open the messages of each other.
+//
+// The OMACs of the nonce, the additional data and the ciphertext are computed
+// in an order chosen randomly (using the rand reader) for every tag. Every
+// value is computed twice: the CTR keystream, the initial counter and the tag
+// (in two independent random orders). Seal panics if the redundant results
+// differ - so a single fault never releases a faulty ciphertext or tag. Open
+// only releases the plaintext if both tags match the tag of the ciphertext,
+// so a single skipped or faulted verification does not accept a forgery.
+// The block cipher itself is not masked - this can only be done by the
+// implementation of the cipher.Block. The blinded EAX is more than twice
+// as slow as EAX and allocates for every Seal and Open call.
+// Seal panics and Open returns an error if reading from rand fails.
+// This function returns a non-nil error if the given block cipher
+// is not supported by CMac, the tag size is invalid or rand is nil.
+func NewEAXBlinded(c cipher.Block, tagsize int, rand io.Reader) (cipher.AEAD, error) {
+	if rand == nil {
+		return nil, errors.New("the random source must not be nil")
+	}
+	eax, err := NewEAX(c, tagsize)
+	if err != nil {
+		return nil, err
+	}
+	return &blindedEAX{
+		eax:     eax,
+		rand:    rand,
+		compare: subtle.ConstantTimeCompare,
+	}, nil
+}
+
+// The fault-attack hardened EAX
+type blindedEAX struct {
+	eax  *EAX
+	rand io.Reader
+
+	// compare is replaced by the tests to simulate faults
+	compare func(x, y []byte) int
+}
+
+func (c *blindedEAX) NonceSize() int { return c.eax.NonceSize() }
+
+func (c *blindedEAX) Overhead() int { return c.eax.Overhead() }
+
+func (c *blindedEAX) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if n := len(nonce); n != c.eax.NonceSize() {
+		panic(crypto.NonceSizeMismatchError{Size: n, Expected: c.eax.NonceSize()})
+	}
+	bs, n := c.eax.blockCipher.BlockSize(), len(plaintext)
+	ret, out := sliceForAppend(dst, n+c.eax.size)
+
+	counter := make([]byte, 3*bs)
+	copy(counter, c.eax.authNonce(nonce))
+	if !c.ctrCrypt(out[:n], plaintext, counter[:bs]) {
+		panic(errFaultDetected)
+	}
+
+	tag := make([]byte, 2*bs)
+	if err := c.tag(tag[:bs], counter[bs:2*bs], nonce, additionalData, out[:n]); err != nil {
+		panic(err)
+	}
+	if err := c.tag(tag[bs:], counter[2*bs:], nonce, additionalData, out[:n]); err != nil {
+		panic(err)
+	}
+	ok := subtle.ConstantTimeCompare(tag[:bs], tag[bs:])
+	ok &= subtle.ConstantTimeCompare(counter[:bs], counter[bs:2*bs])
+	ok &= subtle.ConstantTimeCompare(counter[:bs], counter[2*bs:])
+	if ok != 1 {
+		panic(errFaultDetected)
+	}
+	copy(out[n:], tag[:c.eax.size])
+	return ret
+}
+
+func (c *blindedEAX) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != c.eax.NonceSize() {
+		return nil, crypto.NonceSizeMismatchError{Size: n, Expected: c.eax.NonceSize()}
+	}
+	body, hash, ok := crypto.SplitTag(ciphertext, c.eax.size)
+	if !ok {
+		return nil, ErrCiphertextTooShort
+	}
+	bs := c.eax.blockCipher.BlockSize()
+
+	counter, tag := make([]byte, 2*bs), make([]byte, 2*bs)
+	if err := c.tag(tag[:bs], counter[:bs], nonce, additionalData, body); err != nil {
+		return nil, err
+	}
+	if c.compare(tag[:c.eax.size], hash) != 1 {
+		return nil, crypto.AuthenticationError{Algorithm: "EAX"}
+	}
+	if err := c.tag(tag[bs:], counter[bs:], nonce, additionalData, body); err != nil {
+		return nil, err
+	}
+	if c.compare(tag[bs:bs+c.eax.size], hash) != 1 || c.compare(counter[:bs], counter[bs:]) != 1 {
+		return nil, crypto.AuthenticationError{Algorithm: "EAX"}
+	}
+
+	ret, out := sliceForAppend(dst, len(body))
+	if !c.ctrCrypt(out, body, counter[:bs]) {
+		return nil, errFaultDetected
+	}
+	return ret, nil
+}
+
+// tag computes the (untruncated) EAX tag of the nonce, the additional data
+// and the ciphertext and writes it to t. The OMAC of the nonce is written to
+// authNonce. The three OMACs are computed in a random order.
+func (c *blindedEAX) tag(t, authNonce, nonce, additionalData, ciphertext []byte) error {
+	var r [1]byte
+	for {
+		if _, err := io.ReadFull(c.rand, r[:]); err != nil {
+			return err
+		}
+		if r[0] < 252 { // avoid the modulo bias
+			break
+		}
+	}
+
+	for i := range t {
+		t[i] = 0
+	}
+	for _, step := range blindedOrders[r[0]%6] {
+		var v []byte
+		switch step {
+		case nTag:
+			v = c.eax.authNonce(nonce)
+			copy(authNonce, v)
+		case hTag:
+			v = c.eax.authAdditionalData(additionalData)
+		case cTag:
+			v = c.eax.authCiphertext(ciphertext)
+		}
+		crypto.XOR(t, t, v)
+	}
+	return nil
+}
+
+// ctrCrypt computes the CTR keystream starting at the counter twice and
+// XORs it with src. It returns false if both keystreams differ. The first
+// pass writes into a separate buffer, so dst and src may overlap entirely.
+func (c *blindedEAX) ctrCrypt(dst, src, counter []byte) bool {
+	tmp := make([]byte, len(src))
+	copy(c.eax.ctr, counter)
+	c.eax.ctrCrypt(tmp, src)
+	copy(c.eax.ctr, counter)
+	c.eax.ctrCrypt(dst, src)
+	return subtle.ConstantTimeCompare(tmp, dst) == 1
+}
@@ -0,0 +1,170 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/subtle"
+	"errors"
+	mrand "math/rand"
+	"testing"
+)
+
+// faultyBlock flips a bit of the output of the n-th Encrypt call.
+type faultyBlock struct {
+	cipher.Block
+	calls, n int
+}
+
+func (b *faultyBlock) Encrypt(dst, src []byte) {
+	b.Block.Encrypt(dst, src)
+	b.calls++
+	if b.calls == b.n {
+		dst[0] ^= 1
+	}
+}
+
+type errReader struct{}
+
+func (errReader) Read([]byte) (int, error) { return 0, errors.New("no randomness") }
+
+func TestEAXBlinded(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	ref, err := NewEAX(block, 12)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+	c, err := NewEAXBlinded(block, 12, rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to create blinded EAX instance: %s", err)
+	}
+	if c.NonceSize() != ref.NonceSize() || c.Overhead() != ref.Overhead() {
+		t.Fatalf("NonceSize, Overhead: %d, %d - but expected %d, %d", c.NonceSize(), c.Overhead(), ref.NonceSize(), ref.Overhead())
+	}
+
+	rng := mrand.New(mrand.NewSource(0))
+	nonce := make([]byte, c.NonceSize())
+	for i := 0; i < 100; i++ {
+		msg, data := make([]byte, rng.Intn(100)), make([]byte, rng.Intn(40))
+		rng.Read(nonce)
+		rng.Read(msg)
+		rng.Read(data)
+
+		ciphertext := c.Seal(nil, nonce, msg, data)
+		if expected := ref.Seal(nil, nonce, msg, data); !bytes.Equal(ciphertext, expected) {
+			t.Fatalf("Test %d: Seal returned %x - but expected %x", i, ciphertext, expected)
+		}
+		plaintext, err := c.Open(nil, nonce, ciphertext, data)
+		if err != nil {
+			t.Fatalf("Test %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("Test %d: Open returned %x - but expected %x", i, plaintext, msg)
+		}
+
+		// in place
+		buf := append(make([]byte, 0, len(msg)+c.Overhead()), msg...)
+		if sealed := c.Seal(buf[:0], nonce, buf, data); !bytes.Equal(sealed, ciphertext) {
+			t.Fatalf("Test %d: in-place Seal returned %x - but expected %x", i, sealed, ciphertext)
+		}
+
+		ciphertext[rng.Intn(len(ciphertext))] ^= 1
+		if _, err = c.Open(nil, nonce, ciphertext, data); err == nil {
+			t.Fatalf("Test %d: Open accepted a modified ciphertext", i)
+		}
+	}
+
+	if _, err = c.Open(nil, nonce, make([]byte, c.Overhead()-1), nil); err != ErrCiphertextTooShort {
+		t.Fatalf("Open returned %v - but expected ErrCiphertextTooShort", err)
+	}
+	if _, err = NewEAXBlinded(block, 12, nil); err == nil {
+		t.Fatal("NewEAXBlinded accepted a nil random source")
+	}
+	if _, err = NewEAXBlinded(block, 17, rand.Reader); err == nil {
+		t.Fatal("NewEAXBlinded accepted an invalid tag size")
+	}
+}
+
+func TestEAXBlindedVerificationFault(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	aead, err := NewEAXBlinded(block, 16, rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to create blinded EAX instance: %s", err)
+	}
+	c := aead.(*blindedEAX)
+	nonce, data := make([]byte, c.NonceSize()), []byte("data")
+	forged := c.Seal(nil, nonce, []byte("payload"), data)
+	forged[0] ^= 1
+
+	// the first comparison is skipped by a fault
+	for fault := 1; fault <= 3; fault++ {
+		calls := 0
+		c.compare = func(x, y []byte) int {
+			calls++
+			if calls == fault {
+				return 1
+			}
+			return subtle.ConstantTimeCompare(x, y)
+		}
+		if _, err = c.Open(nil, nonce, forged, data); err == nil {
+			t.Fatalf("Fault in comparison %d: Open accepted a forged ciphertext", fault)
+		}
+	}
+}
+
+func TestEAXBlindedCipherFault(t *testing.T) {
+	aes128, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	block := &faultyBlock{Block: aes128}
+	c, err := NewEAXBlinded(block, 16, rand.Reader)
+	if err != nil {
+		t.Fatalf("Failed to create blinded EAX instance: %s", err)
+	}
+	nonce, msg, data := make([]byte, c.NonceSize()), make([]byte, 40), []byte("data")
+
+	block.calls = 0
+	c.Seal(nil, nonce, msg, data)
+	total := block.calls
+
+	for n := 1; n <= total; n++ {
+		block.calls, block.n = 0, n
+		func() {
+			defer func() {
+				if err := recover(); err != errFaultDetected {
+					t.Fatalf("Fault in Encrypt call %d of %d: Seal recovered %v - but expected %v", n, total, err, errFaultDetected)
+				}
+			}()
+			c.Seal(nil, nonce, msg, data)
+		}()
+	}
+	block.n = 0
+
+	mustFail := func(msg string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Expected error: %s", msg)
+			}
+		}()
+		f()
+	}
+	c, err = NewEAXBlinded(aes128, 16, errReader{})
+	if err != nil {
+		t.Fatalf("Failed to create blinded EAX instance: %s", err)
+	}
+	mustFail("reading from rand fails", func() { c.Seal(nil, nonce, msg, data) })
+	if _, err = c.Open(nil, nonce, make([]byte, 16), data); err == nil {
+		t.Fatal("Open succeeded although reading from rand fails")
+	}
+}
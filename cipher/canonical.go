@@ -0,0 +1,55 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+)
+
+// NewCanonicalAEAD returns a cipher.AEAD wrapping the given AEAD, which
+// passes the additional data through the canon function before it is
+// authenticated - both by Seal and Open. So producers and consumers
+// encoding the same logical additional data differently (e.g. JSON objects
+// with a different order of the keys) authenticate the same bytes, if
+// canon maps all encodings to one canonical encoding. If canon is nil, the
+// additional data is canonicalized by LengthPrefix.
+// The NonceSize and the Overhead of the wrapped AEAD are preserved.
+func NewCanonicalAEAD(inner cipher.AEAD, canon func([]byte) []byte) cipher.AEAD {
+	if canon == nil {
+		canon = LengthPrefix
+	}
+	return &canonicalAEAD{
+		aead:  inner,
+		canon: canon,
+	}
+}
+
+// LengthPrefix returns the additional data prefixed with
+// its length (64 bit big-endian). It is the default
+// canonicalizer of NewCanonicalAEAD.
+func LengthPrefix(additionalData []byte) []byte {
+	out := make([]byte, 8+len(additionalData))
+	binary.BigEndian.PutUint64(out, uint64(len(additionalData)))
+	copy(out[8:], additionalData)
+	return out
+}
+
+// The canonical AEAD wrapper
+type canonicalAEAD struct {
+	aead  cipher.AEAD
+	canon func([]byte) []byte
+}
+
+func (c *canonicalAEAD) NonceSize() int { return c.aead.NonceSize() }
+
+func (c *canonicalAEAD) Overhead() int { return c.aead.Overhead() }
+
+func (c *canonicalAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	return c.aead.Seal(dst, nonce, plaintext, c.canon(additionalData))
+}
+
+func (c *canonicalAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	return c.aead.Open(dst, nonce, ciphertext, c.canon(additionalData))
+}
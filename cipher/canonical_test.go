@@ -0,0 +1,62 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+)
+
+// canonicalJSON re-encodes a JSON object with sorted keys.
+func canonicalJSON(data []byte) []byte {
+	var v map[string]interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return data
+	}
+	canon, err := json.Marshal(v)
+	if err != nil {
+		return data
+	}
+	return canon
+}
+
+func TestCanonicalAEAD(t *testing.T) {
+	c := NewCanonicalAEAD(newTestEAX(t), canonicalJSON)
+	nonce, msg := make([]byte, c.NonceSize()), []byte("canonical")
+
+	ciphertext := c.Seal(nil, nonce, msg, []byte(`{"user":"alice","id":7}`))
+	plaintext, err := c.Open(nil, nonce, ciphertext, []byte(`{"id": 7, "user": "alice"}`))
+	if err != nil {
+		t.Fatalf("Open failed for a different encoding of the additional data: %s", err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Fatalf("Open returned %q - but expected %q", plaintext, msg)
+	}
+	if _, err = c.Open(nil, nonce, ciphertext, []byte(`{"id":8,"user":"alice"}`)); err == nil {
+		t.Fatal("Open accepted different additional data")
+	}
+
+	// the canonicalized additional data is passed to the wrapped AEAD
+	ref := newTestEAX(t).Seal(nil, nonce, msg, []byte(`{"id":7,"user":"alice"}`))
+	if !bytes.Equal(ciphertext, ref) {
+		t.Fatal("Seal did not authenticate the canonical additional data")
+	}
+}
+
+func TestLengthPrefix(t *testing.T) {
+	c := NewCanonicalAEAD(newTestEAX(t), nil)
+	nonce, msg, data := make([]byte, c.NonceSize()), []byte("msg"), []byte("data")
+
+	ciphertext := c.Seal(nil, nonce, msg, data)
+	if ref := newTestEAX(t).Seal(nil, nonce, msg, LengthPrefix(data)); !bytes.Equal(ciphertext, ref) {
+		t.Fatal("NewCanonicalAEAD does not use LengthPrefix by default")
+	}
+	if _, err := c.Open(nil, nonce, ciphertext, data); err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	if p := LengthPrefix([]byte("ab")); !bytes.Equal(p, []byte{0, 0, 0, 0, 0, 0, 0, 2, 'a', 'b'}) {
+		t.Fatalf("LengthPrefix returned %x", p)
+	}
+}
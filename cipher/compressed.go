@@ -0,0 +1,145 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"compress/flate"
+	"crypto/cipher"
+	"errors"
+	"io"
+	"io/ioutil"
+	"math"
+)
+
+const (
+	uncompressed = 0x0 // The plaintext is stored uncompressed
+	compressed   = 0x1 // The plaintext is flate compressed
+)
+
+// DefaultMaxDecompressed is the maximal size of a decompressed
+// plaintext of the AEAD returned by NewCompressedAEAD.
+const DefaultMaxDecompressed = 64 << 20
+
+var errInvalidCompression = errors.New("invalid compressed plaintext")
+
+// ErrDecompressedTooLarge is returned by the Open function of a
+// compressing AEAD if the decompressed plaintext exceeds the
+// maximal size.
+var ErrDecompressedTooLarge = errors.New("decompressed plaintext is too large")
+
+// NewCompressedAEAD returns a cipher.AEAD wrapping the given AEAD, which
+// compresses the plaintext with flate (using the compression level) before
+// sealing it and decompresses it after Open authenticated the ciphertext.
+// A flag byte prepended to the plaintext marks if the plaintext is stored
+// compressed or - if compression does not reduce its size - uncompressed.
+// So the Overhead is the Overhead of the wrapped AEAD plus one byte.
+// The compressed plaintext (including its length) is authenticated
+// by the wrapped AEAD.
+//
+// WARNING: The length of the ciphertext reveals how well the plaintext
+// compresses. If an attacker can influence parts of the plaintext, which
+// also contains secrets, the length of the ciphertexts leak the secrets
+// (see the CRIME and BREACH attacks). Only use this AEAD if attackers
+// cannot inject data into plaintexts containing secrets.
+//
+// Open decompresses at most DefaultMaxDecompressed bytes - a small
+// compressed plaintext can expand to a huge plaintext, so the size
+// must be bounded. Use NewCompressedAEADMax for another limit.
+//
+// If the compression level is invalid (see compress/flate)
+// this function panics.
+func NewCompressedAEAD(inner cipher.AEAD, level int) cipher.AEAD {
+	return NewCompressedAEADMax(inner, level, DefaultMaxDecompressed)
+}
+
+// NewCompressedAEADMax works like NewCompressedAEAD but Open decompresses
+// at most maxDecompressed bytes and returns ErrDecompressedTooLarge for
+// larger plaintexts. Seal stores plaintexts larger than maxDecompressed
+// uncompressed, so every sealed plaintext can be opened.
+// If the compression level is invalid (see compress/flate) or
+// maxDecompressed is negative this function panics.
+func NewCompressedAEADMax(inner cipher.AEAD, level int, maxDecompressed int64) cipher.AEAD {
+	if _, err := flate.NewWriter(ioutil.Discard, level); err != nil {
+		panic(err)
+	}
+	if maxDecompressed < 0 {
+		panic("maximal decompressed size must not be negative")
+	}
+	return &compressedAEAD{
+		aead:  inner,
+		level: level,
+		max:   maxDecompressed,
+	}
+}
+
+// The compressing AEAD wrapper
+type compressedAEAD struct {
+	aead  cipher.AEAD
+	level int
+	max   int64 // the maximal size of a decompressed plaintext
+}
+
+func (c *compressedAEAD) NonceSize() int { return c.aead.NonceSize() }
+
+func (c *compressedAEAD) Overhead() int { return c.aead.Overhead() + 1 }
+
+func (c *compressedAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	var buf bytes.Buffer
+	if err := c.compress(&buf, plaintext); err != nil || buf.Len() > len(plaintext) {
+		buf.Reset()
+		buf.WriteByte(uncompressed)
+		buf.Write(plaintext)
+	}
+	return c.aead.Seal(dst, nonce, buf.Bytes(), additionalData)
+}
+
+// compress writes the flag byte and the compressed plaintext to buf.
+// It returns a non-nil error if the plaintext is too large to be
+// decompressed by Open or the compression fails.
+func (c *compressedAEAD) compress(buf *bytes.Buffer, plaintext []byte) error {
+	if int64(len(plaintext)) > c.max {
+		return ErrDecompressedTooLarge
+	}
+	buf.WriteByte(compressed)
+	w, err := flate.NewWriter(buf, c.level)
+	if err != nil {
+		return err
+	}
+	if _, err = w.Write(plaintext); err != nil {
+		return err
+	}
+	return w.Close()
+}
+
+func (c *compressedAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	plaintext, err := c.aead.Open(nil, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	if len(plaintext) == 0 {
+		return nil, errInvalidCompression
+	}
+	switch plaintext[0] {
+	case uncompressed:
+		return append(dst, plaintext[1:]...), nil
+	case compressed:
+		out := bytes.NewBuffer(dst)
+		r := flate.NewReader(bytes.NewReader(plaintext[1:]))
+		limit := c.max
+		if limit < math.MaxInt64 {
+			limit++ // read one more byte to detect larger plaintexts
+		}
+		n, err := io.Copy(out, io.LimitReader(r, limit))
+		if err != nil {
+			return nil, errInvalidCompression
+		}
+		if n > c.max {
+			return nil, ErrDecompressedTooLarge
+		}
+		return out.Bytes(), nil
+	default:
+		return nil, errInvalidCompression
+	}
+}
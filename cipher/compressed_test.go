@@ -0,0 +1,88 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"compress/flate"
+	"math/rand"
+	"testing"
+)
+
+func TestCompressedAEAD(t *testing.T) {
+	eax := newTestEAX(t)
+	c := NewCompressedAEAD(eax, flate.BestCompression)
+	if c.NonceSize() != eax.NonceSize() || c.Overhead() != eax.Overhead()+1 {
+		t.Fatalf("NonceSize %d and Overhead %d - but expected %d and %d", c.NonceSize(), c.Overhead(), eax.NonceSize(), eax.Overhead()+1)
+	}
+	nonce, data := make([]byte, c.NonceSize()), []byte("data")
+
+	random := make([]byte, 64*1024)
+	rand.New(rand.NewSource(0)).Read(random)
+	for name, msg := range map[string][]byte{
+		"empty":          nil,
+		"one byte":       {'a'},
+		"compressible":   bytes.Repeat([]byte("compress me "), 1000),
+		"incompressible": random,
+	} {
+		ciphertext := c.Seal(nil, nonce, msg, data)
+		if len(ciphertext) > len(msg)+c.Overhead() {
+			t.Fatalf("%s: Seal returned %d bytes for %d bytes plaintext", name, len(ciphertext), len(msg))
+		}
+		plaintext, err := c.Open([]byte("prefix"), nonce, ciphertext, data)
+		if err != nil {
+			t.Fatalf("%s: Open failed: %s", name, err)
+		}
+		if !bytes.Equal(plaintext, append([]byte("prefix"), msg...)) {
+			t.Fatalf("%s: Open returned a wrong plaintext", name)
+		}
+
+		ciphertext[0] ^= 1
+		if _, err = c.Open(nil, nonce, ciphertext, data); err == nil {
+			t.Fatalf("%s: Open accepted a modified ciphertext", name)
+		}
+	}
+
+	msg := bytes.Repeat([]byte("compress me "), 1000)
+	if n := len(c.Seal(nil, nonce, msg, data)); n >= len(msg)/10 {
+		t.Fatalf("Seal returned %d bytes for %d bytes compressible plaintext", n, len(msg))
+	}
+
+	// a plaintext without valid flag byte is rejected
+	for _, plaintext := range [][]byte{nil, {2, 'a'}, {compressed, 0xff, 0xff}} {
+		ciphertext := eax.Seal(nil, nonce, plaintext, data)
+		if _, err := c.Open(nil, nonce, ciphertext, data); err == nil {
+			t.Fatalf("Open accepted the invalid compressed plaintext %x", plaintext)
+		}
+	}
+
+	// a compressed plaintext must not expand beyond the maximal size
+	limited := NewCompressedAEADMax(eax, flate.BestCompression, int64(len(msg)))
+	long := append(append([]byte{}, msg...), 'x')
+	var bomb bytes.Buffer
+	bomb.WriteByte(compressed)
+	w, _ := flate.NewWriter(&bomb, flate.BestCompression)
+	w.Write(long)
+	w.Close()
+	if _, err := limited.Open(nil, nonce, eax.Seal(nil, nonce, bomb.Bytes(), data), data); err != ErrDecompressedTooLarge {
+		t.Fatalf("Open returned %v for a too large plaintext - but expected %v", err, ErrDecompressedTooLarge)
+	}
+	if plaintext, err := limited.Open(nil, nonce, limited.Seal(nil, nonce, msg, data), data); err != nil || !bytes.Equal(plaintext, msg) {
+		t.Fatalf("Open of a plaintext of the maximal size failed: %v", err)
+	}
+	ciphertext := limited.Seal(nil, nonce, long, data)
+	if len(ciphertext) != len(long)+limited.Overhead() {
+		t.Fatal("Seal compressed a plaintext larger than the maximal size")
+	}
+	if plaintext, err := limited.Open(nil, nonce, ciphertext, data); err != nil || !bytes.Equal(plaintext, long) {
+		t.Fatalf("Open of a plaintext larger than the maximal size failed: %v", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewCompressedAEAD accepted an invalid compression level")
+		}
+	}()
+	NewCompressedAEAD(eax, 42)
+}
@@ -0,0 +1,71 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/sha256"
+	"encoding/binary"
+
+	"github.com/enceve/crypto"
+)
+
+// NewContextAEAD returns a cipher.AEAD wrapping the given AEAD, which mixes
+// the context into every nonce. The nonce passed to the wrapped AEAD is the
+// nonce XOR a mask derived from the context using SHA-256:
+//
+//	nonce' = nonce XOR SHA-256(0 || context) || SHA-256(1 || context) || ...
+//
+// (the counter is encoded as 32 bit big-endian and the mask is truncated to
+// the nonce size). Within one context the mapping is a permutation of the
+// nonces, so unique nonces stay unique. Protocol contexts sharing one key use
+// different nonces for the same nonce argument - e.g. the message counters
+// of two contexts do not collide. The NonceSize and the Overhead of the
+// wrapped AEAD are preserved.
+func NewContextAEAD(inner cipher.AEAD, context []byte) cipher.AEAD {
+	mask := make([]byte, 0, inner.NonceSize()+sha256.Size)
+	var ctr [4]byte
+	for i := uint32(0); len(mask) < inner.NonceSize(); i++ {
+		binary.BigEndian.PutUint32(ctr[:], i)
+		h := sha256.New()
+		h.Write(ctr[:])
+		h.Write(context)
+		mask = h.Sum(mask)
+	}
+	return &contextAEAD{
+		aead: inner,
+		mask: mask[:inner.NonceSize()],
+	}
+}
+
+// The context AEAD wrapper
+type contextAEAD struct {
+	aead cipher.AEAD
+	mask []byte
+}
+
+func (c *contextAEAD) NonceSize() int { return c.aead.NonceSize() }
+
+func (c *contextAEAD) Overhead() int { return c.aead.Overhead() }
+
+func (c *contextAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if n := len(nonce); n != c.aead.NonceSize() {
+		panic(crypto.NonceSizeMismatchError{Size: n, Expected: c.aead.NonceSize()})
+	}
+	return c.aead.Seal(dst, c.contextNonce(nonce), plaintext, additionalData)
+}
+
+func (c *contextAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != c.aead.NonceSize() {
+		return nil, crypto.NonceSizeMismatchError{Size: n, Expected: c.aead.NonceSize()}
+	}
+	return c.aead.Open(dst, c.contextNonce(nonce), ciphertext, additionalData)
+}
+
+// contextNonce returns the nonce XOR the context mask.
+func (c *contextAEAD) contextNonce(nonce []byte) []byte {
+	masked := make([]byte, len(nonce))
+	crypto.XOR(masked, nonce, c.mask)
+	return masked
+}
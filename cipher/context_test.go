@@ -0,0 +1,70 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestContextAEAD(t *testing.T) {
+	inner := newTestEAX(t)
+	c0 := NewContextAEAD(inner, []byte("context 0"))
+	c1 := NewContextAEAD(inner, []byte("context 1"))
+
+	if c0.NonceSize() != inner.NonceSize() || c0.Overhead() != inner.Overhead() {
+		t.Fatal("NewContextAEAD changed the nonce size or the overhead")
+	}
+
+	nonce, msg, data := make([]byte, inner.NonceSize()), []byte("context message"), []byte("data")
+	ct0 := c0.Seal(nil, nonce, msg, data)
+	ct1 := c1.Seal(nil, nonce, msg, data)
+	ct := inner.Seal(nil, nonce, msg, data)
+	if bytes.Equal(ct0, ct1) || bytes.Equal(ct0, ct) || bytes.Equal(ct1, ct) {
+		t.Fatal("Different contexts produce the same ciphertext for the same nonce")
+	}
+
+	plaintext, err := c0.Open(nil, nonce, ct0, data)
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Fatalf("Open returned %q - but expected %q", plaintext, msg)
+	}
+	if _, err = c1.Open(nil, nonce, ct0, data); err == nil {
+		t.Fatal("Open accepted a ciphertext of another context")
+	}
+	if _, err = inner.Open(nil, nonce, ct0, data); err == nil {
+		t.Fatal("The inner AEAD accepted a ciphertext of a context with the same nonce")
+	}
+
+	// the same context string produces the same nonces
+	if ct := NewContextAEAD(inner, []byte("context 0")).Seal(nil, nonce, msg, data); !bytes.Equal(ct, ct0) {
+		t.Fatal("The same context produces different ciphertexts")
+	}
+
+	// unique nonces stay unique within one context
+	seen := make(map[string]bool)
+	for i := 0; i < 256; i++ {
+		nonce[len(nonce)-1] = byte(i)
+		ct := string(c0.Seal(nil, nonce, nil, nil))
+		if seen[ct] {
+			t.Fatalf("Nonce %d: The context nonce is not unique", i)
+		}
+		seen[ct] = true
+	}
+}
+
+func TestContextAEADLongNonce(t *testing.T) {
+	inner, err := NewEAX(dummyBlock(64), 16)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+	c := NewContextAEAD(inner, []byte("context"))
+	nonce := make([]byte, c.NonceSize())
+	ct := c.Seal(nil, nonce, []byte("msg"), nil)
+	if _, err = c.Open(nil, nonce, ct, nil); err != nil {
+		t.Fatalf("Open failed for a %d byte nonce: %s", len(nonce), err)
+	}
+}
@@ -0,0 +1,87 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/internal/alias"
+)
+
+// NewCTRWithCounterBits returns a cipher.Stream implementing the CTR mode
+// like crypto/cipher.NewCTR, but only the low counterBits bits of the
+// counter block (big-endian) are incremented. The leading bits of the iv
+// are a fixed prefix and the counter field wraps around to zero without
+// carrying into the prefix - e.g. GCM uses a 32 bit counter field:
+//
+//	NewCTRWithCounterBits(block, iv, 32)
+//
+// With counterBits = 8 * BlockSize() the stream is equal to crypto/cipher.NewCTR.
+// The keystream repeats after 2^counterBits blocks, so the caller must not
+// encrypt more blocks with one iv. If the iv is not BlockSize() bytes long or
+// counterBits is not between 1 and 8 * BlockSize() this function panics.
+func NewCTRWithCounterBits(c cipher.Block, iv []byte, counterBits int) cipher.Stream {
+	bs := c.BlockSize()
+	if len(iv) != bs {
+		panic("IV length must equal block size")
+	}
+	if counterBits < 1 || counterBits > 8*bs {
+		panic("counterBits must be between 1 and 8 * BlockSize()")
+	}
+	s := &ctrBits{
+		block:     c,
+		bits:      counterBits,
+		ctr:       make([]byte, bs),
+		keystream: make([]byte, bs),
+		off:       bs,
+	}
+	copy(s.ctr, iv)
+	return s
+}
+
+// The CTR mode with a counter field of bits bits
+type ctrBits struct {
+	block          cipher.Block
+	bits           int
+	ctr, keystream []byte
+	off            int
+}
+
+func (s *ctrBits) XORKeyStream(dst, src []byte) {
+	if len(dst) < len(src) {
+		panic("output smaller than input")
+	}
+	if alias.InexactOverlap(dst[:len(src)], src) {
+		panic("invalid buffer overlap")
+	}
+	for len(src) > 0 {
+		if s.off == len(s.keystream) {
+			s.block.Encrypt(s.keystream, s.ctr)
+			incrementBits(s.ctr, s.bits)
+			s.off = 0
+		}
+		n := crypto.XOR(dst, src, s.keystream[s.off:])
+		s.off += n
+		dst, src = dst[n:], src[n:]
+	}
+}
+
+// incrementBits increments the low bits bits of the big-endian
+// number ctr by one - the other bits are not changed. The
+// execution time only depends on bits.
+func incrementBits(ctr []byte, bits int) {
+	carry := uint16(1)
+	i := len(ctr) - 1
+	for ; bits >= 8; bits -= 8 {
+		carry += uint16(ctr[i])
+		ctr[i] = byte(carry)
+		carry >>= 8
+		i--
+	}
+	if bits > 0 {
+		mask := byte(1)<<uint(bits) - 1
+		ctr[i] = ctr[i]&^mask | (ctr[i]+byte(carry))&mask
+	}
+}
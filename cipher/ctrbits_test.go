@@ -0,0 +1,100 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"testing"
+
+	"github.com/enceve/crypto/internal/testvec"
+)
+
+func TestIncrementBits(t *testing.T) {
+	for i, v := range []struct {
+		ctr  string
+		bits int
+		next string
+	}{
+		{"aaaaaaaa00000000", 32, "aaaaaaaa00000001"},
+		{"aaaaaaaa000000ff", 32, "aaaaaaaa00000100"},
+		{"aaaaaaaaffffffff", 32, "aaaaaaaa00000000"},
+		{"aaaaaaafffffffff", 36, "aaaaaaa000000000"},
+		{"aaaaaaa7ffffffff", 35, "aaaaaaa000000000"},
+		{"aaaaaaa8ffffffff", 36, "aaaaaaa900000000"},
+		{"aaaaaaaaaaaaaaff", 1, "aaaaaaaaaaaaaafe"},
+		{"ffffffffffffffff", 64, "0000000000000000"},
+	} {
+		ctr := testvec.MustDecodeHex(v.ctr)
+		incrementBits(ctr, v.bits)
+		if !testvec.EqualHex(ctr, v.next) {
+			t.Fatalf("Test %d: incrementBits returned %x - but expected %s", i, ctr, v.next)
+		}
+	}
+}
+
+func TestCTRWithCounterBits(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	src := make([]byte, 5*16+7)
+	for i := range src {
+		src[i] = byte(i)
+	}
+
+	// far from the boundary of the counter field the stream is
+	// equal to the CTR mode - written in chunks of different sizes
+	iv := testvec.MustDecodeHex("000102030405060708090a0b00000001")
+	dst, expected := make([]byte, len(src)), make([]byte, len(src))
+	s := NewCTRWithCounterBits(block, iv, 32)
+	s.XORKeyStream(dst[:3], src[:3])
+	s.XORKeyStream(dst[3:40], src[3:40])
+	s.XORKeyStream(dst[40:], src[40:])
+	cipher.NewCTR(block, iv).XORKeyStream(expected, src)
+	if !bytes.Equal(dst, expected) {
+		t.Fatal("NewCTRWithCounterBits differs from the CTR mode")
+	}
+
+	// the 32 bit counter field wraps without carry into the prefix
+	iv = testvec.MustDecodeHex("000102030405060708090a0bfffffffe")
+	NewCTRWithCounterBits(block, iv, 32).XORKeyStream(dst, src)
+	for i, ctr := range []string{
+		"000102030405060708090a0bfffffffe",
+		"000102030405060708090a0bffffffff",
+		"000102030405060708090a0b00000000",
+		"000102030405060708090a0b00000001",
+	} {
+		keystream := make([]byte, 16)
+		block.Encrypt(keystream, testvec.MustDecodeHex(ctr))
+		for j := range keystream {
+			keystream[j] ^= src[16*i+j]
+		}
+		if !bytes.Equal(dst[16*i:16*(i+1)], keystream) {
+			t.Fatalf("Block %d: keystream was not generated from counter %s", i, ctr)
+		}
+	}
+
+	// a full counter field is equal to the CTR mode
+	iv = testvec.MustDecodeHex("ffffffffffffffffffffffffffffffff")
+	NewCTRWithCounterBits(block, iv, 128).XORKeyStream(dst, src)
+	cipher.NewCTR(block, iv).XORKeyStream(expected, src)
+	if !bytes.Equal(dst, expected) {
+		t.Fatal("NewCTRWithCounterBits with 128 bits differs from the CTR mode")
+	}
+
+	mustFail := func(msg string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Expected error: %s", msg)
+			}
+		}()
+		f()
+	}
+	mustFail("iv is too short", func() { NewCTRWithCounterBits(block, iv[1:], 32) })
+	mustFail("counterBits is 0", func() { NewCTRWithCounterBits(block, iv, 0) })
+	mustFail("counterBits is 129", func() { NewCTRWithCounterBits(block, iv, 129) })
+	mustFail("dst is too small", func() { NewCTRWithCounterBits(block, iv, 32).XORKeyStream(dst[:1], src) })
+}
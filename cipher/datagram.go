@@ -0,0 +1,86 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+)
+
+// The max. sequence number of a DatagramAEAD record (48 bit)
+const maxDatagramSequence = 1<<48 - 1
+
+var errDatagramSequence = errors.New("sequence number must be less than 2^48")
+
+// DatagramAEAD implements the per-record nonces of datagram transports like
+// DTLS (RFC 7905, RFC 6655). The nonce of a record is the 2 byte epoch and the
+// 6 byte sequence number (big-endian), left-padded with zeros to the nonce size
+// and XORed with the per-connection salt:
+//
+//	nonce = salt XOR (0 || epoch (16 bit) || sequence number (48 bit))
+//
+// This is the same construction as the record nonce of TLS 1.3, where the
+// 64 bit sequence number consists of the epoch and the sequence number.
+// Every (epoch, sequence number) pair must be used for only one record.
+type DatagramAEAD struct {
+	aead cipher.AEAD
+	salt []byte
+}
+
+// NewDatagramAEAD returns a new DatagramAEAD using the given AEAD and salt.
+// The salt must be as long as the nonce of the AEAD, which must be at least
+// 8 bytes long. If the salt size is invalid this function panics.
+func NewDatagramAEAD(inner cipher.AEAD, salt []byte) *DatagramAEAD {
+	if n := inner.NonceSize(); n < 8 {
+		panic("nonce size of the AEAD must be at least 8 bytes")
+	}
+	if len(salt) != inner.NonceSize() {
+		panic("salt must be as long as the AEAD nonce")
+	}
+	d := &DatagramAEAD{
+		aead: inner,
+		salt: make([]byte, len(salt)),
+	}
+	copy(d.salt, salt)
+	return d
+}
+
+// Overhead returns the overhead of every record.
+func (d *DatagramAEAD) Overhead() int { return d.aead.Overhead() }
+
+// Seal encrypts and authenticates the plaintext of the record with the given
+// epoch and sequence number and appends the result to dst.
+// If the sequence number is not less than 2^48 this function panics.
+func (d *DatagramAEAD) Seal(epoch uint16, seq uint64, dst, plaintext, additionalData []byte) []byte {
+	if seq > maxDatagramSequence {
+		panic(errDatagramSequence)
+	}
+	return d.aead.Seal(dst, d.recordNonce(epoch, seq), plaintext, additionalData)
+}
+
+// Open decrypts and authenticates the ciphertext of the record with the given
+// epoch and sequence number and appends the plaintext to dst.
+// Open returns a non-nil error if the sequence number is not less than 2^48
+// or the authentication fails.
+func (d *DatagramAEAD) Open(epoch uint16, seq uint64, dst, ciphertext, additionalData []byte) ([]byte, error) {
+	if seq > maxDatagramSequence {
+		return nil, errDatagramSequence
+	}
+	return d.aead.Open(dst, d.recordNonce(epoch, seq), ciphertext, additionalData)
+}
+
+// recordNonce returns the nonce of the record with the
+// given epoch and sequence number.
+func (d *DatagramAEAD) recordNonce(epoch uint16, seq uint64) []byte {
+	nonce := make([]byte, len(d.salt))
+	copy(nonce, d.salt)
+	var ctr [8]byte
+	binary.BigEndian.PutUint64(ctr[:], uint64(epoch)<<48|seq)
+	n := len(nonce) - 8
+	for i := range ctr {
+		nonce[n+i] ^= ctr[i]
+	}
+	return nonce
+}
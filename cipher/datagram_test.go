@@ -0,0 +1,97 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"testing"
+
+	"github.com/enceve/crypto/chacha20"
+	"github.com/enceve/crypto/internal/testvec"
+)
+
+// recordAEAD simulates an AEAD and returns the nonce as ciphertext
+type recordAEAD int
+
+func (r recordAEAD) NonceSize() int { return int(r) }
+
+func (r recordAEAD) Overhead() int { return int(r) }
+
+func (r recordAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	return append(dst, nonce...)
+}
+
+func (r recordAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	return append(dst, nonce...), nil
+}
+
+func TestDatagramNonce(t *testing.T) {
+	salt := testvec.MustDecodeHex("000102030405060708090a0b")
+	d := NewDatagramAEAD(recordAEAD(12), salt)
+	for _, v := range []struct {
+		epoch uint16
+		seq   uint64
+		nonce string
+	}{
+		{0, 0, "000102030405060708090a0b"},
+		{0, 1, "000102030405060708090a0a"},
+		{1, 0, "000102030404060708090a0b"},
+		{0xffff, maxDatagramSequence, "00010203fbfaf9f8f7f6f5f4"},
+	} {
+		if nonce := d.Seal(v.epoch, v.seq, nil, nil, nil); !testvec.EqualHex(nonce, v.nonce) {
+			t.Fatalf("Epoch %d - sequence %d: nonce is %x - but expected %s", v.epoch, v.seq, nonce, v.nonce)
+		}
+	}
+	salt[0] = 0xff
+	if nonce := d.Seal(0, 0, nil, nil, nil); nonce[0] != 0 {
+		t.Fatal("NewDatagramAEAD did not copy the salt")
+	}
+
+	if _, err := d.Open(0, maxDatagramSequence+1, nil, nil, nil); err == nil {
+		t.Fatal("Open accepted a sequence number of 2^48")
+	}
+	mustFail := func(msg string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Expected error: %s", msg)
+			}
+		}()
+		f()
+	}
+	mustFail("sequence number is 2^48", func() { d.Seal(0, maxDatagramSequence+1, nil, nil, nil) })
+	mustFail("salt is too short", func() { NewDatagramAEAD(recordAEAD(12), salt[1:]) })
+	mustFail("nonce is too short", func() { NewDatagramAEAD(recordAEAD(7), salt[:7]) })
+}
+
+// The ChaCha20-Poly1305 record protection test vector of RFC 9001 (QUIC):
+// https://tools.ietf.org/html/rfc9001#appendix-A.5
+// QUIC and TLS 1.3 derive the nonce from the 64 bit packet (or record) number
+// like DatagramAEAD.
+func TestDatagramVector(t *testing.T) {
+	var key [32]byte
+	copy(key[:], testvec.MustDecodeHex("c6d98ff3441c3fe1b2182094f69caa2ed4b716b65488960a7a984979fb23e1c8"))
+	var aead cipher.AEAD = chacha20.NewChaCha20Poly1305(&key)
+	d := NewDatagramAEAD(aead, testvec.MustDecodeHex("e0459b3474bdd0e44a41c144"))
+
+	const seq = 654360564
+	header, msg := testvec.MustDecodeHex("4200bff4"), []byte{0x01}
+	ciphertext := d.Seal(0, seq, nil, msg, header)
+	if expected := "655e5cd55c41f69080575d7999c25a5bfb"; !testvec.EqualHex(ciphertext, expected) {
+		t.Fatalf("Seal returned %x - but expected %s", ciphertext, expected)
+	}
+	plaintext, err := d.Open(0, seq, nil, ciphertext, header)
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Fatalf("Open returned %x - but expected %x", plaintext, msg)
+	}
+	if _, err = d.Open(0, seq+1, nil, ciphertext, header); err == nil {
+		t.Fatal("Open accepted a wrong sequence number")
+	}
+	if _, err = d.Open(1, seq, nil, ciphertext, header); err == nil {
+		t.Fatal("Open accepted a wrong epoch")
+	}
+}
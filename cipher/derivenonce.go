@@ -0,0 +1,42 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+
+	"github.com/enceve/crypto/cmac"
+)
+
+// The OMAC tag constant of DeriveNonce - distinct from the tags used by EAX
+const dTag = 0x3
+
+// DeriveNonce derives a deterministic but unpredictable nonce of size bytes
+// from a unique input (e.g. a row ID) by computing its OMAC under the block
+// cipher and truncating it to size bytes:
+//
+//	nonce = OMAC^3(rowID)[:size]
+//
+// The tag 3 separates the derived nonces from the OMAC values of EAX, so
+// the block cipher may also be used for EAX. Distinct row IDs produce
+// distinct nonces unless the truncated OMAC values collide, which happens
+// after about 2^(4*size) row IDs - so size should be as large as possible.
+// If size is not between 1 and the block size of the cipher or the block
+// cipher is not supported by CMac (see crypto/cmac for details) this
+// function panics.
+func DeriveNonce(c cipher.Block, rowID []byte, size int) []byte {
+	bs := c.BlockSize()
+	if size < 1 || size > bs {
+		panic("nonce size must be between 1 and BlockSize() of the given cipher")
+	}
+	mac, err := cmac.New(c)
+	if err != nil {
+		panic(err)
+	}
+	tag := make([]byte, bs)
+	tag[bs-1] = dTag
+	mac.Write(tag)
+	mac.Write(rowID)
+	return mac.Sum(tag[:0])[:size]
+}
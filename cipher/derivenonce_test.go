@@ -0,0 +1,61 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/binary"
+	"testing"
+)
+
+func TestDeriveNonce(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	for _, size := range []int{1, 8, 12, 16} {
+		nonces := make(map[string]bool)
+		rowID := make([]byte, 8)
+		n := 1000
+		if size == 1 {
+			n = 16 // 256 possible nonces
+		}
+		for i := 0; i < n; i++ {
+			binary.BigEndian.PutUint64(rowID, uint64(i))
+			nonce := DeriveNonce(block, rowID, size)
+			if len(nonce) != size {
+				t.Fatalf("Size %d: DeriveNonce returned %d bytes", size, len(nonce))
+			}
+			if nonces[string(nonce)] {
+				t.Fatalf("Size %d: DeriveNonce returned the same nonce for row %d and a previous row", size, i)
+			}
+			nonces[string(nonce)] = true
+			if !bytes.Equal(nonce, DeriveNonce(block, rowID, size)) {
+				t.Fatalf("Size %d: DeriveNonce is not deterministic", size)
+			}
+		}
+	}
+
+	// the derived nonces are no EAX OMAC values
+	rowID := make([]byte, block.BlockSize())
+	counter, err := EAXNonceToCounter(block, rowID)
+	if err != nil {
+		t.Fatalf("EAXNonceToCounter failed: %s", err)
+	}
+	if bytes.Equal(DeriveNonce(block, rowID, 16), counter) {
+		t.Fatal("DeriveNonce is equal to the EAX nonce OMAC")
+	}
+
+	mustFail := func(msg string, size int) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Expected error: %s", msg)
+			}
+		}()
+		DeriveNonce(block, rowID, size)
+	}
+	mustFail("size is 0", 0)
+	mustFail("size is greater than the block size", 17)
+}
@@ -7,10 +7,11 @@
 package cipher
 
 import (
+	"crypto/aes"
 	"crypto/cipher"
 	"crypto/subtle"
 	"errors"
-	"hash"
+	"math"
 
 	"github.com/enceve/crypto"
 	"github.com/enceve/crypto/cmac"
@@ -22,160 +23,605 @@ const (
 	cTag = 0x2 // The ciphertext tag constant
 )
 
-// The EAX cipher
-type eaxCipher struct {
-	blockCipher cipher.Block
-	ctr, block  []byte
-	mac         hash.Hash
-	size        int
+var errTagSize = errors.New("tagSize must between 1 and BlockSize() of the given cipher")
+
+// ErrCiphertextTooShort is returned by the Open methods of EAX if the
+// ciphertext is shorter than the tag. Such a ciphertext is obviously
+// malformed (e.g. misframed by the caller) - in contrast to a ciphertext
+// with a wrong tag, which causes a crypto.AuthenticationError. The type
+// of ErrCiphertextTooShort differs from crypto.AuthenticationError to
+// help diagnosing integration bugs. In security-sensitive contexts
+// callers should treat both errors identically and must not report the
+// difference to the sender.
+var ErrCiphertextTooShort error = ciphertextTooShortError{}
+
+type ciphertextTooShortError struct{}
+
+func (ciphertextTooShortError) Error() string {
+	return "ciphertext is shorter than the tag"
+}
+
+func init() {
+	crypto.DefaultRegistry.Register(crypto.AESEAX, "AES-EAX", func(key []byte) (cipher.AEAD, error) {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return NewEAX(block, block.BlockSize())
+	})
+}
+
+// EAX implements the EAX AEAD mode of operation.
+// An EAX instance is not safe for concurrent use.
+type EAX struct {
+	blockCipher  cipher.Block
+	ctr, block   []byte
+	mac          *cmac.Hash
+	size         int
+	nonceSize    int
+	littleEndian bool
+	bindTagSize  bool
+	ctrOffset    uint32
+	nonces       *NonceSequence
+
+	// scratch buffers for the OMAC computations
+	tagBlock, authNonceBuf, authData, authCt []byte
 }
 
-// NewEAX returns a cipher.AEAD wrapping the cipher.Block.
+// NewEAX returns a *EAX implementing cipher.AEAD wrapping the cipher.Block.
 // EAX is a two pass-scheme AEAD cipher with provable security.
 // For authentication EAX uses CMac (OMAC1).
 // The tagsize argument specifies the number of bytes of the auth. tag
 // and must be between 1 and the block size of the cipher.
 // This function returns a non-nil error if the given block cipher
 // is not supported by CMac (see crypto/cmac for details)
-func NewEAX(c cipher.Block, tagsize int) (cipher.AEAD, error) {
+//
+// The CTR counter block is treated as one big-endian number:
+// the last byte is incremented first and carries propagate towards
+// the first byte - e.g. ...00fe, ...00ff, ...0100, ...0101.
+// This matches the EAX specification and crypto/cipher.NewCTR.
+//
+// The tag size must be fixed for one key and agreed on out-of-band.
+// An EAX tag truncated to n bytes is a valid tag for tag size n, so
+// if the receiver accepts different tag sizes for the same key an
+// attacker can truncate the tag. Use NewEAXBindTagSize if the tag size
+// cannot be fixed.
+func NewEAX(c cipher.Block, tagsize int) (*EAX, error) {
 	m, err := cmac.New(c)
 	if err != nil {
 		return nil, err
 	}
 	if tagsize < 1 || tagsize > c.BlockSize() {
-		return nil, errors.New("tagSize must between 1 and BlockSize() of the given cipher")
+		return nil, errTagSize
 	}
-	return &eaxCipher{
+	eax := &EAX{
 		blockCipher: c,
 		mac:         m,
-		ctr:         make([]byte, c.BlockSize()),
-		block:       make([]byte, c.BlockSize()),
 		size:        tagsize,
-	}, nil
+		nonceSize:   c.BlockSize(),
+	}
+	eax.allocBuffers()
+	return eax, nil
 }
 
-func (c *eaxCipher) NonceSize() int { return c.blockCipher.BlockSize() }
+// NewEAXNoTag returns a *EAX like NewEAX, but WITHOUT any authentication.
+// Seal only encrypts the plaintext with the CTR mode - starting at the counter
+// derived from the nonce as EAX does - and does not append a tag. Open only
+// decrypts the ciphertext and never returns an error for a valid nonce size.
+// The additional data is ignored. This is UNAUTHENTICATED encryption: it is only
+// secure if the ciphertext (and the nonce and the additional data) is protected
+// by an outer MAC, which must be verified before calling Open.
+// This function returns a non-nil error if the given block cipher
+// is not supported by CMac (see crypto/cmac for details).
+func NewEAXNoTag(c cipher.Block) (*EAX, error) {
+	eax, err := NewEAX(c, c.BlockSize())
+	if err != nil {
+		return nil, err
+	}
+	eax.size = 0
+	return eax, nil
+}
 
-func (c *eaxCipher) Overhead() int { return c.size }
+// NewEAXBindTagSize returns a *EAX like NewEAX, but the tag size is
+// included in the OMAC of the nonce. So a tag computed for one tag size
+// is not a valid (truncated) tag for any other tag size.
+// This is NOT the standard EAX mode and is not compatible with NewEAX.
+func NewEAXBindTagSize(c cipher.Block, tagsize int) (*EAX, error) {
+	eax, err := NewEAX(c, tagsize)
+	if err != nil {
+		return nil, err
+	}
+	eax.bindTagSize = true
+	return eax, nil
+}
 
-func (c *eaxCipher) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
-	if n := len(nonce); n != c.blockCipher.BlockSize() {
-		panic(crypto.NonceSizeError(n))
+// NewEAXLE returns a *EAX like NewEAX, but the CTR counter block
+// is treated as one little-endian number: the first byte is incremented
+// first and carries propagate towards the last byte - e.g. fe00...,
+// ff00..., 0001..., 0101...
+// This is NOT the standard EAX mode and only exists for interoperability
+// with implementations using a little-endian counter. Both variants produce
+// the same ciphertext for messages of at most one block.
+func NewEAXLE(c cipher.Block, tagsize int) (*EAX, error) {
+	eax, err := NewEAX(c, tagsize)
+	if err != nil {
+		return nil, err
 	}
-	if len(dst) < len(plaintext) {
-		panic("dst buffer to small")
+	eax.littleEndian = true
+	return eax, nil
+}
+
+// NewEAXCTROffset returns a *EAX like NewEAX, but the CTR encryption of
+// the body starts startBlock blocks after the counter derived from the
+// nonce - e.g. to reserve the first keystream block(s) for a header, which
+// is encrypted separately. The OMACs and so the tag of the ciphertext are
+// computed as usual. This is NOT the standard EAX mode: it changes the
+// ciphertext, so both sides must use the same offset - a different offset
+// produces a wrong plaintext, which is only detected by the tag. Any reserved
+// keystream block must not be used for more than one header per nonce. The
+// offset does not apply to SealCounter and OpenCounter with a non-nil counter.
+func NewEAXCTROffset(c cipher.Block, tagsize int, startBlock uint32) (*EAX, error) {
+	eax, err := NewEAX(c, tagsize)
+	if err != nil {
+		return nil, err
 	}
+	eax.ctrOffset = startBlock
+	return eax, nil
+}
 
-	tag := make([]byte, c.mac.BlockSize())
+// NewEAXNonce96 returns a *EAX like NewEAX, which uses 12 byte (96 bit)
+// nonces like GCM - e.g. to replace AES-GCM by AES-EAX without changing
+// the nonce generation. EAX processes the nonce with OMAC, which accepts
+// nonces of any length, so this is still the standard EAX mode: the
+// ciphertexts are equal to the ones of any EAX implementation using the
+// same 12 byte nonce. Only the NonceSize differs from NewEAX.
+// This function returns a non-nil error if the given block cipher
+// is not supported by CMac or the tag size is invalid.
+func NewEAXNonce96(c cipher.Block, tagsize int) (*EAX, error) {
+	eax, err := NewEAX(c, tagsize)
+	if err != nil {
+		return nil, err
+	}
+	eax.nonceSize = 12
+	return eax, nil
+}
 
-	// process nonce
-	tag[len(tag)-1] = nTag
-	c.mac.Write(tag)
-	c.mac.Write(nonce)
-	authNonce := c.mac.Sum(nil)
-	c.mac.Reset()
+// NewEAXWithNonceSequence returns a *EAX like NewEAX, which takes the
+// nonces of SealNext from the given NonceSequence. The nonce size of the
+// sequence must be equal to the block size of the cipher. The sequence
+// must not be used for anything else - otherwise the uniqueness of the
+// nonces is not guaranteed.
+// This function returns a non-nil error if the block cipher is not
+// supported by CMac, the tag size is invalid or the nonce size of the
+// sequence does not match.
+func NewEAXWithNonceSequence(c cipher.Block, tagsize int, nonces *NonceSequence) (*EAX, error) {
+	eax, err := NewEAX(c, tagsize)
+	if err != nil {
+		return nil, err
+	}
+	if n := nonces.NonceSize(); n != c.BlockSize() {
+		return nil, crypto.NonceSizeMismatchError{Size: n, Expected: c.BlockSize()}
+	}
+	eax.nonces = nonces
+	return eax, nil
+}
 
-	// process additional data
-	tag[len(tag)-1] = hTag
-	c.mac.Write(tag)
-	c.mac.Write(additionalData)
-	authData := c.mac.Sum(nil)
-	c.mac.Reset()
+// Clone returns a copy of the EAX instance with its own scratch
+// buffers and CMac state. The block cipher is shared and the CMac
+// subkeys are not recomputed, so Clone is cheaper than NewEAX.
+// The block cipher must be safe for concurrent use (as all
+// crypto/aes and crypto/des ciphers are) to use the clone
+// concurrently to c.
+func (c *EAX) Clone() cipher.AEAD {
+	clone := &EAX{
+		blockCipher:  c.blockCipher,
+		mac:          c.mac.Clone(),
+		size:         c.size,
+		nonceSize:    c.nonceSize,
+		littleEndian: c.littleEndian,
+		bindTagSize:  c.bindTagSize,
+		ctrOffset:    c.ctrOffset,
+		nonces:       c.nonces,
+	}
+	clone.allocBuffers()
+	return clone
+}
 
-	// encrypt
+// allocBuffers allocates the counter and the scratch buffers.
+func (c *EAX) allocBuffers() {
+	bs := c.blockCipher.BlockSize()
+	buf := make([]byte, 6*bs)
+	c.ctr, c.block = buf[:bs:bs], buf[bs:2*bs:2*bs]
+	c.tagBlock, c.authNonceBuf = buf[2*bs:3*bs:3*bs], buf[3*bs:4*bs:4*bs]
+	c.authData, c.authCt = buf[4*bs:5*bs:5*bs], buf[5*bs:]
+}
+
+// EAXNonceToCounter returns the initial CTR counter block EAX derives
+// from the nonce - the OMAC of the nonce (with the nonce tag 0).
+// The keystream of EAX is the CTR mode (see crypto/cipher.NewCTR)
+// starting at this counter.
+// This function returns a non-nil error if the given block cipher
+// is not supported by CMac (see crypto/cmac for details).
+func EAXNonceToCounter(c cipher.Block, nonce []byte) ([]byte, error) {
+	eax, err := NewEAX(c, c.BlockSize())
+	if err != nil {
+		return nil, err
+	}
+	counter := make([]byte, c.BlockSize())
+	copy(counter, eax.authNonce(nonce))
+	return counter, nil
+}
+
+// NonceSize returns the size of the nonce - which is equal to
+// the block size of the block cipher or 12 for NewEAXNonce96.
+func (c *EAX) NonceSize() int { return c.nonceSize }
+
+// Overhead returns the size of the authentication tag.
+func (c *EAX) Overhead() int { return c.size }
+
+// MaxPlaintext returns the max. number of plaintext bytes, which can be
+// encrypted with one nonce. The CTR mode repeats the keystream after
+// 2^(8 * BlockSize()) blocks. All block sizes supported by CMac are at least
+// 8 bytes, so this limit always exceeds the max. int64 value.
+func (c *EAX) MaxPlaintext() int64 { return math.MaxInt64 }
+
+// Seal encrypts and authenticates the plaintext and the additional data
+// and appends the result to dst. To reuse the storage of the plaintext
+// for the ciphertext use plaintext[:0] as dst.
+func (c *EAX) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	return c.SealCounter(dst, nonce, nil, plaintext, additionalData)
+}
+
+// SealAppend is equal to Seal. It exists to document that sealing does not
+// allocate, if dst has enough capacity for the ciphertext and the tag:
+// all OMAC computations use internal scratch buffers of the EAX instance.
+func (c *EAX) SealAppend(dst, nonce, plaintext, additionalData []byte) []byte {
+	return c.SealCounter(dst, nonce, nil, plaintext, additionalData)
+}
+
+// SealInPlace encrypts and authenticates the plaintext buf[:plaintextLen] in
+// place and writes the tag to buf[plaintextLen:plaintextLen+Overhead()]. The
+// capacity of buf must be at least plaintextLen + Overhead() bytes - buf may
+// be shorter than that. The returned slice is buf[:plaintextLen+Overhead()]
+// and shares the backing array of buf, so SealInPlace never allocates.
+// SealInPlace returns a non-nil error if the nonce size is invalid,
+// plaintextLen is negative or the capacity of buf is too small.
+func (c *EAX) SealInPlace(buf []byte, plaintextLen int, nonce, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != c.nonceSize {
+		return nil, crypto.NonceSizeMismatchError{Size: n, Expected: c.nonceSize}
+	}
+	if plaintextLen < 0 || cap(buf)-c.size < plaintextLen {
+		return nil, errors.New("capacity of buffer is too small for plaintext and tag")
+	}
+	buf = buf[:plaintextLen+c.size]
+	return c.Seal(buf[:0], nonce, buf[:plaintextLen], additionalData), nil
+}
+
+// OpenInPlace decrypts and authenticates the ciphertext buf (including the
+// tag) in place. The returned plaintext is buf[:len(buf)-Overhead()] and
+// shares the backing array of buf. If the authentication fails, buf is
+// not modified.
+func (c *EAX) OpenInPlace(buf []byte, nonce, additionalData []byte) ([]byte, error) {
+	return c.Open(buf[:0], nonce, buf, additionalData)
+}
+
+// Open decrypts and authenticates the ciphertext and the additional data
+// and appends the plaintext to dst. To reuse the storage of the ciphertext
+// for the plaintext use ciphertext[:0] as dst.
+// Open returns ErrCiphertextTooShort for every ciphertext shorter than
+// Overhead() and a crypto.AuthenticationError if the tag is not valid.
+// It always compares the full tag of Overhead() bytes.
+func (c *EAX) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	return c.OpenCounter(dst, nonce, nil, ciphertext, additionalData)
+}
+
+// SealNext encrypts and authenticates the plaintext and the additional
+// data like Seal using the next nonce of the NonceSequence of the EAX
+// instance and appends the nonce followed by the ciphertext to dst.
+// So the receiver can open the message with Open and the nonce prefix.
+// To reuse the storage of the plaintext use plaintext[:0] as dst.
+// SealNext returns ErrNonceExhausted if the sequence is exhausted and a
+// non-nil error if the instance was not created by NewEAXWithNonceSequence.
+func (c *EAX) SealNext(dst, plaintext, additionalData []byte) ([]byte, error) {
+	if c.nonces == nil {
+		return nil, errors.New("EAX instance has no nonce sequence")
+	}
+	nonce, err := c.nonces.Next()
+	if err != nil {
+		return nil, err
+	}
+
+	// seal first - dst may be plaintext[:0] - and prepend the nonce afterwards
+	ret, out := sliceForAppend(dst, len(nonce)+len(plaintext)+c.Overhead())
+	sealed := c.Seal(out[:0], nonce, plaintext, additionalData)
+	copy(out[len(nonce):], sealed)
+	copy(out, nonce)
+	return ret, nil
+}
+
+// SealTag works like Seal but appends a tag of tagsize bytes instead of
+// Overhead() bytes. The tagsize must be between 1 and the block size of
+// the cipher - otherwise SealTag panics.
+// An EAX tag truncated to n bytes is a valid tag for tag size n, so the
+// tag size of every message must be agreed on out-of-band (e.g. fixed by
+// the message type) and must never be derived from the received message.
+// Otherwise an attacker can truncate the tag of a message. Instances
+// created by NewEAXBindTagSize bind the tag size of every call.
+func (c *EAX) SealTag(dst, nonce, plaintext, additionalData []byte, tagsize int) []byte {
+	if !c.validTagSize(tagsize) {
+		panic(errTagSize)
+	}
+	defer func(size int) { c.size = size }(c.size)
+	c.size = tagsize
+	return c.Seal(dst, nonce, plaintext, additionalData)
+}
+
+// OpenTag works like Open but verifies a tag of tagsize bytes instead of
+// Overhead() bytes. See SealTag for details.
+// OpenTag returns a non-nil error if the tagsize is not between 1 and the
+// block size of the cipher.
+func (c *EAX) OpenTag(dst, nonce, ciphertext, additionalData []byte, tagsize int) ([]byte, error) {
+	if !c.validTagSize(tagsize) {
+		return nil, errTagSize
+	}
+	defer func(size int) { c.size = size }(c.size)
+	c.size = tagsize
+	return c.Open(dst, nonce, ciphertext, additionalData)
+}
+
+func (c *EAX) validTagSize(tagsize int) bool {
+	return tagsize >= 1 && tagsize <= c.blockCipher.BlockSize()
+}
+
+// VerifyAD verifies the tag of the ciphertext (including the tag) for the
+// given nonce and additional data without decrypting the ciphertext - e.g.
+// to validate a header before committing to the decryption. VerifyAD returns
+// true if the ciphertext is authentic - then Open with the same arguments
+// succeeds. VerifyAD returns a non-nil error if the nonce size is invalid or
+// the EAX instance has no tag (see NewEAXNoTag).
+func (c *EAX) VerifyAD(nonce, ciphertext, additionalData []byte) (bool, error) {
+	if n := len(nonce); n != c.nonceSize {
+		return false, crypto.NonceSizeMismatchError{Size: n, Expected: c.nonceSize}
+	}
+	if c.size == 0 {
+		return false, errors.New("EAX instance has no authentication tag")
+	}
+	authNonce, authData := c.authenticate(nonce, additionalData)
+	return c.verify(ciphertext, authNonce, authData), nil
+}
+
+// Counter returns the CTR counter block following the last keystream
+// block used by the previous Seal or Open call. Passing it to SealCounter
+// (OpenCounter) continues the keystream of the previous message, so
+// chunks of a stream can share one CTR keystream. If the previous call
+// used no keystream - the message was empty or Open failed - Counter
+// returns the initial counter block of that call. Calls failing before
+// the counter is known (e.g. an invalid nonce size) and VerifyAD do not
+// change the counter.
+func (c *EAX) Counter() []byte {
+	ctr := make([]byte, len(c.ctr))
+	copy(ctr, c.ctr)
+	return ctr
+}
+
+// SealCounter works like Seal but starts the CTR encryption at the given
+// counter block instead of the counter derived from the nonce. If counter
+// is nil, SealCounter is equal to Seal.
+// The counter is not authenticated - the tag only covers the nonce, the
+// additional data and the ciphertext of this chunk. So every chunk must
+// still be sealed with its own tag and a unique nonce (e.g. the chunk index)
+// to bind the order of the chunks. Both sides must derive the counter blocks
+// in the same way - a wrong counter produces a wrong plaintext, which is not
+// detected by OpenCounter. Continued counters must not overlap with the
+// keystream of any other message.
+func (c *EAX) SealCounter(dst, nonce, counter, plaintext, additionalData []byte) []byte {
+	if n := len(nonce); n != c.nonceSize {
+		panic(crypto.NonceSizeMismatchError{Size: n, Expected: c.nonceSize})
+	}
+	if counter != nil && len(counter) != c.blockCipher.BlockSize() {
+		panic("counter must be BlockSize() bytes long")
+	}
+	authNonce, authData := c.authenticate(nonce, additionalData)
+	return c.seal(dst, counter, plaintext, authNonce, authData)
+}
+
+// seal encrypts the plaintext - starting at the counter or at authNonce if
+// the counter is nil - and appends the ciphertext and the tag to dst.
+func (c *EAX) seal(dst, counter, plaintext, authNonce, authData []byte) []byte {
 	n := len(plaintext)
-	copy(c.ctr, authNonce) // set the ctr-mode nonce
-	c.ctrCrypt(dst, plaintext)
+	ret, out := sliceForAppend(dst, n+c.size)
 
-	// process ciphertext
-	tag[len(tag)-1] = cTag
-	c.mac.Write(tag)
-	c.mac.Write(dst[:n])
-	tag = c.mac.Sum(tag[:0])
-	c.mac.Reset()
+	// encrypt
+	c.setCtr(counter, authNonce)
+	c.ctrCrypt(out, plaintext)
 
+	if c.size == 0 {
+		return ret
+	}
+	tag := c.authCiphertext(out[:n])
 	for i := range tag {
 		tag[i] ^= authData[i] ^ authNonce[i]
 	}
-	return append(dst[:n], tag[:c.size]...)
+	copy(out[n:], tag[:c.size])
+	return ret
 }
 
-func (c *eaxCipher) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
-	if n := len(nonce); n != c.blockCipher.BlockSize() {
-		return nil, crypto.NonceSizeError(n)
+// OpenCounter works like Open but starts the CTR decryption at the given
+// counter block instead of the counter derived from the nonce. If counter
+// is nil, OpenCounter is equal to Open. See SealCounter for details.
+func (c *EAX) OpenCounter(dst, nonce, counter, ciphertext, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != c.nonceSize {
+		return nil, crypto.NonceSizeMismatchError{Size: n, Expected: c.nonceSize}
+	}
+	if counter != nil && len(counter) != c.blockCipher.BlockSize() {
+		return nil, errors.New("counter must be BlockSize() bytes long")
 	}
+	authNonce, authData := c.authenticate(nonce, additionalData)
+	return c.open(dst, counter, ciphertext, authNonce, authData)
+}
+
+// open verifies the tag of the ciphertext, decrypts the ciphertext - starting
+// at the counter or at authNonce if the counter is nil - and appends the
+// plaintext to dst.
+func (c *EAX) open(dst, counter, ciphertext, authNonce, authData []byte) ([]byte, error) {
+	// set the counter first, so Counter is valid on every path
+	c.setCtr(counter, authNonce)
 	if len(ciphertext) < c.size {
-		return nil, crypto.AuthenticationError{}
+		return nil, ErrCiphertextTooShort
 	}
-	if len(dst) < len(ciphertext)-c.mac.Size() {
-		panic("dst buffer to small")
+	if !c.verify(ciphertext, authNonce, authData) {
+		return nil, crypto.AuthenticationError{Algorithm: "EAX"}
 	}
+	ciphertext, _, _ = crypto.SplitTag(ciphertext, c.size)
 
-	hash := ciphertext[len(ciphertext)-c.size:]
-	ciphertext = ciphertext[:len(ciphertext)-c.size]
+	// a tag without ciphertext authenticates the empty plaintext
+	if len(ciphertext) == 0 {
+		return dst, nil
+	}
 
-	tag := make([]byte, c.mac.BlockSize())
+	// decrypt
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	c.ctrCrypt(out, ciphertext)
 
-	// process nonce
-	tag[len(tag)-1] = nTag
-	c.mac.Write(tag)
-	c.mac.Write(nonce)
-	authNonce := c.mac.Sum(nil)
-	c.mac.Reset()
+	return ret, nil
+}
 
-	// process additional data
-	tag[len(tag)-1] = hTag
-	c.mac.Write(tag)
+// verify returns true if the ciphertext ends with a valid tag.
+func (c *EAX) verify(ciphertext, authNonce, authData []byte) bool {
+	body, hash, ok := crypto.SplitTag(ciphertext, c.size)
+	if !ok {
+		return false
+	}
+	if c.size == 0 {
+		return true
+	}
+	tag := c.authCiphertext(body)
+	for i := range tag {
+		tag[i] ^= authData[i] ^ authNonce[i]
+	}
+	return subtle.ConstantTimeCompare(tag[:c.size], hash) == 1
+}
+
+// authenticate computes the OMAC of the nonce and the additional data.
+// The returned slices are only valid until the next call.
+func (c *EAX) authenticate(nonce, additionalData []byte) (authNonce, authData []byte) {
+	return c.authNonce(nonce), c.authAdditionalData(additionalData)
+}
+
+// authAdditionalData computes the OMAC of the additional data.
+// The returned slice is only valid until the next call.
+func (c *EAX) authAdditionalData(additionalData []byte) []byte {
+	c.writeTag(hTag)
 	c.mac.Write(additionalData)
-	authData := c.mac.Sum(nil)
+	authData := c.mac.Sum(c.authData[:0])
 	c.mac.Reset()
+	return authData
+}
 
-	// process ciphertext
-	tag[len(tag)-1] = cTag
-	c.mac.Write(tag)
-	c.mac.Write(ciphertext)
-	tag = c.mac.Sum(tag[:0])
+// authNonce computes the OMAC of the nonce.
+// The returned slice is only valid until the next call.
+func (c *EAX) authNonce(nonce []byte) []byte {
+	c.writeTag(nTag)
+	c.mac.Write(nonce)
+	authNonce := c.mac.Sum(c.authNonceBuf[:0])
 	c.mac.Reset()
+	return authNonce
+}
 
+// writeTag writes the OMAC tag block [0, ..., 0, t] to the mac.
+// If the tag size is bound, the first byte of the nonce tag block
+// is the tag size.
+func (c *EAX) writeTag(t byte) {
+	tag := c.tagBlock
 	for i := range tag {
-		tag[i] ^= authData[i] ^ authNonce[i]
+		tag[i] = 0
 	}
-
-	if subtle.ConstantTimeCompare(tag[:c.size], hash) != 1 {
-		return nil, crypto.AuthenticationError{}
+	tag[len(tag)-1] = t
+	if t == nTag && c.bindTagSize {
+		tag[0] = byte(c.size)
 	}
+	c.mac.Write(tag)
+}
 
-	// decrypt
-	n := len(ciphertext)
-	copy(c.ctr, authNonce) // set the ctr-mode nonce
-	c.ctrCrypt(dst, ciphertext)
-
-	return dst[:n], nil
+// authCiphertext computes the OMAC of the ciphertext.
+// The returned slice is only valid until the next call.
+func (c *EAX) authCiphertext(ciphertext []byte) []byte {
+	c.writeTag(cTag)
+	c.mac.Write(ciphertext)
+	tag := c.mac.Sum(c.authCt[:0])
+	c.mac.Reset()
+	return tag
 }
 
 // ctrCrypt encrypts the bytes in src with the CTR mode and writes
-// the ciphertext into dst
-func (c *eaxCipher) ctrCrypt(dst, src []byte) {
+// the ciphertext into dst. After ctrCrypt the counter points to
+// the block following the last used keystream block.
+func (c *EAX) ctrCrypt(dst, src []byte) {
 	length := len(src)
 	bs := c.blockCipher.BlockSize()
-	n := length & (^(length - bs))
+	n := length & (^(bs - 1))
 
 	for i := 0; i < n; i += bs {
 		j := i + bs
 		c.blockCipher.Encrypt(c.block, c.ctr)
 		crypto.XOR(dst[i:j], src[i:j], c.block)
-
-		// Increment counter
-		for k := len(c.ctr) - 1; k >= 0; k-- {
-			c.ctr[k]++
-			if c.ctr[k] != 0 {
-				break
-			}
-		}
+		c.incCtr()
 	}
 	if n < length {
 		c.blockCipher.Encrypt(c.block, c.ctr)
 		crypto.XOR(dst[n:], src[n:], c.block)
+		c.incCtr()
 	}
 	// no reset of ctr needed - Seal or Open does this for us
 }
+
+// setCtr sets the CTR counter to the given counter or - if the counter
+// is nil - to authNonce advanced by the CTR offset.
+func (c *EAX) setCtr(counter, authNonce []byte) {
+	if counter != nil {
+		copy(c.ctr, counter)
+		return
+	}
+	copy(c.ctr, authNonce)
+	if c.ctrOffset == 0 {
+		return
+	}
+	// add the offset in constant time like incCtr
+	carry := uint64(c.ctrOffset)
+	n := len(c.ctr)
+	for i := 0; i < n; i++ {
+		j := n - 1 - i
+		if c.littleEndian {
+			j = i
+		}
+		v := uint64(c.ctr[j]) + carry
+		c.ctr[j] = byte(v)
+		carry = v >> 8
+	}
+}
+
+// incCtr increments the CTR counter in constant time. A loop stopping
+// at the first byte without carry would reveal the carry pattern of the
+// counter through its timing - and the initial counter is the (secret)
+// OMAC of the nonce.
+func (c *EAX) incCtr() {
+	if c.littleEndian {
+		crypto.IncrementLE(c.ctr)
+		return
+	}
+	crypto.IncrementBE(c.ctr)
+}
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns a
+// slice with the contents of the given slice followed by that many bytes and
+// a second slice that aliases into it and contains only the extra bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
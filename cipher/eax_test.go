@@ -4,10 +4,165 @@
 package cipher
 
 import (
+	"bytes"
 	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"sync"
 	"testing"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/internal/testvec"
 )
 
+func TestCTRCrypt(t *testing.T) {
+	c := newTestEAX(t)
+	iv := make([]byte, c.NonceSize())
+	for i := range iv {
+		iv[i] = byte(255 - i)
+	}
+
+	src := make([]byte, 256)
+	for i := range src {
+		src[i] = byte(i)
+	}
+	for i := range src {
+		dst0, dst1 := make([]byte, i), make([]byte, i)
+
+		copy(c.ctr, iv)
+		c.ctrCrypt(dst0, src[:i])
+		cipher.NewCTR(c.blockCipher, iv).XORKeyStream(dst1, src[:i])
+
+		if !bytes.Equal(dst0, dst1) {
+			t.Fatalf("Length %d: ctrCrypt differ from CTR mode:\nctrCrypt: %x\nCTR:      %x", i, dst0, dst1)
+		}
+	}
+}
+
+var counterSequences = []struct {
+	littleEndian bool
+	counter      []string
+}{
+	{
+		littleEndian: false,
+		counter: []string{
+			"000000000000000000000000000000fe",
+			"000000000000000000000000000000ff",
+			"00000000000000000000000000000100",
+			"00000000000000000000000000000101",
+		},
+	},
+	{
+		littleEndian: false,
+		counter: []string{
+			"fffffffffffffffffffffffffffffffe",
+			"ffffffffffffffffffffffffffffffff",
+			"00000000000000000000000000000000",
+			"00000000000000000000000000000001",
+		},
+	},
+	{
+		littleEndian: true,
+		counter: []string{
+			"fe000000000000000000000000000000",
+			"ff000000000000000000000000000000",
+			"00010000000000000000000000000000",
+			"01010000000000000000000000000000",
+		},
+	},
+	{
+		littleEndian: true,
+		counter: []string{
+			"feffffffffffffffffffffffffffffff",
+			"ffffffffffffffffffffffffffffffff",
+			"00000000000000000000000000000000",
+			"01000000000000000000000000000000",
+		},
+	},
+}
+
+func TestCounterEndianness(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	for i, v := range counterSequences {
+		newEAX := NewEAX
+		if v.littleEndian {
+			newEAX = NewEAXLE
+		}
+		c, err := newEAX(block, block.BlockSize())
+		if err != nil {
+			t.Fatalf("Test vector %d: Failed to create EAX instance: %s", i, err)
+		}
+
+		// the keystream must be E(ctr_0) || E(ctr_1) || ...
+		keystream := make([]byte, len(v.counter)*block.BlockSize())
+		for j, ctr := range v.counter {
+			block.Encrypt(keystream[j*block.BlockSize():], testvec.MustDecodeHex(ctr))
+		}
+
+		copy(c.ctr, testvec.MustDecodeHex(v.counter[0]))
+		stream := make([]byte, len(keystream))
+		c.ctrCrypt(stream, stream)
+		if !bytes.Equal(stream, keystream) {
+			t.Fatalf("Test vector %d: Keystream does not match the counter sequence", i)
+		}
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	c := newTestEAX(t)
+	nonce, data := make([]byte, c.NonceSize()), []byte("data")
+
+	msg := make([]byte, 100)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for i := range msg {
+		// fresh buffers
+		ciphertext := c.Seal(nil, nonce, msg[:i], data)
+		if n := len(ciphertext); n != i+c.Overhead() {
+			t.Fatalf("Length %d: Seal returned %d bytes - but expected %d", i, n, i+c.Overhead())
+		}
+		plaintext, err := c.Open(nil, nonce, ciphertext, data)
+		if err != nil {
+			t.Fatalf("Length %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(plaintext, msg[:i]) {
+			t.Fatalf("Length %d: Open returned %x - but expected %x", i, plaintext, msg[:i])
+		}
+
+		// append to existing data
+		prefix := []byte("prefix")
+		plaintext, err = c.Open(prefix, nonce, ciphertext, data)
+		if err != nil {
+			t.Fatalf("Length %d: Open failed: %s", i, err)
+		}
+		if !bytes.HasPrefix(plaintext, prefix) || !bytes.Equal(plaintext[len(prefix):], msg[:i]) {
+			t.Fatalf("Length %d: Open did not append the plaintext to dst", i)
+		}
+
+		// in-place
+		buf := make([]byte, i, i+c.Overhead())
+		copy(buf, msg)
+		sealed := c.Seal(buf[:0], nonce, buf, data)
+		if !bytes.Equal(sealed, ciphertext) {
+			t.Fatalf("Length %d: in-place Seal returned %x - but expected %x", i, sealed, ciphertext)
+		}
+		if &sealed[0] != &buf[:1][0] {
+			t.Fatalf("Length %d: in-place Seal did not reuse the plaintext buffer", i)
+		}
+		opened, err := c.Open(sealed[:0], nonce, sealed, data)
+		if err != nil {
+			t.Fatalf("Length %d: in-place Open failed: %s", i, err)
+		}
+		if !bytes.Equal(opened, msg[:i]) {
+			t.Fatalf("Length %d: in-place Open returned %x - but expected %x", i, opened, msg[:i])
+		}
+	}
+}
+
 // Benchmarks
 
 func BenchmarkSeal_64B(b *testing.B) {
@@ -25,7 +180,7 @@ func BenchmarkSeal_64B(b *testing.B) {
 	data := make([]byte, 8)
 	b.SetBytes(64)
 	for i := 0; i < b.N; i++ {
-		dst = c.Seal(dst, nonce, msg, data)
+		dst = c.Seal(dst[:0], nonce, msg, data)
 	}
 }
 
@@ -44,7 +199,7 @@ func BenchmarkSeal_1K(b *testing.B) {
 	data := make([]byte, 8)
 	b.SetBytes(1024)
 	for i := 0; i < b.N; i++ {
-		dst = c.Seal(dst, nonce, msg, data)
+		dst = c.Seal(dst[:0], nonce, msg, data)
 	}
 }
 
@@ -60,12 +215,11 @@ func BenchmarkOpen_64B(b *testing.B) {
 	}
 	msg := make([]byte, 64)
 	dst := make([]byte, len(msg))
-	ciphertext := make([]byte, len(msg)+aes.BlockSize)
 	data := make([]byte, 8)
-	ciphertext = c.Seal(ciphertext, nonce, msg, data)
+	ciphertext := c.Seal(nil, nonce, msg, data)
 	b.SetBytes(64)
 	for i := 0; i < b.N; i++ {
-		dst, _ = c.Open(dst, nonce, ciphertext, data)
+		dst, _ = c.Open(dst[:0], nonce, ciphertext, data)
 	}
 }
 
@@ -81,11 +235,697 @@ func BenchmarkOpen_1K(b *testing.B) {
 	}
 	msg := make([]byte, 1024)
 	dst := make([]byte, len(msg))
-	ciphertext := make([]byte, len(msg)+aes.BlockSize)
 	data := make([]byte, 8)
-	ciphertext = c.Seal(ciphertext, nonce, msg, data)
+	ciphertext := c.Seal(nil, nonce, msg, data)
 	b.SetBytes(1024)
 	for i := 0; i < b.N; i++ {
-		dst, _ = c.Open(dst, nonce, ciphertext, data)
+		dst, _ = c.Open(dst[:0], nonce, ciphertext, data)
+	}
+}
+
+func TestSealCounter(t *testing.T) {
+	c := newTestEAX(t)
+	n0, n1 := make([]byte, c.NonceSize()), make([]byte, c.NonceSize())
+	n1[len(n1)-1] = 1
+
+	msg := make([]byte, 37+50)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+
+	for _, split := range []int{48, 37} {
+		p0, p1 := msg[:split], msg[split:]
+
+		ct0 := c.Seal(nil, n0, p0, nil)
+		ct1 := c.SealCounter(nil, n1, c.Counter(), p1, nil)
+
+		// the second chunk continues the keystream at the next block
+		authNonce, _ := c.authenticate(n0, nil)
+		skip := (split + c.NonceSize() - 1) / c.NonceSize() * c.NonceSize()
+		stream := make([]byte, skip+len(p1))
+		copy(stream, p0)
+		copy(stream[skip:], p1)
+		cipher.NewCTR(c.blockCipher, authNonce).XORKeyStream(stream, stream)
+		if !bytes.Equal(ct0[:split], stream[:split]) || !bytes.Equal(ct1[:len(p1)], stream[skip:]) {
+			t.Fatalf("Split %d: Continued chunks differ from a single CTR stream", split)
+		}
+
+		pt0, err := c.Open(nil, n0, ct0, nil)
+		if err != nil {
+			t.Fatalf("Split %d: Open failed: %s", split, err)
+		}
+		pt1, err := c.OpenCounter(nil, n1, c.Counter(), ct1, nil)
+		if err != nil {
+			t.Fatalf("Split %d: OpenCounter failed: %s", split, err)
+		}
+		if !bytes.Equal(pt0, p0) || !bytes.Equal(pt1, p1) {
+			t.Fatalf("Split %d: Open returned wrong plaintext", split)
+		}
+
+		// every chunk is still authenticated on its own
+		ct1[0] ^= 1
+		if _, err = c.OpenCounter(nil, n1, c.Counter(), ct1, nil); err == nil {
+			t.Fatalf("Split %d: OpenCounter accepted a modified chunk", split)
+		}
+	}
+}
+
+// Counter returns the initial counter block after a call
+// using no keystream - an empty message or a failed Open.
+func TestCounterWithoutKeystream(t *testing.T) {
+	c := newTestEAX(t)
+	n0, n1 := make([]byte, c.NonceSize()), make([]byte, c.NonceSize())
+	n1[len(n1)-1] = 1
+	authNonce := func(nonce []byte) []byte {
+		a, _ := c.authenticate(nonce, nil)
+		return append([]byte{}, a...)
+	}
+	ciphertext := c.Seal(nil, n0, []byte("advances the counter"), nil)
+
+	c.Seal(nil, n1, nil, nil)
+	if ctr := c.Counter(); !bytes.Equal(ctr, authNonce(n1)) {
+		t.Fatalf("Counter after an empty Seal is %x - but expected %x", ctr, authNonce(n1))
+	}
+	c.Seal(nil, n0, []byte("advances the counter"), nil)
+	if _, err := c.Open(nil, n1, c.Seal(nil, n1, nil, nil), nil); err != nil {
+		t.Fatalf("Open of an empty message failed: %s", err)
+	}
+	if ctr := c.Counter(); !bytes.Equal(ctr, authNonce(n1)) {
+		t.Fatalf("Counter after an empty Open is %x - but expected %x", ctr, authNonce(n1))
+	}
+
+	c.Seal(nil, n0, []byte("advances the counter"), nil)
+	if _, err := c.Open(nil, n1, ciphertext, nil); err == nil {
+		t.Fatal("Open accepted a ciphertext of another nonce")
+	}
+	if ctr := c.Counter(); !bytes.Equal(ctr, authNonce(n1)) {
+		t.Fatalf("Counter after a failed Open is %x - but expected %x", ctr, authNonce(n1))
+	}
+	c.Seal(nil, n0, []byte("advances the counter"), nil)
+	if _, err := c.Open(nil, n1, ciphertext[:c.Overhead()-1], nil); err == nil {
+		t.Fatal("Open accepted a truncated ciphertext")
+	}
+	if ctr := c.Counter(); !bytes.Equal(ctr, authNonce(n1)) {
+		t.Fatalf("Counter after Open of a truncated ciphertext is %x - but expected %x", ctr, authNonce(n1))
+	}
+
+	counter := make([]byte, c.NonceSize())
+	counter[0] = 0xff
+	if _, err := c.OpenCounter(nil, n1, counter, ciphertext, nil); err == nil {
+		t.Fatal("OpenCounter accepted a ciphertext of another nonce")
+	}
+	if ctr := c.Counter(); !bytes.Equal(ctr, counter) {
+		t.Fatalf("Counter after a failed OpenCounter is %x - but expected %x", ctr, counter)
+	}
+}
+
+func FuzzEAXOpen(f *testing.F) {
+	c := newTestEAX(f)
+	nonce := make([]byte, c.NonceSize())
+	valid := c.Seal(nil, nonce, []byte("fuzz"), []byte("data"))
+
+	f.Add([]byte{}, []byte{}, []byte{})
+	f.Add(nonce, []byte{}, []byte{})
+	f.Add(nonce, make([]byte, c.Overhead()-1), []byte{})
+	f.Add(nonce, make([]byte, c.Overhead()), []byte{})
+	f.Add(nonce, valid, []byte("data"))
+	f.Add(nonce[:1], valid, []byte("data"))
+	f.Add(nonce, valid[:len(valid)-1], []byte("data"))
+
+	f.Fuzz(func(t *testing.T, nonce, ciphertext, data []byte) {
+		plaintext, err := c.Open(nil, nonce, ciphertext, data)
+		if err != nil && plaintext != nil {
+			t.Fatalf("Open returned plaintext and error: %s", err)
+		}
+		if err == nil && len(plaintext) != len(ciphertext)-c.Overhead() {
+			t.Fatalf("Open returned %d bytes for %d bytes ciphertext", len(plaintext), len(ciphertext))
+		}
+	})
+}
+
+func TestClone(t *testing.T) {
+	c := newTestEAX(t)
+	nonce, data := make([]byte, c.NonceSize()), []byte("data")
+	msg := make([]byte, 100)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	ciphertext := c.Seal(nil, nonce, msg, data)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		clone := c.Clone()
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 16; j++ {
+				ct := clone.Seal(nil, nonce, msg, data)
+				if !bytes.Equal(ct, ciphertext) {
+					t.Errorf("Clone produced a different ciphertext")
+					return
+				}
+				if _, err := clone.Open(nil, nonce, ct, data); err != nil {
+					t.Errorf("Clone failed to open ciphertext: %s", err)
+					return
+				}
+			}
+		}()
+	}
+	for j := 0; j < 16; j++ {
+		c.Seal(nil, nonce, msg, data)
+	}
+	wg.Wait()
+}
+
+func TestTagTruncation(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	nonce, msg, data := make([]byte, 16), []byte("truncation"), []byte("data")
+
+	for _, v := range []struct {
+		newEAX func(cipher.Block, int) (*EAX, error)
+		accept bool
+	}{
+		{NewEAX, true},
+		{NewEAXBindTagSize, false},
+	} {
+		long, err := v.newEAX(block, 16)
+		if err != nil {
+			t.Fatalf("Failed to create EAX instance: %s", err)
+		}
+		ciphertext := long.Seal(nil, nonce, msg, data)
+		if _, err = long.Open(nil, nonce, ciphertext[:len(ciphertext)-1], data); err == nil {
+			t.Fatal("Open accepted a truncated tag")
+		}
+		for n := 0; n < long.Overhead(); n++ {
+			if _, err = long.Open(nil, nonce, ciphertext[:n], data); err == nil {
+				t.Fatalf("Open accepted a %d byte ciphertext shorter than Overhead()", n)
+			}
+		}
+
+		for size := 1; size < 16; size++ {
+			short, err := v.newEAX(block, size)
+			if err != nil {
+				t.Fatalf("Failed to create EAX instance: %s", err)
+			}
+			truncated := ciphertext[:len(msg)+size]
+			_, err = short.Open(nil, nonce, truncated, data)
+			if v.accept && err != nil {
+				t.Fatalf("Tag size %d: standard EAX rejected a truncated tag: %s", size, err)
+			}
+			if !v.accept && err == nil {
+				t.Fatalf("Tag size %d: bound EAX accepted a truncated tag", size)
+			}
+		}
+	}
+}
+
+func TestNoTag(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	c, err := NewEAXNoTag(block)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+	if n := c.Overhead(); n != 0 {
+		t.Fatalf("Overhead returned %d - but expected 0", n)
+	}
+	eax := newTestEAX(t)
+
+	nonce, data := make([]byte, c.NonceSize()), []byte("data")
+	for _, n := range []int{0, 1, 16, 37} {
+		msg := make([]byte, n)
+		for i := range msg {
+			msg[i] = byte(i)
+		}
+		ciphertext := c.Seal(nil, nonce, msg, data)
+		if len(ciphertext) != n {
+			t.Fatalf("Length %d: Seal returned %d bytes", n, len(ciphertext))
+		}
+		// the ciphertext is the EAX ciphertext without the tag
+		if expected := eax.Seal(nil, nonce, msg, data); !bytes.Equal(ciphertext, expected[:n]) {
+			t.Fatalf("Length %d: Ciphertext differ from EAX", n)
+		}
+		plaintext, err := c.Open(nil, nonce, ciphertext, nil)
+		if err != nil {
+			t.Fatalf("Length %d: Open failed: %s", n, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("Length %d: Open returned %x - but expected %x", n, plaintext, msg)
+		}
+	}
+	if _, err := NewEAX(block, 0); err == nil {
+		t.Fatal("NewEAX accepted tag size 0")
+	}
+}
+
+func TestEAXNonceToCounter(t *testing.T) {
+	c := newTestEAX(t)
+	nonce := make([]byte, c.NonceSize())
+	for i := range nonce {
+		nonce[i] = byte(i)
+	}
+	msg := make([]byte, 100)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+
+	counter, err := EAXNonceToCounter(c.blockCipher, nonce)
+	if err != nil {
+		t.Fatalf("EAXNonceToCounter failed: %s", err)
+	}
+	stream := make([]byte, len(msg))
+	cipher.NewCTR(c.blockCipher, counter).XORKeyStream(stream, msg)
+
+	ciphertext := c.Seal(nil, nonce, msg, []byte("data"))
+	if !bytes.Equal(ciphertext[:len(msg)], stream) {
+		t.Fatal("CTR mode starting at EAXNonceToCounter differ from EAX")
+	}
+
+	if _, err = EAXNonceToCounter(dummyBlock(12), nonce); err == nil {
+		t.Fatal("EAXNonceToCounter accepted an unsupported block size")
+	}
+}
+
+func TestSealAllocs(t *testing.T) {
+	c := newTestEAX(t)
+	nonce, data := make([]byte, c.NonceSize()), make([]byte, 13)
+	msg := make([]byte, 256)
+	dst := make([]byte, 0, len(msg)+c.Overhead())
+	ciphertext := c.Seal(nil, nonce, msg, data)
+	plaintext := make([]byte, 0, len(msg))
+
+	if n := testing.AllocsPerRun(100, func() { dst = c.SealAppend(dst[:0], nonce, msg, data) }); n != 0 {
+		t.Fatalf("SealAppend allocates %.1f times - but expected 0", n)
+	}
+	if n := testing.AllocsPerRun(100, func() { plaintext, _ = c.Open(plaintext[:0], nonce, ciphertext, data) }); n != 0 {
+		t.Fatalf("Open allocates %.1f times - but expected 0", n)
+	}
+	if !bytes.Equal(dst, ciphertext) || !bytes.Equal(plaintext, msg) {
+		t.Fatal("SealAppend and Open produce a wrong result")
+	}
+}
+
+func TestOpenEmptyCiphertext(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	nonce, data := make([]byte, block.BlockSize()), []byte("data")
+	for tagsize := 1; tagsize <= block.BlockSize(); tagsize++ {
+		c, err := NewEAX(block, tagsize)
+		if err != nil {
+			t.Fatalf("Tag size %d: Failed to create EAX instance: %s", tagsize, err)
+		}
+		tag := c.Seal(nil, nonce, nil, data)
+		if len(tag) != tagsize {
+			t.Fatalf("Tag size %d: Seal returned %d bytes for an empty plaintext", tagsize, len(tag))
+		}
+
+		plaintext, err := c.Open(nil, nonce, tag, data)
+		if err != nil {
+			t.Fatalf("Tag size %d: Open failed: %s", tagsize, err)
+		}
+		if len(plaintext) != 0 {
+			t.Fatalf("Tag size %d: Open returned %x - but expected an empty plaintext", tagsize, plaintext)
+		}
+		prefix := []byte("prefix")
+		if plaintext, err = c.Open(prefix, nonce, tag, data); err != nil || !bytes.Equal(plaintext, prefix) {
+			t.Fatalf("Tag size %d: Open returned %q, %v - but expected %q", tagsize, plaintext, err, prefix)
+		}
+
+		if _, err = c.Open(nil, nonce, tag, []byte("other")); err == nil {
+			t.Fatalf("Tag size %d: Open accepted wrong additional data", tagsize)
+		}
+		modified := append([]byte{}, tag...)
+		modified[tagsize-1] ^= 1
+		if _, err = c.Open(nil, nonce, modified, data); err == nil {
+			t.Fatalf("Tag size %d: Open accepted a modified tag", tagsize)
+		}
+		if _, err = c.Open(nil, nonce, tag[:tagsize-1], data); err != ErrCiphertextTooShort {
+			t.Fatalf("Tag size %d: Open of %d bytes returned %v - but expected ErrCiphertextTooShort", tagsize, tagsize-1, err)
+		}
+	}
+}
+
+func TestSealInPlace(t *testing.T) {
+	c := newTestEAX(t)
+	nonce, data := make([]byte, c.NonceSize()), []byte("data")
+	for _, size := range []int{0, 1, 16, 33} {
+		msg := make([]byte, size)
+		for i := range msg {
+			msg[i] = byte(i)
+		}
+		expected := c.Seal(nil, nonce, msg, data)
+
+		buf := make([]byte, size, size+c.Overhead())
+		copy(buf, msg)
+		sealed, err := c.SealInPlace(buf, size, nonce, data)
+		if err != nil {
+			t.Fatalf("Length %d: SealInPlace failed: %s", size, err)
+		}
+		if !bytes.Equal(sealed, expected) {
+			t.Fatalf("Length %d: SealInPlace returned %x - but expected %x", size, sealed, expected)
+		}
+		if &sealed[:1][0] != &buf[:1][0] {
+			t.Fatalf("Length %d: SealInPlace did not reuse the buffer", size)
+		}
+
+		opened, err := c.OpenInPlace(sealed, nonce, data)
+		if err != nil {
+			t.Fatalf("Length %d: OpenInPlace failed: %s", size, err)
+		}
+		if !bytes.Equal(opened, msg) {
+			t.Fatalf("Length %d: OpenInPlace returned %x - but expected %x", size, opened, msg)
+		}
+		if &opened[:1][0] != &buf[:1][0] {
+			t.Fatalf("Length %d: OpenInPlace did not reuse the buffer", size)
+		}
+
+		sealed, _ = c.SealInPlace(buf, size, nonce, data)
+		sealed[len(sealed)-1] ^= 1
+		modified := append([]byte{}, sealed...)
+		if _, err = c.OpenInPlace(sealed, nonce, data); err == nil {
+			t.Fatalf("Length %d: OpenInPlace accepted a modified tag", size)
+		}
+		if !bytes.Equal(sealed, modified) {
+			t.Fatalf("Length %d: OpenInPlace modified the buffer although the authentication failed", size)
+		}
+	}
+
+	buf := make([]byte, 10, 10+c.Overhead()-1)
+	if _, err := c.SealInPlace(buf, 10, nonce, data); err == nil {
+		t.Fatal("SealInPlace accepted a buffer without capacity for the tag")
+	}
+	if _, err := c.SealInPlace(buf, -1, nonce, data); err == nil {
+		t.Fatal("SealInPlace accepted a negative plaintext length")
+	}
+	if _, err := c.SealInPlace(buf, 0, nonce[1:], data); err == nil {
+		t.Fatal("SealInPlace accepted an invalid nonce")
+	}
+}
+
+func TestSealNext(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	nonces := NewNonceSequence(block.BlockSize())
+	nonces.ctr = nonces.max - 2
+	c, err := NewEAXWithNonceSequence(block, 16, nonces)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+	msg, data := []byte("sealed with the next nonce"), []byte("data")
+
+	prev := []byte{}
+	for i := 0; i < 3; i++ {
+		ciphertext, err := c.SealNext(nil, msg, data)
+		if err != nil {
+			t.Fatalf("Iteration %d: SealNext failed: %s", i, err)
+		}
+		nonce := ciphertext[:c.NonceSize()]
+		if bytes.Equal(nonce, prev) {
+			t.Fatalf("Iteration %d: SealNext used the same nonce twice", i)
+		}
+		prev = nonce
+		plaintext, err := c.Open(nil, nonce, ciphertext[c.NonceSize():], data)
+		if err != nil {
+			t.Fatalf("Iteration %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("Iteration %d: Open returned %q - but expected %q", i, plaintext, msg)
+		}
+	}
+	if _, err = c.SealNext(nil, msg, data); err != ErrNonceExhausted {
+		t.Fatalf("SealNext returned %v - but expected: %v", err, ErrNonceExhausted)
+	}
+
+	// in place
+	nonces = NewNonceSequence(block.BlockSize())
+	if c, err = NewEAXWithNonceSequence(block, 16, nonces); err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+	buf := append(make([]byte, 0, c.NonceSize()+len(msg)+c.Overhead()), msg...)
+	ciphertext, err := c.SealNext(buf[:0], buf, data)
+	if err != nil {
+		t.Fatalf("In-place SealNext failed: %s", err)
+	}
+	plaintext, err := c.Open(nil, ciphertext[:c.NonceSize()], ciphertext[c.NonceSize():], data)
+	if err != nil {
+		t.Fatalf("Open of the in-place SealNext ciphertext failed: %s", err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Fatalf("Open of the in-place SealNext ciphertext returned %q - but expected %q", plaintext, msg)
+	}
+
+	if _, err = NewEAXWithNonceSequence(block, 16, NewNonceSequence(12)); err == nil {
+		t.Fatal("NewEAXWithNonceSequence accepted a 12 byte nonce sequence")
+	}
+	if _, err = newTestEAX(t).SealNext(nil, msg, data); err == nil {
+		t.Fatal("SealNext succeeded without a nonce sequence")
+	}
+}
+
+func TestSealTag(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	nonce, msg, data := make([]byte, 16), []byte("mixed tag sizes"), []byte("data")
+
+	c := newTestEAX(t)
+	for _, size := range []int{16, 8, 1, 16, 12} {
+		ref, err := NewEAX(block, size)
+		if err != nil {
+			t.Fatalf("Tag size %d: Failed to create EAX instance: %s", size, err)
+		}
+		ciphertext := c.SealTag(nil, nonce, msg, data, size)
+		if expected := ref.Seal(nil, nonce, msg, data); !bytes.Equal(ciphertext, expected) {
+			t.Fatalf("Tag size %d: SealTag differs from NewEAX with tag size %d", size, size)
+		}
+		plaintext, err := c.OpenTag(nil, nonce, ciphertext, data, size)
+		if err != nil {
+			t.Fatalf("Tag size %d: OpenTag failed: %s", size, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("Tag size %d: OpenTag returned %q - but expected %q", size, plaintext, msg)
+		}
+		if _, err = c.OpenTag(nil, nonce, ciphertext[:len(ciphertext)-1], data, size); err == nil {
+			t.Fatalf("Tag size %d: OpenTag accepted a truncated tag", size)
+		}
+		if c.Overhead() != 16 {
+			t.Fatalf("Tag size %d: SealTag changed the default tag size", size)
+		}
+	}
+
+	// a bound tag size is bound per call
+	bound, err := NewEAXBindTagSize(block, 16)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+	ciphertext := bound.SealTag(nil, nonce, msg, data, 16)
+	if _, err = bound.OpenTag(nil, nonce, ciphertext[:len(ciphertext)-8], data, 8); err == nil {
+		t.Fatal("OpenTag accepted a truncated tag of a bound tag size")
+	}
+
+	if _, err = c.OpenTag(nil, nonce, ciphertext, data, 17); err == nil {
+		t.Fatal("OpenTag accepted a tag size of 17")
+	}
+	mustFail := func(msg string, tagsize int) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Expected error: %s", msg)
+			}
+		}()
+		c.SealTag(nil, nonce, nil, nil, tagsize)
+	}
+	mustFail("tag size is 0", 0)
+	mustFail("tag size is larger than the block size", 17)
+}
+
+func TestVerifyAD(t *testing.T) {
+	c := newTestEAX(t)
+	nonce, msg, header := make([]byte, c.NonceSize()), []byte("payload"), []byte("header")
+	ciphertext := c.Seal(nil, nonce, msg, header)
+	orig := append([]byte{}, ciphertext...)
+
+	if ok, err := c.VerifyAD(nonce, ciphertext, header); err != nil || !ok {
+		t.Fatalf("VerifyAD rejected the correct header: %v, %v", ok, err)
+	}
+	if !bytes.Equal(ciphertext, orig) {
+		t.Fatal("VerifyAD modified the ciphertext")
+	}
+	if ok, err := c.VerifyAD(nonce, ciphertext, []byte("Header")); err != nil || ok {
+		t.Fatalf("VerifyAD accepted a wrong header: %v, %v", ok, err)
+	}
+	if ok, _ := c.VerifyAD(nonce, ciphertext[:c.Overhead()-1], header); ok {
+		t.Fatal("VerifyAD accepted a truncated ciphertext")
+	}
+	if _, err := c.VerifyAD(nonce[1:], ciphertext, header); err == nil {
+		t.Fatal("VerifyAD accepted an invalid nonce")
+	}
+
+	noTag, err := NewEAXNoTag(c.blockCipher)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+	if _, err = noTag.VerifyAD(nonce, ciphertext, header); err == nil {
+		t.Fatal("VerifyAD accepted an EAX instance without tag")
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	c := newTestEAX(t)
+	nonce, data := make([]byte, c.NonceSize()), []byte("data")
+	ciphertext := c.Seal(nil, nonce, []byte("payload"), data)
+
+	for n := 0; n < c.Overhead(); n++ {
+		_, err := c.Open(nil, nonce, ciphertext[:n], data)
+		if _, ok := err.(crypto.AuthenticationError); ok || err != ErrCiphertextTooShort {
+			t.Fatalf("Open of %d bytes returned %v - but expected ErrCiphertextTooShort", n, err)
+		}
+		if err := c.OpenStream(new(bytes.Buffer), bytes.NewReader(ciphertext[:n]), nonce, data); err != ErrCiphertextTooShort {
+			t.Fatalf("OpenStream of %d bytes returned %v - but expected ErrCiphertextTooShort", n, err)
+		}
+	}
+	for _, n := range []int{c.Overhead(), len(ciphertext) - 1} {
+		if _, err := c.Open(nil, nonce, ciphertext[:n], data); !errors.Is(err, crypto.AuthenticationError{Algorithm: "EAX"}) {
+			t.Fatalf("Open of %d bytes returned %v - but expected an AuthenticationError", n, err)
+		}
+		if err := c.OpenStream(new(bytes.Buffer), bytes.NewReader(ciphertext[:n]), nonce, data); !errors.Is(err, crypto.AuthenticationError{Algorithm: "EAX"}) {
+			t.Fatalf("OpenStream of %d bytes returned %v - but expected an AuthenticationError", n, err)
+		}
+	}
+	ciphertext[0] ^= 1
+	if _, err := c.Open(nil, nonce, ciphertext, data); !errors.Is(err, crypto.AuthenticationError{Algorithm: "EAX"}) {
+		t.Fatalf("Open of a modified ciphertext returned %v - but expected an AuthenticationError", err)
+	}
+}
+
+func TestCTROffset(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	ref, err := NewEAX(block, 16)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+	nonce, msg, data := make([]byte, ref.NonceSize()), make([]byte, 40), []byte("data")
+
+	for _, offset := range []uint32{0, 1, 2, 0xffffffff} {
+		c, err := NewEAXCTROffset(block, 16, offset)
+		if err != nil {
+			t.Fatalf("Offset %d: Failed to create EAX instance: %s", offset, err)
+		}
+
+		// the body must be encrypted with the counter authNonce + offset
+		counter := append([]byte{}, ref.authNonce(nonce)...)
+		carry := uint64(offset)
+		for j := len(counter) - 1; j >= 0; j-- {
+			v := uint64(counter[j]) + carry
+			counter[j], carry = byte(v), v>>8
+		}
+		ciphertext := c.Seal(nil, nonce, msg, data)
+		if expected := ref.SealCounter(nil, nonce, counter, msg, data); !bytes.Equal(ciphertext, expected) {
+			t.Fatalf("Offset %d: Seal returned %x - but expected %x", offset, ciphertext, expected)
+		}
+		plaintext, err := c.Open(nil, nonce, ciphertext, data)
+		if err != nil {
+			t.Fatalf("Offset %d: Open failed: %s", offset, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("Offset %d: Open returned %x - but expected %x", offset, plaintext, msg)
+		}
+		if clone := c.Clone().(*EAX); !bytes.Equal(clone.Seal(nil, nonce, msg, data), ciphertext) {
+			t.Fatalf("Offset %d: Clone does not keep the CTR offset", offset)
+		}
+		var streamed bytes.Buffer
+		if err = c.OpenStream(&streamed, bytes.NewReader(ciphertext), nonce, data); err != nil {
+			t.Fatalf("Offset %d: OpenStream failed: %s", offset, err)
+		}
+		if !bytes.Equal(streamed.Bytes(), msg) {
+			t.Fatalf("Offset %d: OpenStream returned %x - but expected %x", offset, streamed.Bytes(), msg)
+		}
+
+		// a mismatched offset decrypts to a different plaintext
+		other, err := NewEAXCTROffset(block, 16, offset+1)
+		if err != nil {
+			t.Fatalf("Offset %d: Failed to create EAX instance: %s", offset, err)
+		}
+		plaintext, err = other.Open(nil, nonce, ciphertext, data)
+		if err != nil {
+			t.Fatalf("Offset %d: Open with offset %d failed: %s", offset, offset+1, err)
+		}
+		if bytes.Equal(plaintext, msg) {
+			t.Fatalf("Offset %d: Open with offset %d returned the plaintext", offset, offset+1)
+		}
+	}
+	if _, err = NewEAXCTROffset(block, 17, 1); err == nil {
+		t.Fatal("NewEAXCTROffset accepted an invalid tag size")
+	}
+}
+
+func TestEAXNonce96(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	c, err := NewEAXNonce96(block, 16)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+	if n := c.NonceSize(); n != 12 {
+		t.Fatalf("NonceSize returned %d - but expected 12", n)
+	}
+	if n := c.Clone().NonceSize(); n != 12 {
+		t.Fatalf("NonceSize of the clone returned %d - but expected 12", n)
+	}
+
+	nonce, msg, data := make([]byte, 12), []byte("migrated from GCM"), []byte("data")
+	for i := range nonce {
+		nonce[i] = byte(i)
+	}
+	ciphertext := c.Seal(nil, nonce, msg, data)
+	plaintext, err := c.Open(nil, nonce, ciphertext, data)
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Fatalf("Open returned %q - but expected %q", plaintext, msg)
+	}
+
+	// the body is the CTR mode starting at the OMAC of the 12 byte nonce
+	counter, err := EAXNonceToCounter(block, nonce)
+	if err != nil {
+		t.Fatalf("EAXNonceToCounter failed: %s", err)
+	}
+	body := make([]byte, len(msg))
+	cipher.NewCTR(block, counter).XORKeyStream(body, msg)
+	if !bytes.Equal(ciphertext[:len(msg)], body) {
+		t.Fatalf("Seal returned %x - but expected the CTR ciphertext %x", ciphertext[:len(msg)], body)
+	}
+
+	session := c.PrecomputeNonce(nonce)
+	if sealed := session.Seal(nil, msg, data); !bytes.Equal(sealed, ciphertext) {
+		t.Fatalf("EAXSession.Seal returned %x - but expected %x", sealed, ciphertext)
+	}
+
+	if _, err = c.Open(nil, make([]byte, 16), ciphertext, data); err == nil {
+		t.Fatal("Open accepted a 16 byte nonce")
+	}
+	mustFail := func(msg string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Expected error: %s", msg)
+			}
+		}()
+		f()
 	}
+	mustFail("nonce size is 16", func() { c.Seal(nil, make([]byte, 16), msg, data) })
 }
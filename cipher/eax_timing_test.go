@@ -0,0 +1,78 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// +build timing
+
+// The timing test is statistical and depends on the load of the
+// machine, so it is flaky by nature and only runs with the timing tag:
+//
+//	go test -tags timing -run Timing github.com/enceve/crypto/cipher
+
+package cipher
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+const (
+	timingSamples   = 2001 // number of samples per tag
+	timingBatch     = 64   // Open calls per sample
+	timingWarmUp    = 200  // samples discarded before measuring
+	timingTolerance = 0.05 // max. relative difference of the medians
+)
+
+// TestEAXOpenTimingConstant checks that the time of Open does not depend on
+// the position of the first differing byte of the tag - so the tag comparison
+// is done in constant time. A correct tag is measured, too, but Open returns
+// the plaintext only for a correct tag, so only the two modified tags are
+// compared strictly. The samples of the three tags are interleaved, so drift
+// of the CPU frequency affects all of them in the same way, and the medians
+// filter outliers caused by interrupts or the scheduler.
+func TestEAXOpenTimingConstant(t *testing.T) {
+	c := newTestEAX(t)
+	nonce, data := make([]byte, c.NonceSize()), make([]byte, 64)
+	ciphertext := c.Seal(nil, nonce, nil, data)
+
+	first := append([]byte{}, ciphertext...)
+	first[0] ^= 1
+	last := append([]byte{}, ciphertext...)
+	last[len(last)-1] ^= 1
+
+	tags := [][]byte{ciphertext, first, last}
+	samples := make([][]time.Duration, len(tags))
+	for i := range samples {
+		samples[i] = make([]time.Duration, 0, timingSamples)
+	}
+	dst := make([]byte, 0, len(ciphertext))
+	for i := 0; i < timingWarmUp+timingSamples; i++ {
+		for j, tag := range tags {
+			start := time.Now()
+			for k := 0; k < timingBatch; k++ {
+				c.Open(dst, nonce, tag, data)
+			}
+			if elapsed := time.Since(start); i >= timingWarmUp {
+				samples[j] = append(samples[j], elapsed)
+			}
+		}
+	}
+
+	correct, firstByte, lastByte := median(samples[0]), median(samples[1]), median(samples[2])
+	t.Logf("median of %d Open calls - correct tag: %v, first byte differs: %v, last byte differs: %v",
+		timingBatch, correct, firstByte, lastByte)
+
+	diff := float64(firstByte-lastByte) / float64(firstByte+lastByte) * 2
+	if diff < 0 {
+		diff = -diff
+	}
+	if diff > timingTolerance {
+		t.Fatalf("Open timing depends on the position of the modified tag byte: medians differ by %.1f%%", diff*100)
+	}
+}
+
+// median returns the median of the durations and sorts them.
+func median(d []time.Duration) time.Duration {
+	sort.Slice(d, func(i, j int) bool { return d[i] < d[j] })
+	return d[len(d)/2]
+}
@@ -0,0 +1,154 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/cmac"
+	"github.com/enceve/crypto/internal/alias"
+)
+
+// The block size of EAX' in bytes.
+const eaxPrimeBlockSize = 16
+
+// EAXPrime implements the EAX' (EAX prime) mode of operation of the
+// ANSI C12.22 standard, which is used to protect smart-meter messages.
+// EAX' is a variant of EAX with a single "cleartext" - in C12.22 the
+// header of the message - instead of a nonce and additional data. The
+// cleartext is authenticated and its OMAC is the CTR counter, so the
+// cleartext of every message must be unique for one key.
+//
+// EAX' is NOT secure and must not be used by new designs - it only exists
+// to interoperate with existing C12.22 devices. Minematsu, Lucks, Morita
+// and Iwata showed (Attacks and Security Proofs of EAX-Prime, FSE 2013)
+// that the optimized OMAC of EAX' cancels out for cleartexts of exactly
+// one block. With such cleartexts an attacker can forge messages and
+// distinguish the ciphertexts from random after a few queries. Short
+// messages and short tags (C12.22 uses 4 bytes) make forgeries even
+// cheaper. Use EAX, SIV or ChaCha20-Poly1305 instead.
+//
+// An EAXPrime instance is not safe for concurrent use.
+type EAXPrime struct {
+	blockCipher cipher.Block
+	d, q        []byte // the OMAC' tweaks - equal to the CMac subkeys
+	size        int
+
+	// scratch buffers for the OMAC' computations
+	authCleartext, authCt []byte
+}
+
+// NewEAXPrime returns a *EAXPrime wrapping the block cipher, which must
+// have a block size of 16 bytes (e.g. AES-128 as required by C12.22).
+// The tagsize argument specifies the number of bytes of the auth. tag
+// and must be between 1 and 16 - C12.22 uses 4 byte tags.
+// This function returns a non-nil error if the block size of the cipher
+// or the tag size is invalid.
+// See EAXPrime for the weaknesses of EAX'.
+func NewEAXPrime(c cipher.Block, tagsize int) (*EAXPrime, error) {
+	if c.BlockSize() != eaxPrimeBlockSize {
+		return nil, errors.New("EAX' requires a block cipher with a block size of 16 bytes")
+	}
+	if tagsize < 1 || tagsize > eaxPrimeBlockSize {
+		return nil, errTagSize
+	}
+	d, q := cmac.SubKeys(c)
+	buf := make([]byte, 2*eaxPrimeBlockSize)
+	return &EAXPrime{
+		blockCipher:   c,
+		d:             d,
+		q:             q,
+		size:          tagsize,
+		authCleartext: buf[:eaxPrimeBlockSize:eaxPrimeBlockSize],
+		authCt:        buf[eaxPrimeBlockSize:],
+	}, nil
+}
+
+// Overhead returns the size of the authentication tag.
+func (c *EAXPrime) Overhead() int { return c.size }
+
+// Seal encrypts and authenticates the plaintext, authenticates the
+// cleartext and appends the ciphertext followed by the tag to dst. The
+// cleartext itself is not appended. To reuse the storage of the plaintext
+// use plaintext[:0] as dst. Otherwise dst and plaintext must not overlap.
+func (c *EAXPrime) Seal(dst, cleartext, plaintext []byte) []byte {
+	authCleartext := c.omac(c.authCleartext, c.d, cleartext)
+
+	ret, out := sliceForAppend(dst, len(plaintext)+c.size)
+	c.ctrCrypt(out[:len(plaintext)], plaintext, authCleartext)
+
+	authCt := c.omac(c.authCt, c.q, out[:len(plaintext)])
+	for i := range out[len(plaintext):] {
+		out[len(plaintext)+i] = authCleartext[i] ^ authCt[i]
+	}
+	return ret
+}
+
+// Open authenticates the cleartext and the ciphertext, decrypts the
+// ciphertext and appends the plaintext to dst. To reuse the storage of
+// the ciphertext use ciphertext[:0] as dst. Otherwise dst and ciphertext
+// must not overlap. Open returns ErrCiphertextTooShort if the ciphertext
+// is shorter than the tag and a crypto.AuthenticationError if the tag is
+// not valid.
+func (c *EAXPrime) Open(dst, cleartext, ciphertext []byte) ([]byte, error) {
+	if len(ciphertext) < c.size {
+		return nil, ErrCiphertextTooShort
+	}
+	n := len(ciphertext) - c.size
+	authCleartext := c.omac(c.authCleartext, c.d, cleartext)
+	authCt := c.omac(c.authCt, c.q, ciphertext[:n])
+
+	var tag [eaxPrimeBlockSize]byte
+	for i := range tag[:c.size] {
+		tag[i] = authCleartext[i] ^ authCt[i]
+	}
+	if subtle.ConstantTimeCompare(tag[:c.size], ciphertext[n:]) != 1 {
+		return nil, crypto.AuthenticationError{Algorithm: "EAX'"}
+	}
+
+	ret, out := sliceForAppend(dst, n)
+	c.ctrCrypt(out, ciphertext[:n], authCleartext)
+	return ret, nil
+}
+
+// omac computes the OMAC' of EAX': the CMac of msg, but the CBC chain
+// starts with the tweak instead of the zero block. The tweak replaces the
+// encrypted tag block of EAX. The result is written to and returned as buf.
+func (c *EAXPrime) omac(buf, tweak, msg []byte) []byte {
+	copy(buf, tweak)
+	for len(msg) > eaxPrimeBlockSize {
+		crypto.XOR(buf, buf, msg[:eaxPrimeBlockSize])
+		c.blockCipher.Encrypt(buf, buf)
+		msg = msg[eaxPrimeBlockSize:]
+	}
+	if len(msg) == eaxPrimeBlockSize {
+		crypto.XOR(buf, buf, msg)
+		crypto.XOR(buf, buf, c.d)
+	} else {
+		crypto.XOR(buf, buf, msg)
+		buf[len(msg)] ^= 0x80
+		crypto.XOR(buf, buf, c.q)
+	}
+	c.blockCipher.Encrypt(buf, buf)
+	return buf
+}
+
+// ctrCrypt encrypts src with the CTR mode starting at the OMAC' of
+// the cleartext with the bits 31 and 15 (from the right) cleared.
+func (c *EAXPrime) ctrCrypt(dst, src, authCleartext []byte) {
+	var ctr [eaxPrimeBlockSize]byte
+	copy(ctr[:], authCleartext)
+	ctr[12] &= 0x7f
+	ctr[14] &= 0x7f
+	if alias.InexactOverlap(dst, src) {
+		tmp := make([]byte, len(src))
+		cipher.NewCTR(c.blockCipher, ctr[:]).XORKeyStream(tmp, src)
+		copy(dst, tmp)
+		return
+	}
+	cipher.NewCTR(c.blockCipher, ctr[:]).XORKeyStream(dst, src)
+}
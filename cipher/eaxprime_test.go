@@ -0,0 +1,136 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"errors"
+	"testing"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/internal/testvec"
+)
+
+// These are not the C12.22 test vectors. They were computed by an
+// independent implementation of the EAX' specification using the
+// AES-128-ECB and AES-128-CTR of OpenSSL.
+var eaxPrimeVectors = []struct {
+	cleartext, plaintext, ciphertext string
+	tagsize                          int
+}{
+	{
+		cleartext:  "",
+		plaintext:  "",
+		ciphertext: "5a97ac16",
+		tagsize:    4,
+	},
+	{
+		cleartext:  "000102030405060708090a0b0c0d0e0f",
+		plaintext:  "",
+		ciphertext: "490d5c999784a3044910cc2e615a60ad",
+		tagsize:    16,
+	},
+	{
+		cleartext:  "000102030405060708090a0b0c0d0e0f10111213",
+		plaintext:  "0001020304",
+		ciphertext: "a35d1ef506" + "1bcddebd",
+		tagsize:    4,
+	},
+	{
+		cleartext:  "000102030405060708090a0b0c0d0e0f",
+		plaintext:  "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f",
+		ciphertext: "2b735bf70bd25a633e59e563c1ce0980bf4e431392914552eae4eb75dd2bddae" + "123dc063",
+		tagsize:    4,
+	},
+	{
+		cleartext: "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021222324252627",
+		plaintext: "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f" +
+			"202122232425262728292a2b2c2d2e2f3031",
+		ciphertext: "cbc1f5e75d6d316ebaffc3af5848badee77e53f41f69ecf3cd6efc638bad9873" +
+			"4f307bdc308d88d7f00042913295219a6ccf" +
+			"0e3638fc3388f198bc473f9dd3b9195a",
+		tagsize: 16,
+	},
+}
+
+func newTestEAXPrime(t testing.TB, tagsize int) *EAXPrime {
+	block, err := aes.NewCipher(testvec.MustDecodeHex("000102030405060708090a0b0c0d0e0f"))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	c, err := NewEAXPrime(block, tagsize)
+	if err != nil {
+		t.Fatalf("Failed to create EAX' instance: %s", err)
+	}
+	return c
+}
+
+func TestEAXPrimeVectors(t *testing.T) {
+	for i, v := range eaxPrimeVectors {
+		c := newTestEAXPrime(t, v.tagsize)
+		cleartext, plaintext := testvec.MustDecodeHex(v.cleartext), testvec.MustDecodeHex(v.plaintext)
+
+		ciphertext := c.Seal(nil, cleartext, plaintext)
+		if !testvec.EqualHex(ciphertext, v.ciphertext) {
+			t.Fatalf("Test vector %d: Seal returned %x - but expected %s", i, ciphertext, v.ciphertext)
+		}
+		decrypted, err := c.Open(nil, cleartext, ciphertext)
+		if err != nil {
+			t.Fatalf("Test vector %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("Test vector %d: Open returned %x - but expected %x", i, decrypted, plaintext)
+		}
+
+		// in place
+		buf := append(make([]byte, 0, len(plaintext)+v.tagsize), plaintext...)
+		if sealed := c.Seal(buf[:0], cleartext, buf); !bytes.Equal(sealed, ciphertext) {
+			t.Fatalf("Test vector %d: In-place Seal returned %x - but expected %x", i, sealed, ciphertext)
+		}
+		if decrypted, err = c.Open(buf[:0], cleartext, buf[:len(ciphertext)]); err != nil || !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("Test vector %d: In-place Open returned %x, %v - but expected %x", i, decrypted, err, plaintext)
+		}
+	}
+}
+
+// The OMAC' of a one block cleartext is the encryption of the cleartext -
+// the weakness of EAX' shown by Minematsu et al.
+func TestEAXPrimeOneBlockCleartext(t *testing.T) {
+	c := newTestEAXPrime(t, 16)
+	cleartext := testvec.MustDecodeHex("000102030405060708090a0b0c0d0e0f")
+
+	expected := make([]byte, 16)
+	c.blockCipher.Encrypt(expected, cleartext)
+	if authCleartext := c.omac(make([]byte, 16), c.d, cleartext); !bytes.Equal(authCleartext, expected) {
+		t.Fatalf("OMAC' of a one block cleartext is %x - but expected %x", authCleartext, expected)
+	}
+}
+
+func TestEAXPrime(t *testing.T) {
+	c := newTestEAXPrime(t, 4)
+	cleartext, msg := []byte("C12.22 header"), []byte("meter reading")
+	ciphertext := c.Seal(nil, cleartext, msg)
+	if len(ciphertext) != len(msg)+c.Overhead() {
+		t.Fatalf("Seal returned %d bytes - but expected %d", len(ciphertext), len(msg)+c.Overhead())
+	}
+
+	if _, err := c.Open(nil, []byte("other header"), ciphertext); !errors.Is(err, crypto.AuthenticationError{Algorithm: "EAX'"}) {
+		t.Fatalf("Open with another cleartext returned %v - but expected an AuthenticationError", err)
+	}
+	ciphertext[0] ^= 1
+	if _, err := c.Open(nil, cleartext, ciphertext); !errors.Is(err, crypto.AuthenticationError{Algorithm: "EAX'"}) {
+		t.Fatalf("Open of a modified ciphertext returned %v - but expected an AuthenticationError", err)
+	}
+	if _, err := c.Open(nil, cleartext, ciphertext[:c.Overhead()-1]); err != ErrCiphertextTooShort {
+		t.Fatalf("Open of a truncated ciphertext returned %v - but expected ErrCiphertextTooShort", err)
+	}
+
+	block, _ := aes.NewCipher(make([]byte, 16))
+	for _, tagsize := range []int{0, 17} {
+		if _, err := NewEAXPrime(block, tagsize); err == nil {
+			t.Fatalf("NewEAXPrime accepted the tag size %d", tagsize)
+		}
+	}
+}
@@ -0,0 +1,52 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import "github.com/enceve/crypto"
+
+// EAXSession seals and opens messages with one fixed nonce, whose OMAC
+// is computed only once by PrecomputeNonce. This saves one OMAC pass per
+// message.
+//
+// All messages of a session share one CTR keystream: the XOR of two
+// ciphertexts is the XOR of their plaintexts - also if the plaintexts are
+// unique random keys. So the confidentiality is lost as soon as more than
+// one message is sealed with a session. A session must seal at most one
+// message per key - it may open this message repeatedly. Key wrapping
+// with a fixed nonce requires a deterministic AEAD like SIV instead.
+//
+// An EAXSession uses the scratch buffers of its EAX instance, so neither
+// the session nor the EAX instance must be used concurrently.
+type EAXSession struct {
+	eax       *EAX
+	authNonce []byte
+}
+
+// PrecomputeNonce returns an EAXSession for the given nonce computing
+// the OMAC of the nonce once. See EAXSession for why at most one message
+// must be sealed with the session. If the nonce size is invalid this function panics.
+func (c *EAX) PrecomputeNonce(nonce []byte) *EAXSession {
+	if n := len(nonce); n != c.nonceSize {
+		panic(crypto.NonceSizeMismatchError{Size: n, Expected: c.nonceSize})
+	}
+	authNonce := make([]byte, c.blockCipher.BlockSize())
+	copy(authNonce, c.authNonce(nonce))
+	return &EAXSession{eax: c, authNonce: authNonce}
+}
+
+// Seal encrypts and authenticates the plaintext and the additional data
+// like the Seal function of the EAX instance with the nonce of the session
+// and appends the result to dst.
+func (s *EAXSession) Seal(dst, plaintext, additionalData []byte) []byte {
+	authData := s.eax.authAdditionalData(additionalData)
+	return s.eax.seal(dst, nil, plaintext, s.authNonce, authData)
+}
+
+// Open decrypts and authenticates the ciphertext and the additional data
+// like the Open function of the EAX instance with the nonce of the session
+// and appends the plaintext to dst.
+func (s *EAXSession) Open(dst, ciphertext, additionalData []byte) ([]byte, error) {
+	authData := s.eax.authAdditionalData(additionalData)
+	return s.eax.open(dst, nil, ciphertext, s.authNonce, authData)
+}
@@ -0,0 +1,70 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestEAXSession(t *testing.T) {
+	c := newTestEAX(t)
+	nonce := []byte("a fixed nonce...")
+	s := c.PrecomputeNonce(nonce)
+
+	for _, size := range []int{0, 1, 16, 32, 100} {
+		msg, data := make([]byte, size), []byte("data")
+		for i := range msg {
+			msg[i] = byte(i)
+		}
+		ciphertext := s.Seal(nil, msg, data)
+		if want := c.Seal(nil, nonce, msg, data); !bytes.Equal(ciphertext, want) {
+			t.Fatalf("Size %d: Seal of the session returned %x - but expected %x", size, ciphertext, want)
+		}
+		plaintext, err := s.Open(nil, ciphertext, data)
+		if err != nil {
+			t.Fatalf("Size %d: Open of the session failed: %s", size, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("Size %d: Open of the session returned %x - but expected %x", size, plaintext, msg)
+		}
+		if _, err = s.Open(nil, ciphertext, nil); err == nil {
+			t.Fatalf("Size %d: Open of the session accepted wrong additional data", size)
+		}
+	}
+
+	// the session is not affected by other nonces used by the EAX instance
+	c.Seal(nil, make([]byte, c.NonceSize()), []byte("other"), nil)
+	if _, err := c.Open(nil, nonce, s.Seal(nil, []byte("msg"), nil), nil); err != nil {
+		t.Fatalf("Open failed for a message sealed by the session: %s", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected error: PrecomputeNonce accepted an invalid nonce")
+		}
+	}()
+	c.PrecomputeNonce(nonce[1:])
+}
+
+func BenchmarkSeal_16B(b *testing.B) {
+	c := newTestEAX(b)
+	nonce, msg, data := make([]byte, c.NonceSize()), make([]byte, 16), make([]byte, 8)
+	dst := make([]byte, len(msg)+c.Overhead())
+	b.SetBytes(16)
+	for i := 0; i < b.N; i++ {
+		dst = c.Seal(dst[:0], nonce, msg, data)
+	}
+}
+
+func BenchmarkSessionSeal_16B(b *testing.B) {
+	c := newTestEAX(b)
+	s := c.PrecomputeNonce(make([]byte, c.NonceSize()))
+	msg, data := make([]byte, 16), make([]byte, 8)
+	dst := make([]byte, len(msg)+c.Overhead())
+	b.SetBytes(16)
+	for i := 0; i < b.N; i++ {
+		dst = s.Seal(dst[:0], msg, data)
+	}
+}
@@ -0,0 +1,86 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	cryptorand "crypto/rand"
+	"encoding/binary"
+	"io"
+
+	"github.com/enceve/crypto"
+)
+
+// EnvelopeAEAD wraps a cipher.AEAD and binds every ciphertext to the
+// envelope of the message - the message ID and the timestamp. So a valid
+// ciphertext cannot be replayed under a different envelope. The additional
+// data is constructed canonically from the envelope:
+//
+//	len(msgID) (64 bit big-endian) || msgID || timestamp (64 bit big-endian)
+//
+// Every message is sealed with a random nonce, which is prepended to the
+// ciphertext. The nonce size of the AEAD should be at least 12 bytes -
+// with 12 byte nonces at most 2^32 messages should be sealed with one key.
+// Notice that EnvelopeAEAD does not detect replays of the same envelope.
+type EnvelopeAEAD struct {
+	aead   cipher.AEAD
+	random io.Reader
+}
+
+// NewEnvelopeAEAD returns a new EnvelopeAEAD wrapping the
+// given cipher.AEAD. The nonces are read from crypto/rand.
+func NewEnvelopeAEAD(inner cipher.AEAD) *EnvelopeAEAD {
+	return NewEnvelopeAEADRand(inner, nil)
+}
+
+// NewEnvelopeAEADRand returns a new EnvelopeAEAD wrapping the given
+// cipher.AEAD, which reads the nonces from rand. If rand is nil,
+// crypto/rand will be used. A deterministic rand must only be used
+// for testing - a repeated nonce breaks the security of the AEAD.
+func NewEnvelopeAEADRand(inner cipher.AEAD, rand io.Reader) *EnvelopeAEAD {
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+	return &EnvelopeAEAD{
+		aead:   inner,
+		random: rand,
+	}
+}
+
+// Overhead returns the difference between the lengths of
+// ciphertext and plaintext (the nonce and the overhead of
+// the wrapped cipher.AEAD).
+func (e *EnvelopeAEAD) Overhead() int { return e.aead.NonceSize() + e.aead.Overhead() }
+
+// Seal encrypts and authenticates the plaintext and the envelope
+// and returns the random nonce followed by the ciphertext.
+// Seal returns a non-nil error if the nonce cannot be generated.
+func (e *EnvelopeAEAD) Seal(msgID string, timestamp int64, plaintext []byte) ([]byte, error) {
+	n := e.aead.NonceSize()
+	out := make([]byte, n, n+len(plaintext)+e.aead.Overhead())
+	if _, err := io.ReadFull(e.random, out); err != nil {
+		return nil, err
+	}
+	return e.aead.Seal(out, out, plaintext, envelopeData(msgID, timestamp)), nil
+}
+
+// Open decrypts and authenticates the ciphertext created by Seal and the
+// envelope and returns the plaintext. If the envelope does not match the
+// envelope used for Seal Open returns an AuthenticationError.
+func (e *EnvelopeAEAD) Open(msgID string, timestamp int64, ciphertext []byte) ([]byte, error) {
+	n := e.aead.NonceSize()
+	if len(ciphertext) < n {
+		return nil, crypto.AuthenticationError{Algorithm: "Envelope"}
+	}
+	return e.aead.Open(nil, ciphertext[:n], ciphertext[n:], envelopeData(msgID, timestamp))
+}
+
+// envelopeData returns the canonical additional data of the envelope.
+func envelopeData(msgID string, timestamp int64) []byte {
+	data := make([]byte, 8+len(msgID)+8)
+	binary.BigEndian.PutUint64(data, uint64(len(msgID)))
+	copy(data[8:], msgID)
+	binary.BigEndian.PutUint64(data[8+len(msgID):], uint64(timestamp))
+	return data
+}
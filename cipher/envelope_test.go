@@ -0,0 +1,89 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/enceve/crypto"
+)
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("no entropy") }
+
+func TestEnvelope(t *testing.T) {
+	e := NewEnvelopeAEAD(newTestEAX(t))
+	msg := []byte("queued message")
+
+	ciphertext, err := e.Seal("msg-1", 1500000000, msg)
+	if err != nil {
+		t.Fatalf("Seal failed: %s", err)
+	}
+	if len(ciphertext) != len(msg)+e.Overhead() {
+		t.Fatalf("Seal returned %d bytes - but expected %d", len(ciphertext), len(msg)+e.Overhead())
+	}
+	plaintext, err := e.Open("msg-1", 1500000000, ciphertext)
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Fatalf("Open returned %q - but expected %q", plaintext, msg)
+	}
+
+	if other, _ := e.Seal("msg-1", 1500000000, msg); bytes.Equal(other, ciphertext) {
+		t.Fatal("Seal used the same nonce twice")
+	}
+	if _, err = e.Open("msg-2", 1500000000, ciphertext); err == nil {
+		t.Fatal("Open accepted a different message ID")
+	}
+	if _, err = e.Open("msg-1", 1500000001, ciphertext); err == nil {
+		t.Fatal("Open accepted a different timestamp")
+	}
+	if _, err = e.Open("msg-1", 1500000000, ciphertext[:e.aead.NonceSize()-1]); !errors.Is(err, crypto.AuthenticationError{Algorithm: "Envelope"}) {
+		t.Fatal("Open accepted a truncated ciphertext")
+	}
+
+	e.random = failingReader{}
+	if _, err = e.Seal("msg-1", 0, msg); err == nil {
+		t.Fatal("Seal succeeded without a nonce")
+	}
+}
+
+func TestEnvelopeData(t *testing.T) {
+	// the message ID must not shift into the timestamp
+	if bytes.Equal(envelopeData("a", 0x6200000000000000), envelopeData("ab", 0)) {
+		t.Fatal("envelopeData is ambiguous")
+	}
+}
+
+func TestEnvelopeRand(t *testing.T) {
+	aead := newTestEAX(t)
+	stream := make([]byte, 2*aead.NonceSize())
+	for i := range stream {
+		stream[i] = byte(i)
+	}
+	e := NewEnvelopeAEADRand(aead, bytes.NewReader(stream))
+	msg := []byte("queued message")
+
+	for i := 0; i < 2; i++ {
+		nonce := stream[i*aead.NonceSize() : (i+1)*aead.NonceSize()]
+		expected := append(append([]byte{}, nonce...), aead.Seal(nil, nonce, msg, envelopeData("msg-1", 1500000000))...)
+		ciphertext, err := e.Seal("msg-1", 1500000000, msg)
+		if err != nil {
+			t.Fatalf("Message %d: Seal failed: %s", i, err)
+		}
+		if !bytes.Equal(ciphertext, expected) {
+			t.Fatalf("Message %d: Seal returned %x - but expected %x", i, ciphertext, expected)
+		}
+	}
+	if _, err := e.Seal("msg-1", 1500000000, msg); err == nil {
+		t.Fatal("Seal succeeded although the random source is exhausted")
+	}
+	if e = NewEnvelopeAEADRand(aead, nil); e.random == nil {
+		t.Fatal("NewEnvelopeAEADRand does not use crypto/rand for a nil random source")
+	}
+}
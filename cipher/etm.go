@@ -0,0 +1,98 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"encoding/binary"
+	"hash"
+
+	"github.com/enceve/crypto"
+)
+
+// NewEtM returns a cipher.AEAD composing a stream cipher and a MAC with
+// independent keys using encrypt-then-MAC. The stream function returns the
+// cipher.Stream for a nonce and the mac function returns the MAC for a nonce.
+// Both functions capture their (independent) keys. Seal encrypts the plaintext
+// with the stream and appends the MAC of:
+//
+//	nonce || additional data || ciphertext || len(additional data) || len(ciphertext)
+//
+// (the lengths are encoded as 64 bit big-endian), so the boundaries are
+// unambiguous and the nonce is authenticated even if the MAC key does not
+// depend on the nonce. Open verifies the MAC before decrypting.
+// The mac function receives the nonce, so one-time MACs like Poly1305 can be
+// keyed per nonce - a one-time MAC must never be used with a fixed key.
+// The Overhead is the Size of the MAC and the nonce must be nonceSize bytes.
+// If nonceSize is negative or one of the functions is nil this function panics.
+func NewEtM(nonceSize int, stream func(nonce []byte) cipher.Stream, mac func(nonce []byte) hash.Hash) cipher.AEAD {
+	if nonceSize < 0 {
+		panic("nonce size must not be negative")
+	}
+	if stream == nil || mac == nil {
+		panic("stream and mac functions must not be nil")
+	}
+	return &etm{
+		nonceSize: nonceSize,
+		tagSize:   mac(make([]byte, nonceSize)).Size(),
+		stream:    stream,
+		mac:       mac,
+	}
+}
+
+// The encrypt-then-MAC AEAD
+type etm struct {
+	nonceSize, tagSize int
+	stream             func(nonce []byte) cipher.Stream
+	mac                func(nonce []byte) hash.Hash
+}
+
+func (c *etm) NonceSize() int { return c.nonceSize }
+
+func (c *etm) Overhead() int { return c.tagSize }
+
+func (c *etm) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if n := len(nonce); n != c.nonceSize {
+		panic(crypto.NonceSizeMismatchError{Size: n, Expected: c.nonceSize})
+	}
+	n := len(plaintext)
+	ret, out := sliceForAppend(dst, n+c.tagSize)
+	c.stream(nonce).XORKeyStream(out[:n], plaintext)
+	copy(out[n:], c.authenticate(nonce, out[:n], additionalData))
+	return ret
+}
+
+func (c *etm) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if n := len(nonce); n != c.nonceSize {
+		return nil, crypto.NonceSizeMismatchError{Size: n, Expected: c.nonceSize}
+	}
+	if len(ciphertext) < c.tagSize {
+		return nil, crypto.AuthenticationError{Algorithm: "EtM"}
+	}
+	n := len(ciphertext) - c.tagSize
+	tag := c.authenticate(nonce, ciphertext[:n], additionalData)
+	if subtle.ConstantTimeCompare(tag, ciphertext[n:]) != 1 {
+		return nil, crypto.AuthenticationError{Algorithm: "EtM"}
+	}
+	ret, out := sliceForAppend(dst, n)
+	c.stream(nonce).XORKeyStream(out, ciphertext[:n])
+	return ret, nil
+}
+
+// authenticate computes the MAC of the nonce, the additional
+// data and the ciphertext.
+func (c *etm) authenticate(nonce, ciphertext, additionalData []byte) []byte {
+	var length [8]byte
+
+	h := c.mac(nonce)
+	h.Write(nonce)
+	h.Write(additionalData)
+	h.Write(ciphertext)
+	binary.BigEndian.PutUint64(length[:], uint64(len(additionalData)))
+	h.Write(length[:])
+	binary.BigEndian.PutUint64(length[:], uint64(len(ciphertext)))
+	h.Write(length[:])
+	return h.Sum(nil)
+}
@@ -0,0 +1,132 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"errors"
+	"hash"
+	"testing"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/chacha20"
+	"github.com/enceve/crypto/cmac"
+	"github.com/enceve/crypto/poly1305"
+)
+
+// polyHash adapts poly1305.Hash to hash.Hash
+type polyHash struct{ *poly1305.Hash }
+
+func (p polyHash) Sum(b []byte) []byte {
+	var tag [poly1305.TagSize]byte
+	p.Hash.Sum(&tag)
+	return append(b, tag[:]...)
+}
+
+func (p polyHash) Size() int { return poly1305.TagSize }
+
+func (p polyHash) BlockSize() int { return poly1305.TagSize }
+
+func (p polyHash) Reset() { panic("poly1305 does not support Reset") }
+
+func newTestEtM() cipher.AEAD {
+	var encKey, macKey [32]byte
+	for i := range encKey {
+		encKey[i], macKey[i] = byte(i), byte(255-i)
+	}
+	return NewEtM(chacha20.NonceSize,
+		func(nonce []byte) cipher.Stream {
+			var n [chacha20.NonceSize]byte
+			copy(n[:], nonce)
+			return chacha20.NewCipher(&n, &encKey)
+		},
+		func(nonce []byte) hash.Hash {
+			// a one-time Poly1305 key for every nonce
+			var n [chacha20.NonceSize]byte
+			var key [32]byte
+			copy(n[:], nonce)
+			chacha20.XORKeyStream(key[:], key[:], &n, &macKey, 0)
+			return polyHash{poly1305.New(&key)}
+		},
+	)
+}
+
+func TestEtM(t *testing.T) {
+	c := newTestEtM()
+	if c.NonceSize() != chacha20.NonceSize || c.Overhead() != poly1305.TagSize {
+		t.Fatalf("NonceSize %d and Overhead %d - but expected %d and %d", c.NonceSize(), c.Overhead(), chacha20.NonceSize, poly1305.TagSize)
+	}
+	nonce, data := make([]byte, c.NonceSize()), []byte("data")
+	for _, size := range []int{0, 1, 64, 100} {
+		msg := make([]byte, size)
+		for i := range msg {
+			msg[i] = byte(i)
+		}
+		ciphertext := c.Seal([]byte("prefix"), nonce, msg, data)[6:]
+		if len(ciphertext) != size+c.Overhead() {
+			t.Fatalf("Length %d: Seal returned %d bytes", size, len(ciphertext))
+		}
+		plaintext, err := c.Open(nil, nonce, ciphertext, data)
+		if err != nil {
+			t.Fatalf("Length %d: Open failed: %s", size, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("Length %d: Open returned %x - but expected %x", size, plaintext, msg)
+		}
+
+		for i := range ciphertext {
+			ciphertext[i] ^= 1
+			if _, err = c.Open(nil, nonce, ciphertext, data); err == nil {
+				t.Fatalf("Length %d: Open accepted a ciphertext modified at byte %d", size, i)
+			}
+			ciphertext[i] ^= 1
+		}
+		otherNonce := append([]byte{}, nonce...)
+		otherNonce[0] ^= 1
+		if _, err = c.Open(nil, otherNonce, ciphertext, data); err == nil {
+			t.Fatalf("Length %d: Open accepted a wrong nonce", size)
+		}
+		if _, err = c.Open(nil, nonce, ciphertext, []byte("date")); err == nil {
+			t.Fatalf("Length %d: Open accepted wrong additional data", size)
+		}
+	}
+
+	// the boundary between additional data and ciphertext is authenticated
+	ciphertext := c.Seal(nil, nonce, []byte("ab"), []byte("c"))
+	if _, err := c.Open(nil, nonce, ciphertext[1:], []byte("cx")); !errors.Is(err, crypto.AuthenticationError{Algorithm: "EtM"}) {
+		t.Fatal("Open accepted a moved boundary between additional data and ciphertext")
+	}
+	if _, err := c.Open(nil, nonce, ciphertext[:c.Overhead()-1], nil); !errors.Is(err, crypto.AuthenticationError{Algorithm: "EtM"}) {
+		t.Fatal("Open accepted a truncated tag")
+	}
+}
+
+func TestEtMFixedKeyMAC(t *testing.T) {
+	// a MAC with a fixed key must still authenticate the nonce
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	c := NewEtM(block.BlockSize(),
+		func(nonce []byte) cipher.Stream { return cipher.NewCTR(block, nonce) },
+		func([]byte) hash.Hash {
+			h, err := cmac.New(block)
+			if err != nil {
+				t.Fatalf("Failed to create CMac instance: %s", err)
+			}
+			return h
+		},
+	)
+	nonce, msg := make([]byte, c.NonceSize()), []byte("message")
+	ciphertext := c.Seal(nil, nonce, msg, nil)
+	if plaintext, err := c.Open(nil, nonce, ciphertext, nil); err != nil || !bytes.Equal(plaintext, msg) {
+		t.Fatalf("Open returned %q, %v - but expected %q", plaintext, err, msg)
+	}
+	nonce[0] ^= 1
+	if _, err = c.Open(nil, nonce, ciphertext, nil); err == nil {
+		t.Fatal("Open accepted a wrong nonce")
+	}
+}
@@ -0,0 +1,70 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+
+	"github.com/enceve/crypto/cmac"
+)
+
+// FingerprintAEAD wraps a cipher.AEAD and computes fingerprints of the
+// ciphertexts - e.g. to deduplicate sealed messages without comparing
+// (and so leaking timing about) the ciphertexts themselves. The fingerprint
+// is the CMac of the ciphertext under a separate fingerprint key, so it is
+// deterministic but reveals nothing about the ciphertext to anyone without
+// the fingerprint key. Identical ciphertexts have equal fingerprints -
+// notice that sealing the same plaintext twice with different nonces
+// produces different ciphertexts and fingerprints. To deduplicate messages
+// stored with their nonce, fingerprint the nonce together with the
+// ciphertext. The fingerprint key must not be used for anything else -
+// especially not as key of the wrapped AEAD.
+// A FingerprintAEAD is safe for concurrent use if the wrapped AEAD is.
+type FingerprintAEAD struct {
+	aead cipher.AEAD
+	mac  *cmac.Hash // never written - every fingerprint uses a clone
+}
+
+// NewFingerprintAEAD returns a new FingerprintAEAD wrapping the given
+// cipher.AEAD, which computes the fingerprints with the block cipher
+// fingerprintKey. This function returns a non-nil error if the block
+// cipher is not supported by CMac.
+func NewFingerprintAEAD(inner cipher.AEAD, fingerprintKey cipher.Block) (*FingerprintAEAD, error) {
+	mac, err := cmac.New(fingerprintKey)
+	if err != nil {
+		return nil, err
+	}
+	return &FingerprintAEAD{
+		aead: inner,
+		mac:  mac,
+	}, nil
+}
+
+// NonceSize returns the nonce size of the wrapped cipher.AEAD.
+func (f *FingerprintAEAD) NonceSize() int { return f.aead.NonceSize() }
+
+// Overhead returns the overhead of the wrapped cipher.AEAD.
+func (f *FingerprintAEAD) Overhead() int { return f.aead.Overhead() }
+
+// Seal seals the plaintext using the wrapped cipher.AEAD.
+func (f *FingerprintAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	return f.aead.Seal(dst, nonce, plaintext, additionalData)
+}
+
+// Open opens the ciphertext using the wrapped cipher.AEAD.
+func (f *FingerprintAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	return f.aead.Open(dst, nonce, ciphertext, additionalData)
+}
+
+// FingerprintSize returns the size of the fingerprints in bytes -
+// the block size of the fingerprint cipher.
+func (f *FingerprintAEAD) FingerprintSize() int { return f.mac.Size() }
+
+// Fingerprint returns the fingerprint of the ciphertext. Fingerprints
+// should be compared in constant time using crypto.CTEqual.
+func (f *FingerprintAEAD) Fingerprint(ciphertext []byte) []byte {
+	mac := f.mac.Clone()
+	mac.Write(ciphertext)
+	return mac.Sum(nil)
+}
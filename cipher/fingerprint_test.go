@@ -0,0 +1,69 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/cmac"
+)
+
+func TestFingerprintAEAD(t *testing.T) {
+	key := make([]byte, 16)
+	key[0] = 1
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	inner := newTestEAX(t)
+	f, err := NewFingerprintAEAD(inner, block)
+	if err != nil {
+		t.Fatalf("Failed to create FingerprintAEAD: %s", err)
+	}
+	if f.NonceSize() != inner.NonceSize() || f.Overhead() != inner.Overhead() {
+		t.Fatalf("NonceSize, Overhead: %d, %d - but expected %d, %d", f.NonceSize(), f.Overhead(), inner.NonceSize(), inner.Overhead())
+	}
+
+	nonce, msg := make([]byte, f.NonceSize()), []byte("deduplicated message")
+	ciphertext := f.Seal(nil, nonce, msg, nil)
+	if expected := inner.Seal(nil, nonce, msg, nil); !bytes.Equal(ciphertext, expected) {
+		t.Fatalf("Seal returned %x - but expected %x", ciphertext, expected)
+	}
+	if plaintext, err := f.Open(nil, nonce, ciphertext, nil); err != nil || !bytes.Equal(plaintext, msg) {
+		t.Fatalf("Open returned %q, %v - but expected %q", plaintext, err, msg)
+	}
+
+	fingerprint := f.Fingerprint(ciphertext)
+	if len(fingerprint) != f.FingerprintSize() {
+		t.Fatalf("Fingerprint returned %d bytes - but expected %d", len(fingerprint), f.FingerprintSize())
+	}
+	if expected, _ := cmac.Sum(ciphertext, block); !bytes.Equal(fingerprint, expected) {
+		t.Fatalf("Fingerprint returned %x - but expected the CMac %x", fingerprint, expected)
+	}
+	same := f.Seal(nil, nonce, msg, nil)
+	if !crypto.CTEqual(f.Fingerprint(same), fingerprint) {
+		t.Fatal("Identical ciphertexts have different fingerprints")
+	}
+
+	nonce[0] = 1
+	other := f.Seal(nil, nonce, msg, nil)
+	if crypto.CTEqual(f.Fingerprint(other), fingerprint) {
+		t.Fatal("Distinct ciphertexts have equal fingerprints")
+	}
+	if crypto.CTEqual(f.Fingerprint(ciphertext[:len(ciphertext)-1]), fingerprint) {
+		t.Fatal("A truncated ciphertext has the same fingerprint")
+	}
+
+	// the fingerprint depends on the fingerprint key
+	g, err := NewFingerprintAEAD(inner, inner.blockCipher)
+	if err != nil {
+		t.Fatalf("Failed to create FingerprintAEAD: %s", err)
+	}
+	if crypto.CTEqual(g.Fingerprint(ciphertext), fingerprint) {
+		t.Fatal("Different fingerprint keys produce the same fingerprint")
+	}
+}
@@ -0,0 +1,68 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+
+	"github.com/enceve/crypto"
+)
+
+// ErrMalformedFrame is returned by OpenFramed if the frame
+// cannot be parsed.
+var ErrMalformedFrame = errors.New("malformed frame")
+
+// SealFramed encrypts and authenticates the plaintext and the additional
+// data and appends a self-describing frame to dst:
+//
+//	len(additionalData) (32 bit big-endian) || additionalData || nonce || ciphertext
+//
+// The additional data is stored in cleartext and recovered by OpenFramed.
+// Dst and plaintext must not overlap. If the additional data is longer
+// than 2^32 - 1 bytes this function panics.
+func (c *EAX) SealFramed(dst, nonce, plaintext, additionalData []byte) []byte {
+	if uint64(len(additionalData)) > math.MaxUint32 {
+		panic("additional data is too large for a frame")
+	}
+	if n := len(nonce); n != c.NonceSize() {
+		panic(crypto.NonceSizeMismatchError{Size: n, Expected: c.NonceSize()})
+	}
+	headerLen := 4 + len(additionalData) + len(nonce)
+	ret, out := sliceForAppend(dst, headerLen)
+
+	binary.BigEndian.PutUint32(out, uint32(len(additionalData)))
+	copy(out[4:], additionalData)
+	copy(out[4+len(additionalData):], nonce)
+
+	return c.Seal(ret, nonce, plaintext, additionalData)
+}
+
+// OpenFramed parses a frame created by SealFramed, decrypts and authenticates
+// the ciphertext and appends the plaintext to dst. The returned additional data
+// is a copy - so the storage of the frame can be reused with frame[:0] as dst.
+// Otherwise dst and frame must not overlap. If the frame is too short for the
+// encoded length of the additional data OpenFramed returns ErrMalformedFrame
+// without authenticating the frame.
+func (c *EAX) OpenFramed(dst, frame []byte) (plaintext, additionalData []byte, err error) {
+	if len(frame) < 4 {
+		return nil, nil, ErrMalformedFrame
+	}
+	adLen := uint64(binary.BigEndian.Uint32(frame))
+	frame = frame[4:]
+	if adLen+uint64(c.NonceSize()+c.Overhead()) > uint64(len(frame)) {
+		return nil, nil, ErrMalformedFrame
+	}
+	// copy the additional data before the decryption may overwrite the frame
+	additionalData = append([]byte(nil), frame[:adLen]...)
+	nonce := frame[adLen : adLen+uint64(c.NonceSize())]
+	ciphertext := frame[adLen+uint64(c.NonceSize()):]
+
+	plaintext, err = c.Open(dst, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, nil, err
+	}
+	return plaintext, additionalData, nil
+}
@@ -0,0 +1,78 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestFramed(t *testing.T) {
+	c := newTestEAX(t)
+	nonce := make([]byte, c.NonceSize())
+	nonce[0] = 1
+	msg := []byte("framed message")
+
+	for _, data := range [][]byte{nil, []byte("d"), []byte("additional data")} {
+		prefix := []byte("prefix")
+		frame := c.SealFramed(prefix, nonce, msg, data)
+		if !bytes.HasPrefix(frame, prefix) {
+			t.Fatal("SealFramed did not append to dst")
+		}
+		if n := len(frame) - len(prefix); n != 4+len(data)+len(nonce)+len(msg)+c.Overhead() {
+			t.Fatalf("SealFramed returned a frame of %d bytes", n)
+		}
+
+		plaintext, ad, err := c.OpenFramed(nil, frame[len(prefix):])
+		if err != nil {
+			t.Fatalf("OpenFramed failed: %s", err)
+		}
+		if !bytes.Equal(plaintext, msg) || !bytes.Equal(ad, data) {
+			t.Fatalf("OpenFramed returned %q and %q - but expected %q and %q", plaintext, ad, msg, data)
+		}
+	}
+
+	// the additional data must survive the in-place decryption
+	data, long := []byte("additional data"), bytes.Repeat([]byte("long framed message"), 4)
+	frame := c.SealFramed(nil, nonce, long, data)
+	plaintext, ad, err := c.OpenFramed(frame[:0], frame)
+	if err != nil {
+		t.Fatalf("In-place OpenFramed failed: %s", err)
+	}
+	if !bytes.Equal(plaintext, long) || !bytes.Equal(ad, data) {
+		t.Fatalf("In-place OpenFramed returned %q and %q - but expected %q and %q", plaintext, ad, long, data)
+	}
+
+	frame = c.SealFramed(nil, nonce, msg, []byte("data"))
+	for i := 0; i < len(frame); i++ {
+		frame[i] ^= 0x80
+		if _, _, err := c.OpenFramed(nil, frame); err == nil {
+			t.Fatalf("OpenFramed accepted a frame modified at byte %d", i)
+		}
+		frame[i] ^= 0x80
+	}
+}
+
+func TestFramedMalformed(t *testing.T) {
+	c := newTestEAX(t)
+	nonce := make([]byte, c.NonceSize())
+	frame := c.SealFramed(nil, nonce, []byte("message"), []byte("data"))
+
+	for _, adLen := range [][]byte{
+		{0, 0, 0, 5},
+		{0, 0, 1, 0},
+		{0xff, 0xff, 0xff, 0xff},
+	} {
+		corrupted := append([]byte{}, frame...)
+		copy(corrupted, adLen)
+		if _, _, err := c.OpenFramed(nil, corrupted); err == nil {
+			t.Fatalf("OpenFramed accepted a corrupted length: %x", adLen)
+		}
+	}
+	for i := 0; i < 4+4+c.NonceSize()+c.Overhead(); i++ {
+		if _, _, err := c.OpenFramed(nil, frame[:i]); err != ErrMalformedFrame {
+			t.Fatalf("OpenFramed returned %v for a frame of %d bytes - but expected: %v", err, i, ErrMalformedFrame)
+		}
+	}
+}
@@ -0,0 +1,27 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+// SealHeaderBody encrypts and authenticates the header and authenticates
+// the body and appends the encrypted header followed by the tag to dst.
+// The body is not encrypted and not appended - it must be sent in clear
+// next to the sealed header. The tag covers the nonce, the header and the
+// body, so modifying any of them is detected by OpenHeaderBody.
+//
+// This maps directly to the structure of EAX: the header is the CTR
+// encrypted message and the body is the OMAC authenticated additional
+// data - so SealHeaderBody is equal to Seal(dst, nonce, header, body).
+// The body is only processed by one OMAC pass, the header by the CTR mode
+// and one OMAC pass. If the nonce size is invalid this function panics.
+func (c *EAX) SealHeaderBody(dst, nonce, header, body []byte) []byte {
+	return c.Seal(dst, nonce, header, body)
+}
+
+// OpenHeaderBody verifies the sealed header created by SealHeaderBody
+// and the body, decrypts the header and appends it to dst. If the sealed
+// header or the body was modified OpenHeaderBody returns an
+// AuthenticationError and nothing is decrypted.
+func (c *EAX) OpenHeaderBody(dst, nonce, sealedHeader, body []byte) ([]byte, error) {
+	return c.Open(dst, nonce, sealedHeader, body)
+}
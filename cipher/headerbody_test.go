@@ -0,0 +1,58 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+
+	"github.com/enceve/crypto"
+)
+
+func TestSealHeaderBody(t *testing.T) {
+	c := newTestEAX(t)
+	nonce := make([]byte, c.NonceSize())
+	header, body := []byte("type=1;len=42"), bytes.Repeat([]byte("authenticated body "), 10)
+
+	sealed := c.SealHeaderBody(nil, nonce, header, body)
+	if len(sealed) != len(header)+c.Overhead() {
+		t.Fatalf("SealHeaderBody returned %d bytes - but expected %d", len(sealed), len(header)+c.Overhead())
+	}
+	if expected := c.Seal(nil, nonce, header, body); !bytes.Equal(sealed, expected) {
+		t.Fatalf("SealHeaderBody returned %x - but expected %x", sealed, expected)
+	}
+	if bytes.Contains(sealed, header) {
+		t.Fatal("SealHeaderBody did not encrypt the header")
+	}
+	plaintext, err := c.OpenHeaderBody(nil, nonce, sealed, body)
+	if err != nil {
+		t.Fatalf("OpenHeaderBody failed: %s", err)
+	}
+	if !bytes.Equal(plaintext, header) {
+		t.Fatalf("OpenHeaderBody returned %q - but expected %q", plaintext, header)
+	}
+
+	for i := range sealed {
+		sealed[i] ^= 1
+		if _, err = c.OpenHeaderBody(nil, nonce, sealed, body); !errors.Is(err, crypto.AuthenticationError{}) {
+			t.Fatalf("Byte %d of the sealed header modified: OpenHeaderBody returned %v", i, err)
+		}
+		sealed[i] ^= 1
+	}
+	for _, i := range []int{0, len(body) / 2, len(body) - 1} {
+		body[i] ^= 1
+		if _, err = c.OpenHeaderBody(nil, nonce, sealed, body); !errors.Is(err, crypto.AuthenticationError{}) {
+			t.Fatalf("Byte %d of the body modified: OpenHeaderBody returned %v", i, err)
+		}
+		body[i] ^= 1
+	}
+	if _, err = c.OpenHeaderBody(nil, nonce, sealed, body[:len(body)-1]); err == nil {
+		t.Fatal("OpenHeaderBody accepted a truncated body")
+	}
+	nonce[0] = 1
+	if _, err = c.OpenHeaderBody(nil, nonce, sealed, body); err == nil {
+		t.Fatal("OpenHeaderBody accepted a different nonce")
+	}
+}
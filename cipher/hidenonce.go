@@ -0,0 +1,97 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+// NewHideNonce returns a cipher.AEAD wrapping the given AEAD, which does
+// not reveal the nonces of the messages. Seal encrypts the nonce with the
+// block cipher and prepends the encrypted nonce to the sealed message. So
+// distinct nonces (even counters) are indistinguishable from random bytes
+// on the wire. Open decrypts the nonce before opening the message.
+// The block cipher should use a key independent from the key of the AEAD.
+// This function returns a non-nil error if the nonce size of the AEAD
+// is greater than the block size of the block cipher.
+func NewHideNonce(aead cipher.AEAD, c cipher.Block) (cipher.AEAD, error) {
+	if aead.NonceSize() > c.BlockSize() {
+		return nil, errors.New("nonce size must not be greater than the BlockSize() of the given cipher")
+	}
+	return &hideNonce{
+		aead:  aead,
+		block: c,
+	}, nil
+}
+
+// The hide-nonce AEAD wrapper
+type hideNonce struct {
+	aead  cipher.AEAD
+	block cipher.Block
+}
+
+func (c *hideNonce) NonceSize() int { return c.aead.NonceSize() }
+
+func (c *hideNonce) Overhead() int { return c.block.BlockSize() + c.aead.Overhead() }
+
+func (c *hideNonce) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if n := len(nonce); n != c.aead.NonceSize() {
+		panic(crypto.NonceSizeMismatchError{Size: n, Expected: c.aead.NonceSize()})
+	}
+
+	// seal first - dst may be plaintext[:0] - and prepend the hidden nonce
+	// afterwards. out has enough capacity, so the wrapped AEAD seals in
+	// place or into a separate buffer, but never into a partial overlap.
+	bs := c.block.BlockSize()
+	ret, out := sliceForAppend(dst, bs+len(plaintext)+c.aead.Overhead())
+	sealed := c.aead.Seal(out[:0], nonce, plaintext, additionalData)
+	copy(out[bs:], sealed)
+
+	hidden := out[:bs]
+	n := copy(hidden, nonce)
+	for i := range hidden[n:] {
+		hidden[n+i] = 0
+	}
+	c.block.Encrypt(hidden, hidden)
+	return ret
+}
+
+// Open recovers the nonce from the ciphertext, so the nonce argument
+// may be nil. If a nonce is given, Open verifies that it is equal to
+// the recovered nonce.
+func (c *hideNonce) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	n, bs := c.aead.NonceSize(), c.block.BlockSize()
+	if len(nonce) != 0 && len(nonce) != n {
+		return nil, crypto.NonceSizeMismatchError{Size: len(nonce), Expected: n}
+	}
+	if len(ciphertext) < bs+c.aead.Overhead() {
+		return nil, crypto.AuthenticationError{Algorithm: "HideNonce"}
+	}
+
+	hidden := make([]byte, bs)
+	c.block.Decrypt(hidden, ciphertext[:bs])
+
+	ok := subtle.ConstantTimeCompare(hidden[n:], make([]byte, bs-n))
+	if len(nonce) != 0 {
+		ok &= subtle.ConstantTimeCompare(hidden[:n], nonce)
+	}
+	if ok != 1 {
+		return nil, crypto.AuthenticationError{Algorithm: "HideNonce"}
+	}
+
+	// move the sealed message to the start of the output - dst may be
+	// ciphertext[:0] - so the wrapped AEAD opens exactly in place.
+	ret, out := sliceForAppend(dst, len(ciphertext)-bs)
+	copy(out, ciphertext[bs:])
+	plaintext, err := c.aead.Open(out[:0], hidden[:n], out, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	// the wrapped AEAD may not have opened in place
+	return ret[:len(dst)+copy(out, plaintext)], nil
+}
@@ -0,0 +1,182 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"math/bits"
+	"testing"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/cryptotest"
+)
+
+func newTestHideNonce(t *testing.T) cipher.AEAD {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create AES-128-GCM instance: %s", err)
+	}
+	nonceKey := make([]byte, 16)
+	nonceKey[0] = 1
+	nonceBlock, err := aes.NewCipher(nonceKey)
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	c, err := NewHideNonce(gcm, nonceBlock)
+	if err != nil {
+		t.Fatalf("Failed to create hide-nonce instance: %s", err)
+	}
+	return c
+}
+
+func TestNewHideNonce(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	eax, err := NewEAX(block, block.BlockSize())
+	if err != nil {
+		t.Fatalf("Failed to create AES-128-EAX instance: %s", err)
+	}
+	if _, err = NewHideNonce(eax, dummyBlock(8)); err == nil {
+		t.Fatal("NewHideNonce accepted a nonce size greater than the block size")
+	}
+}
+
+func TestHideNonce(t *testing.T) {
+	c := newTestHideNonce(t)
+
+	msg, data := []byte("hidden nonce message"), []byte("data")
+	nonce := make([]byte, c.NonceSize())
+
+	ciphertext := c.Seal(nil, nonce, msg, data)
+	if n := len(ciphertext); n != len(msg)+c.Overhead() {
+		t.Fatalf("Seal returned %d bytes - but expected %d", n, len(msg)+c.Overhead())
+	}
+	plaintext, err := c.Open(nil, nil, ciphertext, data)
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Fatalf("Open returned %q - but expected %q", plaintext, msg)
+	}
+	if _, err = c.Open(nil, nonce, ciphertext, data); err != nil {
+		t.Fatalf("Open failed with the correct nonce: %s", err)
+	}
+
+	authErr := crypto.AuthenticationError{Algorithm: "HideNonce"}
+	nonce[0] = 1
+	if _, err = c.Open(nil, nonce, ciphertext, data); err != authErr {
+		t.Fatalf("Open with a wrong nonce returned %v - but expected %v", err, authErr)
+	}
+	ciphertext[0] ^= 1
+	if _, err = c.Open(nil, nil, ciphertext, data); err != authErr {
+		t.Fatalf("Open of a modified hidden nonce returned %v - but expected %v", err, authErr)
+	}
+	if _, err = c.Open(nil, nil, ciphertext[:c.Overhead()-1], data); err != authErr {
+		t.Fatalf("Open of a too short ciphertext returned %v - but expected %v", err, authErr)
+	}
+}
+
+// allocatingAEAD returns the plaintext of Open in a new slice
+// instead of appending it to dst.
+type allocatingAEAD struct{ cipher.AEAD }
+
+func (c allocatingAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	return c.AEAD.Open(nil, nonce, ciphertext, additionalData)
+}
+
+func TestHideNonceAllocatingAEAD(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create AES-128-GCM instance: %s", err)
+	}
+	c, err := NewHideNonce(allocatingAEAD{gcm}, block)
+	if err != nil {
+		t.Fatalf("Failed to create hide-nonce instance: %s", err)
+	}
+
+	msg := []byte("opened by an allocating AEAD")
+	ciphertext := c.Seal(nil, make([]byte, c.NonceSize()), msg, nil)
+	plaintext, err := c.Open([]byte("prefix"), nil, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	if expected := append([]byte("prefix"), msg...); !bytes.Equal(plaintext, expected) {
+		t.Fatalf("Open returned %q - but expected %q", plaintext, expected)
+	}
+}
+
+func TestHideNonceInPlace(t *testing.T) {
+	c := newTestHideNonce(t)
+	nonce, data := make([]byte, c.NonceSize()), []byte("data")
+	for _, size := range []int{0, 1, 15, 16, 17, 64, 100} {
+		msg := make([]byte, size)
+		for i := range msg {
+			msg[i] = byte(i + 1)
+		}
+		expected := c.Seal(nil, nonce, msg, data)
+
+		buf := append(make([]byte, 0, size+c.Overhead()), msg...)
+		ciphertext := c.Seal(buf[:0], nonce, buf, data)
+		if !bytes.Equal(ciphertext, expected) {
+			t.Fatalf("Size %d: in-place Seal returned %x - but expected %x", size, ciphertext, expected)
+		}
+		plaintext, err := c.Open(ciphertext[:0], nil, ciphertext, data)
+		if err != nil {
+			t.Fatalf("Size %d: in-place Open failed: %s", size, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("Size %d: in-place Open returned %x - but expected %x", size, plaintext, msg)
+		}
+	}
+}
+
+func TestHideNonceConformance(t *testing.T) {
+	cryptotest.ConformanceTestAEAD(t, newTestHideNonce(t))
+}
+
+func TestHideNonceRandom(t *testing.T) {
+	c := newTestHideNonce(t)
+
+	// counter nonces must not be visible on the wire
+	nonce := make([]byte, c.NonceSize())
+	var prev []byte
+	for i := 0; i < 64; i++ {
+		nonce[len(nonce)-1] = byte(i)
+		hidden := c.Seal(nil, nonce, nil, nil)[:16]
+		if bytes.HasPrefix(hidden, nonce) {
+			t.Fatalf("Iteration %d: Seal revealed the nonce", i)
+		}
+		if prev != nil {
+			var d int
+			for j := range hidden {
+				d += bits.OnesCount8(hidden[j] ^ prev[j])
+			}
+			if d < 32 || d > 96 {
+				t.Fatalf("Iteration %d: Consecutive hidden nonces differ in %d of 128 bits", i, d)
+			}
+		}
+		prev = hidden
+	}
+}
+
+// A cipher.Block mock with any block size.
+type dummyBlock int
+
+func (c dummyBlock) BlockSize() int { return int(c) }
+
+func (c dummyBlock) Encrypt(dst, src []byte) { copy(dst, src) }
+
+func (c dummyBlock) Decrypt(dst, src []byte) { copy(dst, src) }
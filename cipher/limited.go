@@ -0,0 +1,50 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"errors"
+)
+
+var errCiphertextTooLarge = errors.New("ciphertext exceeds the max. ciphertext size")
+
+// NewLimitedAEAD returns a cipher.AEAD wrapping the given AEAD, which
+// rejects ciphertexts longer than maxCiphertext bytes (including the tag).
+// Open returns an error for those ciphertexts before the wrapped AEAD is
+// called - so no memory is allocated for the plaintext. This protects
+// servers decrypting untrusted input against huge allocations. Seal
+// is not limited. The NonceSize and the Overhead of the wrapped AEAD
+// are preserved.
+// If maxCiphertext is negative this function panics.
+func NewLimitedAEAD(inner cipher.AEAD, maxCiphertext int) cipher.AEAD {
+	if maxCiphertext < 0 {
+		panic("max. ciphertext size must not be negative")
+	}
+	return &limitedAEAD{
+		aead:          inner,
+		maxCiphertext: maxCiphertext,
+	}
+}
+
+// The size limiting AEAD wrapper
+type limitedAEAD struct {
+	aead          cipher.AEAD
+	maxCiphertext int
+}
+
+func (c *limitedAEAD) NonceSize() int { return c.aead.NonceSize() }
+
+func (c *limitedAEAD) Overhead() int { return c.aead.Overhead() }
+
+func (c *limitedAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	return c.aead.Seal(dst, nonce, plaintext, additionalData)
+}
+
+func (c *limitedAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	if len(ciphertext) > c.maxCiphertext {
+		return nil, errCiphertextTooLarge
+	}
+	return c.aead.Open(dst, nonce, ciphertext, additionalData)
+}
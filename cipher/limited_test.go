@@ -0,0 +1,52 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"testing"
+)
+
+// countingAEAD counts the Open calls of the wrapped AEAD.
+type countingAEAD struct {
+	cipher.AEAD
+	opened int
+}
+
+func (c *countingAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	c.opened++
+	return c.AEAD.Open(dst, nonce, ciphertext, additionalData)
+}
+
+func TestLimitedAEAD(t *testing.T) {
+	inner := &countingAEAD{AEAD: newTestEAX(t)}
+	c := NewLimitedAEAD(inner, 64)
+	nonce, data := make([]byte, c.NonceSize()), []byte("data")
+
+	msg := make([]byte, 64-c.Overhead())
+	ciphertext := c.Seal(nil, nonce, msg, data)
+	plaintext, err := c.Open(nil, nonce, ciphertext, data)
+	if err != nil {
+		t.Fatalf("Open failed for a ciphertext of the max. size: %s", err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Fatalf("Open returned %x - but expected %x", plaintext, msg)
+	}
+
+	ciphertext = c.Seal(nil, nonce, append(msg, 0), data)
+	if _, err = c.Open(nil, nonce, ciphertext, data); err != errCiphertextTooLarge {
+		t.Fatalf("Open returned %v - but expected %v", err, errCiphertextTooLarge)
+	}
+	if inner.opened != 1 {
+		t.Fatalf("The wrapped AEAD was called %d times - but expected 1", inner.opened)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected error: max. ciphertext size is negative")
+		}
+	}()
+	NewLimitedAEAD(inner, -1)
+}
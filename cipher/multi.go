@@ -0,0 +1,49 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"encoding/binary"
+
+	"github.com/enceve/crypto"
+)
+
+// SealMulti works like Seal but authenticates a vector of additional data
+// strings instead of one. Every string is prefixed with its length (64 bit
+// big-endian) before the OMAC of the header is computed, so the boundaries
+// of the strings are unambiguous - e.g. ("a", "bc") and ("ab", "c") are
+// different headers.
+// Notice that SealMulti with one additional data string is NOT equal to Seal,
+// because of the length prefix.
+func (c *EAX) SealMulti(dst, nonce, plaintext []byte, additionalData ...[]byte) []byte {
+	if n := len(nonce); n != c.nonceSize {
+		panic(crypto.NonceSizeMismatchError{Size: n, Expected: c.nonceSize})
+	}
+	return c.seal(dst, nil, plaintext, c.authNonce(nonce), c.authHeaderMulti(additionalData))
+}
+
+// OpenMulti works like Open but authenticates a vector of additional data
+// strings instead of one. See SealMulti for details.
+func (c *EAX) OpenMulti(dst, nonce, ciphertext []byte, additionalData ...[]byte) ([]byte, error) {
+	if n := len(nonce); n != c.nonceSize {
+		return nil, crypto.NonceSizeMismatchError{Size: n, Expected: c.nonceSize}
+	}
+	return c.open(dst, nil, ciphertext, c.authNonce(nonce), c.authHeaderMulti(additionalData))
+}
+
+// authHeaderMulti computes the OMAC of the length-prefixed
+// additional data strings.
+func (c *EAX) authHeaderMulti(additionalData [][]byte) []byte {
+	var length [8]byte
+
+	c.writeTag(hTag)
+	for _, data := range additionalData {
+		binary.BigEndian.PutUint64(length[:], uint64(len(data)))
+		c.mac.Write(length[:])
+		c.mac.Write(data)
+	}
+	authData := c.mac.Sum(c.authData[:0])
+	c.mac.Reset()
+	return authData
+}
@@ -0,0 +1,57 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestSealMulti(t *testing.T) {
+	c := newTestEAX(t)
+	nonce, msg := make([]byte, c.NonceSize()), []byte("multi")
+
+	headers := [][][]byte{
+		{},
+		{[]byte("")},
+		{[]byte(""), []byte("")},
+		{[]byte("abc")},
+		{[]byte("a"), []byte("bc")},
+		{[]byte("ab"), []byte("c")},
+		{[]byte("abc"), []byte("")},
+		{[]byte(""), []byte("abc")},
+	}
+	ciphertexts := make([][]byte, len(headers))
+	for i, data := range headers {
+		ciphertexts[i] = c.SealMulti(nil, nonce, msg, data...)
+
+		plaintext, err := c.OpenMulti(nil, nonce, ciphertexts[i], data...)
+		if err != nil {
+			t.Fatalf("Header %d: OpenMulti failed: %s", i, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("Header %d: OpenMulti returned %q - but expected %q", i, plaintext, msg)
+		}
+	}
+
+	// the tags of different headers must differ and no
+	// ciphertext must be accepted with another header
+	for i := range headers {
+		for j := range headers {
+			if i == j {
+				continue
+			}
+			if bytes.Equal(ciphertexts[i], ciphertexts[j]) {
+				t.Fatalf("Header %d and %d produce the same tag", i, j)
+			}
+			if _, err := c.OpenMulti(nil, nonce, ciphertexts[i], headers[j]...); err == nil {
+				t.Fatalf("OpenMulti accepted ciphertext of header %d with header %d", i, j)
+			}
+		}
+	}
+
+	if _, err := c.OpenMulti(nil, nonce[1:], ciphertexts[0]); err == nil {
+		t.Fatal("OpenMulti accepted an invalid nonce size")
+	}
+}
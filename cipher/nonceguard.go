@@ -0,0 +1,146 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	cryptorand "crypto/rand"
+	"io"
+	"math"
+	"sync"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/siphash"
+)
+
+// ErrNonceReused is the panic value of the Seal function of a
+// NonceGuardAEAD if the nonce was (probably) used before. It is
+// crypto.ErrNonceReused, so crypto.SafeSeal returns it as error.
+var ErrNonceReused = crypto.ErrNonceReused
+
+// The false-positive rate of the nonce guard after expectedN
+// nonces and the number of hash functions for this rate.
+const (
+	nonceGuardFalsePositives = 0.001
+	nonceGuardHashes         = 10
+)
+
+// NewNonceGuardAEAD returns a cipher.AEAD wrapping the given AEAD, which
+// detects the reuse of a nonce on Seal. The nonces are not stored - they
+// are added to a counting Bloom filter sized for expectedN nonces, so the
+// memory is fixed at construction (about 15 bytes per expected nonce) and
+// independent of the nonce size. If the nonce of a Seal call is found in
+// the filter, Seal panics with ErrNonceReused before anything is encrypted -
+// use crypto.SafeSeal to get the error instead.
+//
+// The filter is probabilistic: every reused nonce is detected, but a fresh
+// nonce is also rejected (a false positive). After expectedN nonces the
+// false-positive rate is about 0.1%. It grows with every further nonce -
+// to about 6% after 2 * expectedN nonces - so expectedN should be an upper
+// bound of the nonces sealed with one key. A rejected nonce is not added to
+// the filter, so the caller can retry Seal with another (fresh) nonce. The
+// nonce of a Seal call which panics for another reason - e.g. an invalid
+// nonce size - is not added either. The nonces are hashed with SipHash
+// using a random key, so the false positives cannot be predicted by an
+// attacker choosing nonces.
+// Open is not changed.
+// A NonceGuardAEAD is safe for concurrent use if the wrapped AEAD is.
+// If expectedN is not positive or the random SipHash key cannot be
+// generated this function panics.
+func NewNonceGuardAEAD(inner cipher.AEAD, expectedN int) cipher.AEAD {
+	return NewNonceGuardAEADRand(inner, expectedN, nil)
+}
+
+// NewNonceGuardAEADRand returns a cipher.AEAD like NewNonceGuardAEAD,
+// but the SipHash key is read from rand. If rand is nil, crypto/rand
+// will be used. A known SipHash key lets an attacker predict the false
+// positives - so a deterministic rand must only be used for testing.
+func NewNonceGuardAEADRand(inner cipher.AEAD, expectedN int, rand io.Reader) cipher.AEAD {
+	if expectedN < 1 {
+		panic("expected number of nonces must be positive")
+	}
+	if rand == nil {
+		rand = cryptorand.Reader
+	}
+	c := &nonceGuardAEAD{aead: inner}
+	if _, err := io.ReadFull(rand, c.key[:]); err != nil {
+		panic(err)
+	}
+	m := math.Ceil(-float64(expectedN) * math.Log(nonceGuardFalsePositives) / (math.Ln2 * math.Ln2))
+	c.counters = make([]uint8, int(math.Min(m, math.MaxUint32)))
+	return c
+}
+
+// The nonce-reuse detecting AEAD wrapper
+type nonceGuardAEAD struct {
+	aead cipher.AEAD
+	key  [16]byte // the SipHash key
+
+	mu       sync.Mutex
+	counters []uint8 // the counting Bloom filter
+}
+
+func (c *nonceGuardAEAD) NonceSize() int { return c.aead.NonceSize() }
+
+func (c *nonceGuardAEAD) Overhead() int { return c.aead.Overhead() }
+
+func (c *nonceGuardAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if len(nonce) != c.aead.NonceSize() {
+		return c.aead.Seal(dst, nonce, plaintext, additionalData) // panics
+	}
+	sum := siphash.Sum64(nonce, &(c.key))
+
+	c.mu.Lock()
+	if c.contains(sum) {
+		c.mu.Unlock()
+		panic(ErrNonceReused)
+	}
+	c.update(sum, true)
+	c.mu.Unlock()
+
+	// remove the nonce again if the wrapped AEAD panics
+	sealed := false
+	defer func() {
+		if !sealed {
+			c.mu.Lock()
+			c.update(sum, false)
+			c.mu.Unlock()
+		}
+	}()
+	ret := c.aead.Seal(dst, nonce, plaintext, additionalData)
+	sealed = true
+	return ret
+}
+
+func (c *nonceGuardAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	return c.aead.Open(dst, nonce, ciphertext, additionalData)
+}
+
+// contains returns true if all counters of the hash are non-zero.
+func (c *nonceGuardAEAD) contains(sum uint64) bool {
+	h1, h2 := uint32(sum), uint32(sum>>32)|1
+	for i := uint32(0); i < nonceGuardHashes; i++ {
+		if c.counters[(h1+i*h2)%uint32(len(c.counters))] == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// update increments (add = true) or decrements the counters of the
+// hash. Saturated counters are never changed again - otherwise a
+// decrement could remove other nonces from the filter.
+func (c *nonceGuardAEAD) update(sum uint64, add bool) {
+	h1, h2 := uint32(sum), uint32(sum>>32)|1
+	for i := uint32(0); i < nonceGuardHashes; i++ {
+		p := &c.counters[(h1+i*h2)%uint32(len(c.counters))]
+		switch {
+		case *p == math.MaxUint8:
+		case add:
+			*p++
+		default:
+			*p--
+		}
+	}
+}
@@ -0,0 +1,101 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/enceve/crypto"
+)
+
+func TestNonceGuardAEAD(t *testing.T) {
+	const n = 1000
+	c := NewNonceGuardAEAD(newTestEAX(t), n)
+	nonce, msg := make([]byte, c.NonceSize()), []byte("guarded")
+
+	var accepted []int
+	for i := 0; i < n; i++ {
+		binary.BigEndian.PutUint64(nonce, uint64(i))
+		ciphertext, err := crypto.SafeSeal(c, nil, nonce, msg, nil)
+		if err == ErrNonceReused {
+			continue // a false positive - the caller retries with another nonce
+		}
+		if err != nil {
+			t.Fatalf("Nonce %d: Seal failed: %s", i, err)
+		}
+		if _, err = c.Open(nil, nonce, ciphertext, nil); err != nil {
+			t.Fatalf("Nonce %d: Open failed: %s", i, err)
+		}
+		if _, err = crypto.SafeSeal(c, nil, nonce, msg, nil); err != ErrNonceReused {
+			t.Fatalf("Nonce %d: Seal returned %v for a reused nonce - but expected %v", i, err, ErrNonceReused)
+		}
+		accepted = append(accepted, i)
+	}
+	// the expected number of false positives is below 0.1% of n
+	if rejected := n - len(accepted); rejected > 10 {
+		t.Fatalf("Seal rejected %d of %d distinct nonces", rejected, n)
+	}
+
+	// every accepted nonce is detected - also the first ones
+	for _, i := range accepted {
+		binary.BigEndian.PutUint64(nonce, uint64(i))
+		if _, err := crypto.SafeSeal(c, nil, nonce, msg, nil); err != ErrNonceReused {
+			t.Fatalf("Nonce %d: Seal returned %v for a reused nonce - but expected %v", i, err, ErrNonceReused)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected error: expected number of nonces is not positive")
+		}
+	}()
+	NewNonceGuardAEAD(newTestEAX(t), 0)
+}
+
+func TestNonceGuardAEADInvalidNonce(t *testing.T) {
+	c := NewNonceGuardAEAD(newTestEAX(t), 10).(*nonceGuardAEAD)
+	nonce := make([]byte, c.NonceSize())
+	for _, n := range [][]byte{nonce[:c.NonceSize()-1], append(nonce, 0)} {
+		if _, err := crypto.SafeSeal(c, nil, n, nil, nil); err == nil || err == ErrNonceReused {
+			t.Fatalf("Nonce size %d: Seal returned %v - but expected a nonce size error", len(n), err)
+		}
+	}
+	for i, v := range c.counters {
+		if v != 0 {
+			t.Fatalf("Counter %d: An invalid nonce was added to the filter", i)
+		}
+	}
+	if _, err := crypto.SafeSeal(c, nil, nonce, nil, nil); err != nil {
+		t.Fatalf("Seal failed: %s", err)
+	}
+}
+
+func TestNonceGuardAEADRand(t *testing.T) {
+	key := make([]byte, 16)
+	for i := range key {
+		key[i] = byte(i + 1)
+	}
+	c := NewNonceGuardAEADRand(newTestEAX(t), 10, bytes.NewReader(key)).(*nonceGuardAEAD)
+	if !bytes.Equal(c.key[:], key) {
+		t.Fatalf("SipHash key is %x - but expected %x", c.key, key)
+	}
+
+	// the same key produces the same filter
+	other := NewNonceGuardAEADRand(newTestEAX(t), 10, bytes.NewReader(key)).(*nonceGuardAEAD)
+	nonce, msg := make([]byte, c.NonceSize()), []byte("guarded")
+	c.Seal(nil, nonce, msg, nil)
+	other.Seal(nil, nonce, msg, nil)
+	if !bytes.Equal(c.counters, other.counters) {
+		t.Fatal("Two nonce guards with the same SipHash key set different counters")
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewNonceGuardAEADRand succeeded although the random source is too short")
+		}
+	}()
+	NewNonceGuardAEADRand(newTestEAX(t), 10, bytes.NewReader(key[:15]))
+}
@@ -0,0 +1,104 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/subtle"
+	"io"
+
+	"github.com/enceve/crypto"
+)
+
+// The size of the chunks read by OpenStream.
+const openStreamBufferSize = 32 * 1024
+
+// OpenStream works like Open but reads the ciphertext (followed by the tag)
+// from in and writes the plaintext to out, so the ciphertext is never held
+// in memory. The ciphertext is read twice: the first pass computes and
+// verifies the tag, the second pass seeks back and decrypts the ciphertext.
+// The ciphertext starts at the current offset of in and ends at the end of in.
+// Nothing is written to out if the authentication fails.
+// Notice that the input must not change between both passes - otherwise
+// unauthenticated plaintext is written to out. So in must not be shared
+// with other writers (e.g. a file opened by another process).
+// OpenStream returns ErrCiphertextTooShort if in holds less bytes than the
+// tag, an AuthenticationError if the authentication fails and the errors
+// of in and out unchanged.
+func (c *EAX) OpenStream(out io.Writer, in io.ReadSeeker, nonce, additionalData []byte) error {
+	if n := len(nonce); n != c.nonceSize {
+		return crypto.NonceSizeMismatchError{Size: n, Expected: c.nonceSize}
+	}
+
+	start, err := in.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return err
+	}
+	end, err := in.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	if end-start < int64(c.size) {
+		return ErrCiphertextTooShort
+	}
+	length := end - start - int64(c.size)
+	if _, err = in.Seek(start, io.SeekStart); err != nil {
+		return err
+	}
+
+	authNonce, authData := c.authenticate(nonce, additionalData)
+
+	// first pass: authenticate the ciphertext
+	if c.size > 0 {
+		c.writeTag(cTag)
+		if _, err = io.CopyN(c.mac, in, length); err != nil {
+			c.mac.Reset()
+			return unexpectedEOF(err)
+		}
+		tag := c.mac.Sum(c.authCt[:0])
+		c.mac.Reset()
+		for i := range tag {
+			tag[i] ^= authData[i] ^ authNonce[i]
+		}
+
+		hash := make([]byte, c.size)
+		if _, err = io.ReadFull(in, hash); err != nil {
+			return unexpectedEOF(err)
+		}
+		if subtle.ConstantTimeCompare(tag[:c.size], hash) != 1 {
+			return crypto.AuthenticationError{Algorithm: "EAX"}
+		}
+		if _, err = in.Seek(start, io.SeekStart); err != nil {
+			return err
+		}
+	}
+
+	// second pass: decrypt the ciphertext
+	bs := int64(c.blockCipher.BlockSize())
+	buf := make([]byte, openStreamBufferSize-openStreamBufferSize%bs)
+	c.setCtr(nil, authNonce)
+	for length > 0 {
+		chunk := buf
+		if length < int64(len(chunk)) {
+			chunk = chunk[:length]
+		}
+		if _, err = io.ReadFull(in, chunk); err != nil {
+			return unexpectedEOF(err)
+		}
+		c.ctrCrypt(chunk, chunk)
+		if _, err = out.Write(chunk); err != nil {
+			return err
+		}
+		length -= int64(len(chunk))
+	}
+	return nil
+}
+
+// unexpectedEOF returns io.ErrUnexpectedEOF if err is io.EOF
+// and err otherwise.
+func unexpectedEOF(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
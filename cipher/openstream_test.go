@@ -0,0 +1,72 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"io"
+	"testing"
+)
+
+func TestOpenStream(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	nonce, data := make([]byte, block.BlockSize()), []byte("data")
+	nonce[0] = 1
+
+	for _, tagsize := range []int{16, 8} {
+		c, err := NewEAX(block, tagsize)
+		if err != nil {
+			t.Fatalf("Failed to create EAX instance: %s", err)
+		}
+		for _, size := range []int{0, 1, 15, 16, 17, openStreamBufferSize - 1, openStreamBufferSize, 3*openStreamBufferSize + 5} {
+			msg := make([]byte, size)
+			for i := range msg {
+				msg[i] = byte(i)
+			}
+			ciphertext := c.Seal(nil, nonce, msg, data)
+			expected, err := c.Open(nil, nonce, ciphertext, data)
+			if err != nil {
+				t.Fatalf("Tag size %d - %d bytes: Open failed: %s", tagsize, size, err)
+			}
+
+			// the ciphertext starts after a prefix at the current offset
+			in := bytes.NewReader(append([]byte("prefix"), ciphertext...))
+			in.Seek(6, io.SeekStart)
+			var out bytes.Buffer
+			if err = c.OpenStream(&out, in, nonce, data); err != nil {
+				t.Fatalf("Tag size %d - %d bytes: OpenStream failed: %s", tagsize, size, err)
+			}
+			if !bytes.Equal(out.Bytes(), expected) {
+				t.Fatalf("Tag size %d - %d bytes: OpenStream and Open produce different plaintexts", tagsize, size)
+			}
+
+			out.Reset()
+			if err = c.OpenStream(&out, bytes.NewReader(ciphertext), nonce, []byte("other")); err == nil {
+				t.Fatalf("Tag size %d - %d bytes: OpenStream accepted wrong additional data", tagsize, size)
+			}
+			for _, i := range []int{0, len(ciphertext) / 2, len(ciphertext) - 1} {
+				modified := append([]byte{}, ciphertext...)
+				modified[i] ^= 1
+				if err = c.OpenStream(&out, bytes.NewReader(modified), nonce, data); err == nil {
+					t.Fatalf("Tag size %d - %d bytes: OpenStream accepted a ciphertext modified at byte %d", tagsize, size, i)
+				}
+			}
+			if err = c.OpenStream(&out, bytes.NewReader(ciphertext[:len(ciphertext)-1]), nonce, data); err == nil {
+				t.Fatalf("Tag size %d - %d bytes: OpenStream accepted a truncated ciphertext", tagsize, size)
+			}
+			if out.Len() != 0 {
+				t.Fatalf("Tag size %d - %d bytes: OpenStream wrote unauthenticated plaintext", tagsize, size)
+			}
+		}
+	}
+
+	c := newTestEAX(t)
+	if err := c.OpenStream(new(bytes.Buffer), bytes.NewReader(nil), nonce[1:], data); err == nil {
+		t.Fatal("OpenStream accepted an invalid nonce")
+	}
+}
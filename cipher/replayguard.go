@@ -0,0 +1,176 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"sync"
+)
+
+var (
+	// ErrReplayed is returned by the Open function of a ReplayGuardAEAD
+	// if the message counter was already accepted.
+	ErrReplayed = errors.New("message was replayed")
+
+	// ErrOutsideWindow is returned by the Open function of a ReplayGuardAEAD
+	// if the message counter is too old to be checked for a replay.
+	ErrOutsideWindow = errors.New("message counter is outside of the replay window")
+
+	// ErrCounterExhausted is returned by the Seal and Open functions
+	// of a ReplayGuardAEAD if all 2^64 message counters are used.
+	ErrCounterExhausted = errors.New("message counter is exhausted")
+)
+
+// ReplayGuardAEAD wraps a cipher.AEAD and embeds a monotonic message
+// counter into every ciphertext. Seal prepends the counter (64 bit
+// big-endian) to the plaintext, so the counter is encrypted and
+// authenticated by the wrapped AEAD. Open rejects every message whose
+// counter was already accepted (ErrReplayed) and every message whose
+// counter is window or more counters behind the highest accepted
+// counter (ErrOutsideWindow) - messages within the window may arrive
+// out of order, like the anti-replay window of IPsec (RFC 4303).
+// The counter is only checked after the message was authenticated,
+// so a forged message never changes the window.
+//
+// Sealing and opening use separate counters - one ReplayGuardAEAD per
+// direction and key. The nonces are still chosen by the caller and must
+// be unique as required by the wrapped AEAD. The replay state is not
+// persisted - a restarted receiver accepts old messages again.
+// A ReplayGuardAEAD is safe for concurrent use.
+type ReplayGuardAEAD struct {
+	aead   cipher.AEAD
+	window uint64
+
+	mu     sync.Mutex
+	seal   uint64   // the next counter of Seal
+	sealed bool     // true if the last counter was used by Seal
+	next   uint64   // the highest accepted counter + 1 (0 if none was)
+	opened bool     // true if the last counter was accepted by Open
+	seen   []uint64 // bit i is set if the counter next-1-i was accepted
+}
+
+// NewReplayGuardAEAD returns a new ReplayGuardAEAD wrapping the given
+// cipher.AEAD, which accepts messages with counters up to window-1
+// counters behind the highest accepted counter. If window is not
+// positive this function panics.
+func NewReplayGuardAEAD(inner cipher.AEAD, window int) *ReplayGuardAEAD {
+	if window < 1 {
+		panic("replay window must be positive")
+	}
+	return &ReplayGuardAEAD{
+		aead:   inner,
+		window: uint64(window),
+		seen:   make([]uint64, (window+63)/64),
+	}
+}
+
+// NonceSize returns the nonce size of the wrapped cipher.AEAD.
+func (r *ReplayGuardAEAD) NonceSize() int { return r.aead.NonceSize() }
+
+// Overhead returns the difference between the lengths of ciphertext
+// and plaintext (the counter and the overhead of the wrapped AEAD).
+func (r *ReplayGuardAEAD) Overhead() int { return 8 + r.aead.Overhead() }
+
+// Seal prepends the next message counter to the plaintext, encrypts and
+// authenticates both and the additional data and appends the result to dst.
+// To reuse the storage of the plaintext use plaintext[:0] as dst.
+// If all counters are used Seal returns ErrCounterExhausted.
+func (r *ReplayGuardAEAD) Seal(dst, nonce, plaintext, additionalData []byte) ([]byte, error) {
+	r.mu.Lock()
+	if r.sealed {
+		r.mu.Unlock()
+		return nil, ErrCounterExhausted
+	}
+	counter := r.seal
+	if r.seal++; r.seal == 0 {
+		r.sealed = true
+	}
+	r.mu.Unlock()
+
+	ret, out := sliceForAppend(dst, r.Overhead()+len(plaintext))
+	out = out[:8+len(plaintext)]
+	copy(out[8:], plaintext)
+	binary.BigEndian.PutUint64(out, counter)
+	return r.aead.Seal(ret[:len(dst)], nonce, out, additionalData), nil
+}
+
+// Open decrypts and authenticates the ciphertext and the additional data,
+// checks the embedded message counter and appends the plaintext (without
+// the counter) to dst. Open returns the error of the wrapped AEAD if the
+// ciphertext is not authentic and ErrReplayed or ErrOutsideWindow if the
+// counter is rejected. After the last counter (2^64 - 1) was accepted
+// Open returns ErrCounterExhausted.
+func (r *ReplayGuardAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	ret, err := r.aead.Open(dst, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	out := ret[len(dst):]
+	if len(out) < 8 {
+		return nil, errors.New("ciphertext contains no message counter")
+	}
+	counter := binary.BigEndian.Uint64(out)
+
+	r.mu.Lock()
+	err = r.accept(counter)
+	r.mu.Unlock()
+	if err != nil {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, err
+	}
+	n := copy(out, out[8:])
+	return ret[:len(dst)+n], nil
+}
+
+// accept checks the counter against the replay window and marks
+// it as accepted.
+func (r *ReplayGuardAEAD) accept(counter uint64) error {
+	if r.opened {
+		return ErrCounterExhausted
+	}
+	if counter >= r.next {
+		r.shift(counter - r.next + 1)
+		r.next = counter + 1
+		if r.next == 0 {
+			r.opened = true
+		}
+		r.seen[0] |= 1
+		return nil
+	}
+	age := r.next - 1 - counter
+	if age >= r.window {
+		return ErrOutsideWindow
+	}
+	word, bit := age/64, uint64(1)<<(age%64)
+	if r.seen[word]&bit != 0 {
+		return ErrReplayed
+	}
+	r.seen[word] |= bit
+	return nil
+}
+
+// shift moves the replay window forward by n counters.
+func (r *ReplayGuardAEAD) shift(n uint64) {
+	if n >= r.window {
+		for i := range r.seen {
+			r.seen[i] = 0
+		}
+		return
+	}
+	words, bits := int(n/64), n%64
+	for i := len(r.seen) - 1; i >= 0; i-- {
+		var v uint64
+		if j := i - words; j >= 0 {
+			v = r.seen[j] << bits
+			if j > 0 && bits != 0 {
+				v |= r.seen[j-1] >> (64 - bits)
+			}
+		}
+		r.seen[i] = v
+	}
+}
@@ -0,0 +1,144 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestReplayGuardAEAD(t *testing.T) {
+	const window = 100
+	sender, receiver := NewReplayGuardAEAD(newTestEAX(t), window), NewReplayGuardAEAD(newTestEAX(t), window)
+	if sender.Overhead() != 8+newTestEAX(t).Overhead() {
+		t.Fatalf("Overhead returned %d - but expected %d", sender.Overhead(), 8+newTestEAX(t).Overhead())
+	}
+	nonce, data := make([]byte, sender.NonceSize()), []byte("data")
+
+	msgs := make([][]byte, 300)
+	for i := range msgs {
+		nonce[0], nonce[1] = byte(i), byte(i>>8)
+		msg := []byte{byte(i), 'm', 's', 'g'}
+		ciphertext, err := sender.Seal(nil, nonce, msg, data)
+		if err != nil {
+			t.Fatalf("Message %d: Seal failed: %s", i, err)
+		}
+		if len(ciphertext) != len(msg)+sender.Overhead() {
+			t.Fatalf("Message %d: Seal returned %d bytes - but expected %d", i, len(ciphertext), len(msg)+sender.Overhead())
+		}
+		msgs[i] = ciphertext
+	}
+	open := func(i int) ([]byte, error) {
+		nonce[0], nonce[1] = byte(i), byte(i>>8)
+		return receiver.Open(nil, nonce, msgs[i], data)
+	}
+
+	// in order
+	for i := 0; i < 10; i++ {
+		plaintext, err := open(i)
+		if err != nil {
+			t.Fatalf("Message %d: Open failed: %s", i, err)
+		}
+		if expected := []byte{byte(i), 'm', 's', 'g'}; !bytes.Equal(plaintext, expected) {
+			t.Fatalf("Message %d: Open returned %q - but expected %q", i, plaintext, expected)
+		}
+	}
+	for _, i := range []int{0, 5, 9} {
+		if _, err := open(i); err != ErrReplayed {
+			t.Fatalf("Message %d: Open returned %v for a replay - but expected ErrReplayed", i, err)
+		}
+	}
+
+	// out of order within the window
+	for _, i := range []int{150, 120, 51, 149, 140} {
+		if _, err := open(i); err != nil {
+			t.Fatalf("Message %d: Open failed: %s", i, err)
+		}
+	}
+	for _, i := range []int{150, 120, 51} {
+		if _, err := open(i); err != ErrReplayed {
+			t.Fatalf("Message %d: Open returned %v for a replay - but expected ErrReplayed", i, err)
+		}
+	}
+	for _, i := range []int{50, 10, 0} {
+		if _, err := open(i); err != ErrOutsideWindow {
+			t.Fatalf("Message %d: Open returned %v - but expected ErrOutsideWindow", i, err)
+		}
+	}
+
+	// a jump larger than the window forgets all counters
+	if _, err := open(299); err != nil {
+		t.Fatalf("Message 299: Open failed: %s", err)
+	}
+	if _, err := open(200); err != nil {
+		t.Fatalf("Message 200: Open failed: %s", err)
+	}
+	if _, err := open(199); err != ErrOutsideWindow {
+		t.Fatalf("Message 199: Open returned %v - but expected ErrOutsideWindow", err)
+	}
+
+	// a forged message does not change the window
+	msgs[250][0] ^= 1
+	if _, err := open(250); err == nil {
+		t.Fatal("Open accepted a modified ciphertext")
+	}
+	msgs[250][0] ^= 1
+	if _, err := open(250); err != nil {
+		t.Fatalf("Message 250: Open failed after a forgery: %s", err)
+	}
+}
+
+func TestReplayGuardAEADInPlace(t *testing.T) {
+	sender, receiver := NewReplayGuardAEAD(newTestEAX(t), 1), NewReplayGuardAEAD(newTestEAX(t), 1)
+	nonce, msg := make([]byte, sender.NonceSize()), []byte("in place")
+	expected, err := NewReplayGuardAEAD(newTestEAX(t), 1).Seal(nil, nonce, msg, nil)
+	if err != nil {
+		t.Fatalf("Seal failed: %s", err)
+	}
+
+	buf := append(make([]byte, 0, len(msg)+sender.Overhead()), msg...)
+	ciphertext, err := sender.Seal(buf[:0], nonce, buf, nil)
+	if err != nil {
+		t.Fatalf("Seal failed: %s", err)
+	}
+	if !bytes.Equal(ciphertext, expected) {
+		t.Fatalf("In-place Seal returned %x - but expected %x", ciphertext, expected)
+	}
+	plaintext, err := receiver.Open(ciphertext[:0], nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Fatalf("In-place Open returned %q - but expected %q", plaintext, msg)
+	}
+
+	// with a window of 1 only newer counters are accepted
+	ciphertext, _ = sender.Seal(nil, nonce, msg, nil)
+	older, _ := sender.Seal(nil, nonce, msg, nil)
+	newer, _ := sender.Seal(nil, nonce, msg, nil)
+	if _, err = receiver.Open(nil, nonce, newer, nil); err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	if _, err = receiver.Open(nil, nonce, older, nil); err != ErrOutsideWindow {
+		t.Fatalf("Open returned %v - but expected ErrOutsideWindow", err)
+	}
+	if _, err = receiver.Open(nil, nonce, ciphertext, nil); err != ErrOutsideWindow {
+		t.Fatalf("Open returned %v - but expected ErrOutsideWindow", err)
+	}
+
+	sender.seal = 1<<64 - 1
+	if _, err = sender.Seal(nil, nonce, msg, nil); err != nil {
+		t.Fatalf("Seal of the last counter failed: %s", err)
+	}
+	if _, err = sender.Seal(nil, nonce, msg, nil); err != ErrCounterExhausted {
+		t.Fatalf("Seal returned %v - but expected ErrCounterExhausted", err)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("NewReplayGuardAEAD accepted a window of 0")
+		}
+	}()
+	NewReplayGuardAEAD(newTestEAX(t), 0)
+}
@@ -0,0 +1,90 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+func init() {
+	crypto.RegisterSelfTest("AES-EAX", eaxSelfTest)
+	crypto.RegisterSelfTest("AES-SIV", sivSelfTest)
+}
+
+// eaxSelfTest checks EAX-AES-128 with the key, nonce and header of a
+// test vector of the EAX paper (http://web.cs.ucdavis.edu/~rogaway/papers/eax.pdf)
+// and a 50 byte message, so the CTR and CMac of more than three blocks
+// are checked. The ciphertext was computed with the CMac and CTR of OpenSSL.
+func eaxSelfTest() error {
+	key, _ := hex.DecodeString("91945D3F4DCBEE0BF45EF52255F095A4")
+	nonce, _ := hex.DecodeString("BECAF043B0A23D843194BA972C66DEBD")
+	data, _ := hex.DecodeString("FA3BFD4806EB53FA")
+	msg, _ := hex.DecodeString("000102030405060708090a0b0c0d0e0f" +
+		"101112131415161718191a1b1c1d1e1f" +
+		"202122232425262728292a2b2c2d2e2f" +
+		"3031")
+	ciphertext, _ := hex.DecodeString("ee2745f767475ae1b3461f103e361ddc" +
+		"d2a865b4901910db4f1e7389028f8713" +
+		"b32461584d87cebc94a70dae28ba70a0" +
+		"fe7c" +
+		"801ced244e465ec3398dd6d98fe37a4b")
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	c, err := NewEAX(block, block.BlockSize())
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(c.Seal(nil, nonce, msg, data), ciphertext) {
+		return errors.New("unexpected ciphertext")
+	}
+	plaintext, err := c.Open(nil, nonce, ciphertext, data)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(plaintext, msg) {
+		return errors.New("unexpected plaintext")
+	}
+	ciphertext[0] ^= 1
+	if _, err = c.Open(nil, nonce, ciphertext, data); err == nil {
+		return errors.New("modified ciphertext accepted")
+	}
+	return nil
+}
+
+// sivSelfTest checks AES-CMAC-SIV against the
+// test vector from RFC 5297 (appendix A.1).
+func sivSelfTest() error {
+	key, _ := hex.DecodeString("fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff")
+	data, _ := hex.DecodeString("101112131415161718191a1b1c1d1e1f2021222324252627")
+	msg, _ := hex.DecodeString("112233445566778899aabbccddee")
+	ciphertext, _ := hex.DecodeString("85632d07c6e8f37f950acd320a2ecc93" + "40c02b9690c4dc04daef7f6afe5c")
+
+	c, err := NewSIV(key)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(c.Seal(nil, msg, data), ciphertext) {
+		return errors.New("unexpected ciphertext")
+	}
+	plaintext, err := c.Open(nil, ciphertext, data)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(plaintext, msg) {
+		return errors.New("unexpected plaintext")
+	}
+	ciphertext[len(ciphertext)-1] ^= 1
+	if _, err = c.Open(nil, ciphertext, data); err == nil {
+		return errors.New("modified ciphertext accepted")
+	}
+	return nil
+}
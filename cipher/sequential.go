@@ -0,0 +1,153 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"errors"
+	"sync"
+)
+
+// ErrNonceExhausted is returned if all nonces of a
+// nonce counter were used.
+var ErrNonceExhausted = errors.New("nonce counter exhausted")
+
+// NonceSequence generates unique nonces from a monotonic counter.
+// The counter is encoded big-endian into the last (up to 8) bytes
+// of the nonce - all other nonce bytes are zero. After the last
+// nonce was generated the sequence is exhausted.
+// A NonceSequence is safe for concurrent use.
+type NonceSequence struct {
+	mu   sync.Mutex
+	size int
+	max  uint64
+	ctr  uint64
+	done bool
+}
+
+// NewNonceSequence returns a new NonceSequence generating nonces
+// of size bytes - starting with the all-zero nonce.
+// If the size is not positive this function panics.
+func NewNonceSequence(size int) *NonceSequence {
+	if size < 1 {
+		panic("nonce size must be positive")
+	}
+	return newNonceSequence(size)
+}
+
+func newNonceSequence(size int) *NonceSequence {
+	max := ^uint64(0)
+	if size < 8 {
+		max = (uint64(1) << uint(8*size)) - 1
+	}
+	return &NonceSequence{
+		size: size,
+		max:  max,
+	}
+}
+
+// NonceSize returns the size of the nonces.
+func (s *NonceSequence) NonceSize() int { return s.size }
+
+// Next returns the next nonce of the sequence.
+// If the sequence is exhausted Next returns ErrNonceExhausted.
+func (s *NonceSequence) Next() ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nonce, err := s.current()
+	if err != nil {
+		return nil, err
+	}
+	s.step()
+	return nonce, nil
+}
+
+// current returns the nonce for the current value of the counter.
+func (s *NonceSequence) current() ([]byte, error) {
+	if s.done {
+		return nil, ErrNonceExhausted
+	}
+	nonce := make([]byte, s.size)
+	ctr := s.ctr
+	for i := len(nonce) - 1; i >= 0 && i >= len(nonce)-8; i-- {
+		nonce[i] = byte(ctr)
+		ctr >>= 8
+	}
+	return nonce, nil
+}
+
+// step increments the counter and marks the sequence as
+// exhausted if the last nonce was used.
+func (s *NonceSequence) step() {
+	if s.ctr == s.max {
+		s.done = true
+		return
+	}
+	s.ctr++
+}
+
+// SequentialAEAD wraps a cipher.AEAD and derives the nonce
+// of every message from an internal monotonic counter.
+// The counter is encoded big-endian into the last (up to 8)
+// bytes of the nonce - all other nonce bytes are zero.
+// Sealing and opening use separate counters, so messages must
+// be opened in the same order as they were sealed.
+// A SequentialAEAD is safe for concurrent use.
+type SequentialAEAD struct {
+	aead cipher.AEAD
+
+	mu         sync.Mutex
+	seal, open *NonceSequence
+}
+
+// Sequential returns a new SequentialAEAD wrapping the given
+// cipher.AEAD. The key of the AEAD must not be used for anything
+// else - otherwise the uniqueness of the nonces is not guaranteed.
+func Sequential(aead cipher.AEAD) *SequentialAEAD {
+	return &SequentialAEAD{
+		aead: aead,
+		seal: newNonceSequence(aead.NonceSize()),
+		open: newNonceSequence(aead.NonceSize()),
+	}
+}
+
+// Overhead returns the overhead of the wrapped cipher.AEAD.
+func (s *SequentialAEAD) Overhead() int { return s.aead.Overhead() }
+
+// Seal encrypts and authenticates the plaintext and the additional data
+// using the next nonce of the seal counter. The dst argument is passed
+// to the Seal function of the wrapped cipher.AEAD.
+// If the counter is exhausted Seal returns ErrNonceExhausted.
+func (s *SequentialAEAD) Seal(dst, plaintext, additionalData []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nonce, err := s.seal.Next()
+	if err != nil {
+		return nil, err
+	}
+	return s.aead.Seal(dst, nonce, plaintext, additionalData), nil
+}
+
+// Open decrypts and authenticates the ciphertext and the additional data
+// using the next nonce of the open counter. The dst argument is passed
+// to the Open function of the wrapped cipher.AEAD.
+// The open counter is only incremented if the ciphertext is authentic.
+// If the counter is exhausted Open returns ErrNonceExhausted.
+func (s *SequentialAEAD) Open(dst, ciphertext, additionalData []byte) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	nonce, err := s.open.current()
+	if err != nil {
+		return nil, err
+	}
+	plaintext, err := s.aead.Open(dst, nonce, ciphertext, additionalData)
+	if err != nil {
+		return nil, err
+	}
+	s.open.step()
+	return plaintext, nil
+}
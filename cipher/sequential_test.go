@@ -0,0 +1,142 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"sync"
+	"testing"
+)
+
+func newTestEAX(t testing.TB) *EAX {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	c, err := NewEAX(block, block.BlockSize())
+	if err != nil {
+		t.Fatalf("Failed to create AES-128-EAX instance: %s", err)
+	}
+	return c
+}
+
+func TestSequential(t *testing.T) {
+	sender, receiver := Sequential(newTestEAX(t)), Sequential(newTestEAX(t))
+
+	msg, data := []byte("sequential message"), []byte("data")
+	for i := 0; i < 16; i++ {
+		ciphertext, err := sender.Seal(nil, msg, data)
+		if err != nil {
+			t.Fatalf("Iteration %d: Seal failed: %s", i, err)
+		}
+		plaintext, err := receiver.Open(nil, ciphertext, data)
+		if err != nil {
+			t.Fatalf("Iteration %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("Iteration %d: Open returned %q - but expected %q", i, plaintext, msg)
+		}
+	}
+
+	ct0, _ := sender.Seal(nil, msg, data)
+	ct1, _ := sender.Seal(nil, msg, data)
+	if bytes.Equal(ct0, ct1) {
+		t.Fatal("Seal used the same nonce twice")
+	}
+	if _, err := receiver.Open(nil, ct1, data); err == nil {
+		t.Fatal("Open accepted a message out of order")
+	}
+	if _, err := receiver.Open(nil, ct0, data); err != nil {
+		t.Fatalf("Open failed after rejecting a message: %s", err)
+	}
+}
+
+func TestSequentialRollover(t *testing.T) {
+	c := newTestEAX(t)
+	s := Sequential(c)
+	s.seal.ctr = s.seal.max - 1
+
+	if _, err := s.Seal(nil, nil, nil); err != nil {
+		t.Fatalf("Seal failed for nonce 2^64-2: %s", err)
+	}
+	ciphertext, err := s.Seal(nil, nil, nil)
+	if err != nil {
+		t.Fatalf("Seal failed for nonce 2^64-1: %s", err)
+	}
+	if _, err = s.Seal(nil, nil, nil); err != ErrNonceExhausted {
+		t.Fatalf("Seal returned %v - but expected: %v", err, ErrNonceExhausted)
+	}
+
+	nonce := make([]byte, c.NonceSize())
+	for i := len(nonce) - 8; i < len(nonce); i++ {
+		nonce[i] = 0xff
+	}
+	if _, err = c.Open(nil, nonce, ciphertext, nil); err != nil {
+		t.Fatalf("Last nonce is not 2^64-1: %s", err)
+	}
+}
+
+func TestSequentialConcurrent(t *testing.T) {
+	c := newTestEAX(t)
+	s := Sequential(c)
+
+	const n = 64
+	ciphertexts := make([][]byte, n)
+
+	var wg sync.WaitGroup
+	for i := range ciphertexts {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ct, err := s.Seal(nil, []byte{byte(i)}, nil)
+			if err != nil {
+				t.Errorf("Seal failed: %s", err)
+			}
+			ciphertexts[i] = ct
+		}(i)
+	}
+	wg.Wait()
+
+	// every nonce 0, ..., n-1 must be used exactly once
+	used := make(map[int]bool)
+	nonce := make([]byte, c.NonceSize())
+	for ctr := 0; ctr < n; ctr++ {
+		nonce[len(nonce)-1] = byte(ctr)
+		for i, ct := range ciphertexts {
+			if _, err := c.Open(nil, nonce, ct, nil); err == nil {
+				if used[i] {
+					t.Fatalf("Ciphertext %d was sealed with more than one nonce", i)
+				}
+				used[i] = true
+			}
+		}
+	}
+	if len(used) != n {
+		t.Fatalf("Only %d of %d ciphertexts use a sequential nonce", len(used), n)
+	}
+}
+
+func TestNonceSequence(t *testing.T) {
+	s := NewNonceSequence(2)
+	for i := 0; i < 1<<16; i++ {
+		nonce, err := s.Next()
+		if err != nil {
+			t.Fatalf("Nonce %d: Next failed: %s", i, err)
+		}
+		if n := int(nonce[0])<<8 | int(nonce[1]); n != i {
+			t.Fatalf("Next returned nonce %d - but expected %d", n, i)
+		}
+	}
+	if _, err := s.Next(); err != ErrNonceExhausted {
+		t.Fatalf("Next returned %v - but expected: %v", err, ErrNonceExhausted)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected error: nonce size is not positive")
+		}
+	}()
+	NewNonceSequence(0)
+}
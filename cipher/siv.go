@@ -0,0 +1,216 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/cmac"
+	"github.com/enceve/crypto/internal/alias"
+	"github.com/enceve/crypto/internal/gf128"
+)
+
+// The size of the synthetic IV of the SIV mode in bytes.
+const SIVSize = 16
+
+// The max. number of additional data strings for SIV (RFC 5297).
+const maxSIVAdditionalData = 126
+
+var errSIVAdditionalData = errors.New("too many additional data strings for SIV")
+
+// SIV implements the AES-CMAC-SIV deterministic AEAD mode of operation
+// specified in RFC 5297 (the S2V construction uses CMac). SIV is resistant
+// against nonce misuse: encrypting the same plaintext with the same additional
+// data twice only reveals that the messages are equal. A nonce can be passed
+// as the last additional data string to get a nonce-based AEAD.
+// A SIV instance is not safe for concurrent use.
+type SIV struct {
+	mac *cmac.Hash
+	ctr cipher.Block
+
+	// scratch buffers of S2V
+	d, t []byte
+}
+
+// NewSIV returns a *SIV using AES-CMAC-SIV. The key must be 32, 48 or
+// 64 bytes long: the first half is the CMac key and the second half is
+// the CTR key - selecting AES-128, AES-192 or AES-256.
+// This function returns a non-nil error if the key size is invalid.
+// See NewSIVWithCiphers for SIV with other block ciphers.
+func NewSIV(key []byte) (*SIV, error) {
+	if k := len(key); k != 32 && k != 48 && k != 64 {
+		return nil, crypto.KeySizeError(k)
+	}
+	macCipher, err := aes.NewCipher(key[:len(key)/2])
+	if err != nil {
+		return nil, err
+	}
+	ctrCipher, err := aes.NewCipher(key[len(key)/2:])
+	if err != nil {
+		return nil, err
+	}
+	return NewSIVWithCiphers(macCipher, ctrCipher)
+}
+
+// NewSIVWithCiphers returns a *SIV using the two block ciphers - keyed with
+// independent keys - for the S2V construction and the CTR encryption.
+// This function returns a non-nil error if the block size of a cipher is
+// not 16 bytes.
+func NewSIVWithCiphers(macCipher, ctrCipher cipher.Block) (*SIV, error) {
+	if macCipher.BlockSize() != SIVSize || ctrCipher.BlockSize() != SIVSize {
+		return nil, errors.New("SIV requires block ciphers with a block size of 16 bytes")
+	}
+	mac, err := cmac.New(macCipher)
+	if err != nil {
+		return nil, err
+	}
+	return &SIV{
+		mac: mac,
+		ctr: ctrCipher,
+		d:   make([]byte, SIVSize),
+		t:   make([]byte, SIVSize),
+	}, nil
+}
+
+// Overhead returns the size of the synthetic IV.
+func (c *SIV) Overhead() int { return SIVSize }
+
+// Seal encrypts and authenticates the plaintext and the additional data
+// strings and appends the synthetic IV followed by the ciphertext to dst.
+// To reuse the storage of the plaintext use plaintext[:0] as dst. Otherwise
+// dst and plaintext must not overlap. If more than 126 additional data
+// strings are given this function panics.
+func (c *SIV) Seal(dst, plaintext []byte, additionalData ...[]byte) []byte {
+	ret, out := sliceForAppend(dst, SIVSize+len(plaintext))
+	c.SealDetached(out[SIVSize:SIVSize], out[:SIVSize], plaintext, additionalData...)
+	return ret
+}
+
+// Open decrypts and authenticates a ciphertext created by Seal and
+// appends the plaintext to dst. To reuse the storage of the ciphertext use
+// ciphertext[:0] as dst. Otherwise dst and ciphertext must not overlap.
+// Open returns a non-nil error if the ciphertext is not authentic or more
+// than 126 additional data strings are given.
+func (c *SIV) Open(dst, ciphertext []byte, additionalData ...[]byte) ([]byte, error) {
+	if len(ciphertext) < SIVSize {
+		return nil, crypto.AuthenticationError{Algorithm: "SIV"}
+	}
+	return c.OpenDetached(dst, ciphertext[:SIVSize], ciphertext[SIVSize:], additionalData...)
+}
+
+// SealDetached works like Seal but writes the synthetic IV to siv instead of
+// prepending it to the ciphertext - like the wire format of miscreant. The
+// ciphertext is appended to dst. To reuse the storage of the plaintext use
+// plaintext[:0] as dst. Otherwise dst and plaintext must not overlap.
+// If len(siv) < SIVSize or more than 126 additional data strings are given
+// this function panics.
+func (c *SIV) SealDetached(dst, siv, plaintext []byte, additionalData ...[]byte) []byte {
+	if len(siv) < SIVSize {
+		panic("siv must be at least SIVSize bytes long")
+	}
+	if len(additionalData) > maxSIVAdditionalData {
+		panic(errSIVAdditionalData)
+	}
+	var v [SIVSize]byte
+	copy(v[:], c.s2v(plaintext, additionalData))
+
+	// siv may overlap the plaintext (e.g. Seal with plaintext[:0] as dst),
+	// so it is written after the plaintext was encrypted.
+	ret, out := sliceForAppend(dst, len(plaintext))
+	c.ctrCryptOverlap(out, plaintext, v[:])
+	copy(siv, v[:])
+	return ret
+}
+
+// OpenDetached works like Open but takes the synthetic IV and the ciphertext
+// separately. See SealDetached for details. If the ciphertext is not authentic
+// the decrypted plaintext is cleared and OpenDetached returns an
+// AuthenticationError.
+func (c *SIV) OpenDetached(dst, siv, ciphertext []byte, additionalData ...[]byte) ([]byte, error) {
+	if len(siv) != SIVSize {
+		return nil, crypto.AuthenticationError{Algorithm: "SIV"}
+	}
+	if len(additionalData) > maxSIVAdditionalData {
+		return nil, errSIVAdditionalData
+	}
+	// siv may overlap the output (e.g. Open with ciphertext[:0] as dst)
+	var v [SIVSize]byte
+	copy(v[:], siv)
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	c.ctrCryptOverlap(out, ciphertext, v[:])
+
+	if subtle.ConstantTimeCompare(c.s2v(out, additionalData), v[:]) != 1 {
+		for i := range out {
+			out[i] = 0
+		}
+		return nil, crypto.AuthenticationError{Algorithm: "SIV"}
+	}
+	return ret, nil
+}
+
+// s2v computes the S2V construction of RFC 5297 with the additional data
+// strings followed by the plaintext. The returned slice is only valid
+// until the next call.
+func (c *SIV) s2v(plaintext []byte, additionalData [][]byte) []byte {
+	d, t := c.d, c.t
+	for i := range d {
+		d[i] = 0
+	}
+	c.mac.Write(d)
+	c.mac.Sum(d[:0])
+	c.mac.Reset()
+
+	for _, ad := range additionalData {
+		c.mac.Write(ad)
+		c.mac.Sum(t[:0])
+		c.mac.Reset()
+		gf128.Double(d)
+		crypto.XOR(d, d, t)
+	}
+
+	if len(plaintext) >= SIVSize {
+		n := len(plaintext) - SIVSize
+		c.mac.Write(plaintext[:n])
+		crypto.XOR(d, d, plaintext[n:])
+		c.mac.Write(d)
+	} else {
+		gf128.Double(d)
+		for i := range t {
+			t[i] = 0
+		}
+		copy(t, plaintext)
+		t[len(plaintext)] = 0x80
+		crypto.XOR(d, d, t)
+		c.mac.Write(d)
+	}
+	v := c.mac.Sum(d[:0])
+	c.mac.Reset()
+	return v
+}
+
+// ctrCryptOverlap works like ctrCrypt but dst and src may overlap.
+func (c *SIV) ctrCryptOverlap(dst, src, siv []byte) {
+	if alias.InexactOverlap(dst, src) {
+		tmp := make([]byte, len(src))
+		c.ctrCrypt(tmp, src, siv)
+		copy(dst, tmp)
+		return
+	}
+	c.ctrCrypt(dst, src, siv)
+}
+
+// ctrCrypt encrypts src with the CTR mode starting at the synthetic
+// IV with the bits 31 and 63 (from the right) cleared.
+func (c *SIV) ctrCrypt(dst, src, siv []byte) {
+	var q [SIVSize]byte
+	copy(q[:], siv)
+	q[8] &= 0x7f
+	q[12] &= 0x7f
+	cipher.NewCTR(c.ctr, q[:]).XORKeyStream(dst, src)
+}
@@ -0,0 +1,164 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/enceve/crypto/internal/testvec"
+)
+
+// Test vectors from RFC 5297 Appendix A (also part of the AES-SIV
+// test vectors of miscreant). The ciphertext is SIV || C.
+var sivVectors = []struct {
+	key            string
+	additionalData []string
+	plaintext      string
+	ciphertext     string
+}{
+	{ // A.1 deterministic authenticated encryption
+		key:            "fffefdfcfbfaf9f8f7f6f5f4f3f2f1f0f0f1f2f3f4f5f6f7f8f9fafbfcfdfeff",
+		additionalData: []string{"101112131415161718191a1b1c1d1e1f2021222324252627"},
+		plaintext:      "112233445566778899aabbccddee",
+		ciphertext:     "85632d07c6e8f37f950acd320a2ecc9340c02b9690c4dc04daef7f6afe5c",
+	},
+	{ // A.2 nonce-based authenticated encryption
+		key: "7f7e7d7c7b7a79787776757473727170404142434445464748494a4b4c4d4e4f",
+		additionalData: []string{
+			"00112233445566778899aabbccddeeffdeaddadadeaddadaffeeddccbbaa99887766554433221100",
+			"102030405060708090a0",
+			"09f911029d74e35bd84156c5635688c0",
+		},
+		plaintext: "7468697320697320736f6d6520706c61696e7465787420746f20656e6372797074207573696e67205349562d414553",
+		ciphertext: "7bdb6e3b432667eb06f4d14bff2fbd0fcb900f2fddbe404326601965c889bf17" +
+			"dba77ceb094fa663b7a3f748ba8af829ea64ad544a272e9c485b62a3fd5c0d",
+	},
+}
+
+func TestSIVVectors(t *testing.T) {
+	for i, v := range sivVectors {
+		c, err := NewSIV(testvec.MustDecodeHex(v.key))
+		if err != nil {
+			t.Fatalf("Test vector %d: Failed to create SIV instance: %s", i, err)
+		}
+		additionalData := make([][]byte, len(v.additionalData))
+		for j := range additionalData {
+			additionalData[j] = testvec.MustDecodeHex(v.additionalData[j])
+		}
+		plaintext := testvec.MustDecodeHex(v.plaintext)
+
+		ciphertext := c.Seal(nil, plaintext, additionalData...)
+		if !testvec.EqualHex(ciphertext, v.ciphertext) {
+			t.Fatalf("Test vector %d: Seal returned %x - but expected %s", i, ciphertext, v.ciphertext)
+		}
+		decrypted, err := c.Open(nil, ciphertext, additionalData...)
+		if err != nil {
+			t.Fatalf("Test vector %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("Test vector %d: Open returned %x - but expected %x", i, decrypted, plaintext)
+		}
+
+		siv := make([]byte, SIVSize)
+		detached := c.SealDetached(nil, siv, plaintext, additionalData...)
+		if !bytes.Equal(siv, ciphertext[:SIVSize]) || !bytes.Equal(detached, ciphertext[SIVSize:]) {
+			t.Fatalf("Test vector %d: SealDetached returned %x, %x", i, siv, detached)
+		}
+		decrypted, err = c.OpenDetached(nil, siv, detached, additionalData...)
+		if err != nil {
+			t.Fatalf("Test vector %d: OpenDetached failed: %s", i, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("Test vector %d: OpenDetached returned %x - but expected %x", i, decrypted, plaintext)
+		}
+	}
+}
+
+func TestSIVInPlace(t *testing.T) {
+	c, err := NewSIV(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to create SIV instance: %s", err)
+	}
+	for _, size := range []int{0, 1, 15, 16, 17, 100} {
+		msg := make([]byte, size)
+		for i := range msg {
+			msg[i] = byte(i)
+		}
+		expected := c.Seal(nil, msg, []byte("data"))
+
+		buf := append(make([]byte, 0, size+SIVSize), msg...)
+		ciphertext := c.Seal(buf[:0], buf, []byte("data"))
+		if !bytes.Equal(ciphertext, expected) {
+			t.Fatalf("Size %d: In-place Seal returned %x - but expected %x", size, ciphertext, expected)
+		}
+		plaintext, err := c.Open(ciphertext[:0], ciphertext, []byte("data"))
+		if err != nil {
+			t.Fatalf("Size %d: In-place Open failed: %s", size, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("Size %d: In-place Open returned %x - but expected %x", size, plaintext, msg)
+		}
+
+		siv, body := expected[:SIVSize], append([]byte{}, expected[SIVSize:]...)
+		plaintext, err = c.OpenDetached(body[:0], siv, body, []byte("data"))
+		if err != nil {
+			t.Fatalf("Size %d: In-place OpenDetached failed: %s", size, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("Size %d: In-place OpenDetached returned %x - but expected %x", size, plaintext, msg)
+		}
+	}
+}
+
+func TestSIV(t *testing.T) {
+	c, err := NewSIV(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("Failed to create SIV instance: %s", err)
+	}
+	for _, size := range []int{0, 1, 15, 16, 17, 100} {
+		msg := make([]byte, size)
+		for i := range msg {
+			msg[i] = byte(i)
+		}
+		siv := make([]byte, SIVSize)
+		ciphertext := c.SealDetached(nil, siv, msg, []byte("a"), []byte("b"))
+		if _, err = c.OpenDetached(nil, siv, ciphertext, []byte("a"), []byte("b")); err != nil {
+			t.Fatalf("Size %d: OpenDetached failed: %s", size, err)
+		}
+		if _, err = c.OpenDetached(nil, siv, ciphertext, []byte("ab")); err == nil {
+			t.Fatalf("Size %d: OpenDetached accepted other additional data", size)
+		}
+		siv[0] ^= 1
+		out := make([]byte, 0, size)
+		if _, err = c.OpenDetached(out, siv, ciphertext, []byte("a"), []byte("b")); err == nil {
+			t.Fatalf("Size %d: OpenDetached accepted a modified SIV", size)
+		}
+		for _, v := range out[:size] {
+			if v != 0 {
+				t.Fatalf("Size %d: OpenDetached did not clear the plaintext", size)
+			}
+		}
+	}
+
+	if _, err = c.Open(nil, make([]byte, SIVSize-1)); err == nil {
+		t.Fatal("Open accepted a truncated ciphertext")
+	}
+	if _, err = c.Open(nil, make([]byte, SIVSize), make([][]byte, 127)...); err == nil {
+		t.Fatal("Open accepted 127 additional data strings")
+	}
+	if _, err = NewSIV(make([]byte, 16)); err == nil {
+		t.Fatal("NewSIV accepted a 16 byte key")
+	}
+	mustFail := func(msg string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Expected error: %s", msg)
+			}
+		}()
+		f()
+	}
+	mustFail("siv is too short", func() { c.SealDetached(nil, make([]byte, SIVSize-1), nil) })
+	mustFail("127 additional data strings", func() { c.Seal(nil, nil, make([][]byte, 127)...) })
+}
@@ -0,0 +1,85 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"errors"
+)
+
+// STREAM implements the nonce-based online authenticated encryption
+// scheme STREAM described in https://eprint.iacr.org/2015/189.pdf.
+// STREAM encrypts a sequence of records using one AEAD and derives
+// the nonce of every record from a base nonce, the index of
+// the record and a flag indicating the last record:
+//
+//	nonce = base nonce || index (32 bit big-endian) || last flag (1 byte)
+//
+// Reordering, dropping or appending records cannot remain undetected.
+// Especially the truncation of the sequence is detected, because the
+// last record is sealed with a different nonce than all other records.
+// The base nonce must be unique for one key for all time.
+//
+// The nonce layout is the one of miscreant's STREAM (an 8 byte base nonce
+// for a 13 byte AEAD nonce). The records are only equal to miscreant's
+// AES-SIV-STREAM records if the AEAD is AES-SIV passing the additional
+// data and then the nonce as additional data strings to S2V - miscreant's
+// AES-PMAC-SIV is not implemented by this package. The AES-SIV-STREAM
+// records are tested against vectors computed with the AES-SIV of OpenSSL.
+type STREAM struct {
+	aead  cipher.AEAD
+	nonce []byte
+}
+
+// NewSTREAM returns a new STREAM using the given AEAD and base nonce.
+// The base nonce must be 5 bytes shorter than the nonce of the AEAD.
+// This function returns a non-nil error if the size of the base
+// nonce is invalid.
+func NewSTREAM(aead cipher.AEAD, baseNonce []byte) (*STREAM, error) {
+	n := aead.NonceSize() - 5
+	if n < 0 {
+		return nil, errors.New("nonce size of the AEAD must be at least 5 bytes")
+	}
+	if len(baseNonce) != n {
+		return nil, errors.New("base nonce must be 5 bytes shorter than the AEAD nonce")
+	}
+	s := &STREAM{
+		aead:  aead,
+		nonce: make([]byte, aead.NonceSize()),
+	}
+	copy(s.nonce, baseNonce)
+	return s, nil
+}
+
+// Overhead returns the overhead of every record.
+func (s *STREAM) Overhead() int { return s.aead.Overhead() }
+
+// Seal encrypts and authenticates the plaintext of the record with the
+// given index and appends the result to dst. The last argument must be
+// true if and only if the record is the last record of the sequence.
+func (s *STREAM) Seal(index uint32, last bool, dst, plaintext, additionalData []byte) []byte {
+	return s.aead.Seal(dst, s.recordNonce(index, last), plaintext, additionalData)
+}
+
+// Open decrypts and authenticates the ciphertext of the record with the
+// given index and appends the plaintext to dst. The last argument must be
+// true if and only if the record is expected to be the last record of the
+// sequence - otherwise a truncated sequence is not detected.
+func (s *STREAM) Open(index uint32, last bool, dst, ciphertext, additionalData []byte) ([]byte, error) {
+	return s.aead.Open(dst, s.recordNonce(index, last), ciphertext, additionalData)
+}
+
+// recordNonce returns the nonce of the record with the given index.
+func (s *STREAM) recordNonce(index uint32, last bool) []byte {
+	nonce := make([]byte, len(s.nonce))
+	n := copy(nonce, s.nonce[:len(s.nonce)-5])
+	nonce[n] = byte(index >> 24)
+	nonce[n+1] = byte(index >> 16)
+	nonce[n+2] = byte(index >> 8)
+	nonce[n+3] = byte(index)
+	if last {
+		nonce[n+4] = 1
+	}
+	return nonce
+}
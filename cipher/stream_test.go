@@ -0,0 +1,197 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/hex"
+	"testing"
+
+	"github.com/enceve/crypto/internal/testvec"
+)
+
+func newTestGCM(t *testing.T) cipher.AEAD {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create AES-128-GCM instance: %s", err)
+	}
+	return gcm
+}
+
+func TestNewSTREAM(t *testing.T) {
+	gcm := newTestGCM(t)
+	if _, err := NewSTREAM(gcm, make([]byte, gcm.NonceSize()-5)); err != nil {
+		t.Fatalf("NewSTREAM rejected a valid base nonce: %s", err)
+	}
+	if _, err := NewSTREAM(gcm, make([]byte, gcm.NonceSize())); err == nil {
+		t.Fatal("NewSTREAM accepted an invalid base nonce")
+	}
+}
+
+func TestSTREAMNonce(t *testing.T) {
+	gcm := newTestGCM(t)
+	base := testvec.MustDecodeHex("00010203040506")
+	s, err := NewSTREAM(gcm, base)
+	if err != nil {
+		t.Fatalf("Failed to create STREAM instance: %s", err)
+	}
+
+	var nonces = []struct {
+		index uint32
+		last  bool
+		nonce string
+	}{
+		{index: 0, last: false, nonce: "00010203040506" + "00000000" + "00"},
+		{index: 1, last: false, nonce: "00010203040506" + "00000001" + "00"},
+		{index: 0x01020304, last: true, nonce: "00010203040506" + "01020304" + "01"},
+	}
+	for i, v := range nonces {
+		if n := hex.EncodeToString(s.recordNonce(v.index, v.last)); n != v.nonce {
+			t.Fatalf("Test %d: Found nonce %s - but expected %s", i, n, v.nonce)
+		}
+	}
+}
+
+// sivAEAD is the nonce-based AEAD of miscreant's AES-SIV-STREAM:
+// S2V takes the additional data and then the nonce.
+type sivAEAD struct{ siv *SIV }
+
+func (c sivAEAD) NonceSize() int { return 13 }
+func (c sivAEAD) Overhead() int  { return SIVSize }
+
+func (c sivAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	return c.siv.Seal(dst, plaintext, additionalData, nonce)
+}
+
+func (c sivAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	return c.siv.Open(dst, ciphertext, additionalData, nonce)
+}
+
+// These are not the miscreant AES-SIV-STREAM test vectors. They were
+// computed with the AES-SIV of OpenSSL (the additional data and then the
+// nonce as S2V strings) using the key 000102...1f, the base nonce
+// 1011121314151617 and the additional data "ad".
+var sivSTREAMVectors = []struct {
+	plaintext, ciphertext string
+}{
+	{
+		plaintext:  "000102030405060708090a0b0c0d0e0f",
+		ciphertext: "dbaef31725cf9437e57e47fdf9018329e1b8d25e511af8feafb00d130a979fe6",
+	},
+	{
+		plaintext: "000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f2021222324",
+		ciphertext: "97aa40cb73659ea8d7dd550465cdb785d96856f8f269dfeebc6b92fa57a4f935" +
+			"999c25f2625cb33b083beca9633cb615559b027ff7",
+	},
+	{
+		plaintext:  "6c617374207265636f7264",
+		ciphertext: "62155c76e0878de9f4107f0793ba167c37882982617a6ed67dd8e0",
+	},
+}
+
+func TestSTREAMWithSIVVectors(t *testing.T) {
+	siv, err := NewSIV(testvec.MustDecodeHex("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f"))
+	if err != nil {
+		t.Fatalf("Failed to create SIV instance: %s", err)
+	}
+	s, err := NewSTREAM(sivAEAD{siv}, testvec.MustDecodeHex("1011121314151617"))
+	if err != nil {
+		t.Fatalf("Failed to create STREAM instance: %s", err)
+	}
+	for i, v := range sivSTREAMVectors {
+		last := i == len(sivSTREAMVectors)-1
+		plaintext := testvec.MustDecodeHex(v.plaintext)
+		ciphertext := s.Seal(uint32(i), last, nil, plaintext, []byte("ad"))
+		if !testvec.EqualHex(ciphertext, v.ciphertext) {
+			t.Fatalf("Record %d: Seal returned %x - but expected %s", i, ciphertext, v.ciphertext)
+		}
+		decrypted, err := s.Open(uint32(i), last, nil, ciphertext, []byte("ad"))
+		if err != nil {
+			t.Fatalf("Record %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("Record %d: Open returned %x - but expected %x", i, decrypted, plaintext)
+		}
+		if _, err = s.Open(uint32(i), !last, nil, ciphertext, []byte("ad")); err == nil {
+			t.Fatalf("Record %d: Open accepted a wrong last flag", i)
+		}
+	}
+}
+
+func TestSTREAMWithSIV(t *testing.T) {
+	key := testvec.MustDecodeHex("000102030405060708090a0b0c0d0e0f101112131415161718191a1b1c1d1e1f")
+	base := testvec.MustDecodeHex("1011121314151617")
+	siv, err := NewSIV(key)
+	if err != nil {
+		t.Fatalf("Failed to create SIV instance: %s", err)
+	}
+	s, err := NewSTREAM(sivAEAD{siv}, base)
+	if err != nil {
+		t.Fatalf("Failed to create STREAM instance: %s", err)
+	}
+
+	records := [][]byte{[]byte("first"), []byte("second"), {}}
+	for i, r := range records {
+		last := i == len(records)-1
+		nonce := append(append([]byte{}, base...), 0, 0, 0, byte(i), 0)
+		if last {
+			nonce[len(nonce)-1] = 1
+		}
+		expected := siv.Seal(nil, r, []byte("ad"), nonce)
+		if ciphertext := s.Seal(uint32(i), last, nil, r, []byte("ad")); !bytes.Equal(ciphertext, expected) {
+			t.Fatalf("Record %d: Seal returned %x - but expected %x", i, ciphertext, expected)
+		}
+		plaintext, err := s.Open(uint32(i), last, nil, expected, []byte("ad"))
+		if err != nil {
+			t.Fatalf("Record %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(plaintext, r) {
+			t.Fatalf("Record %d: Open returned %q - but expected %q", i, plaintext, r)
+		}
+	}
+}
+
+func TestSTREAM(t *testing.T) {
+	gcm := newTestGCM(t)
+	sender, err := NewSTREAM(gcm, make([]byte, gcm.NonceSize()-5))
+	if err != nil {
+		t.Fatalf("Failed to create STREAM instance: %s", err)
+	}
+	receiver, _ := NewSTREAM(gcm, make([]byte, gcm.NonceSize()-5))
+
+	records := [][]byte{[]byte("first"), []byte("second"), []byte("third")}
+	var ciphertexts [][]byte
+	for i, r := range records {
+		ciphertexts = append(ciphertexts, sender.Seal(uint32(i), i == len(records)-1, nil, r, nil))
+	}
+
+	for i, c := range ciphertexts {
+		plaintext, err := receiver.Open(uint32(i), i == len(ciphertexts)-1, nil, c, nil)
+		if err != nil {
+			t.Fatalf("Record %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(plaintext, records[i]) {
+			t.Fatalf("Record %d: Open returned %q - but expected %q", i, plaintext, records[i])
+		}
+	}
+
+	// truncation: the second record is not the last one
+	if _, err = receiver.Open(1, true, nil, ciphertexts[1], nil); err == nil {
+		t.Fatal("Open accepted a truncated sequence")
+	}
+	// reordering
+	if _, err = receiver.Open(0, false, nil, ciphertexts[1], nil); err == nil {
+		t.Fatal("Open accepted a reordered sequence")
+	}
+	// appending after the last record
+	if _, err = receiver.Open(2, false, nil, ciphertexts[2], nil); err == nil {
+		t.Fatal("Open accepted a record appended after the last one")
+	}
+}
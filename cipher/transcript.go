@@ -0,0 +1,109 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"crypto/cipher"
+	"hash"
+	"sync"
+)
+
+// TranscriptAEAD wraps a cipher.AEAD and binds all records of a session
+// into a chain. Every record is authenticated together with a running
+// transcript hash of the tags of all previous records:
+//
+//	t(0)   = H()
+//	t(i+1) = H(t(i) || tag(i))
+//	additional data(i) = t(i) || additional data
+//
+// where tag(i) are the last Overhead() bytes of the i-th ciphertext.
+// So a record is only authentic if all previous records were opened in
+// the same order as they were sealed - reordered, dropped or injected
+// records are detected. Sealing and opening use separate transcripts,
+// so one TranscriptAEAD can be used for both directions of a session.
+// The nonces must still be unique for the key of the wrapped AEAD.
+// A TranscriptAEAD is safe for concurrent use.
+type TranscriptAEAD struct {
+	aead cipher.AEAD
+
+	mu         sync.Mutex
+	seal, open transcript
+}
+
+// The state of a transcript hash
+type transcript struct {
+	hash   hash.Hash
+	digest []byte
+	ad     []byte
+}
+
+// NewTranscriptAEAD returns a new TranscriptAEAD wrapping the given
+// cipher.AEAD. The hashNew function creates the hash function of the
+// transcript - e.g. sha256.New. If the AEAD has no authentication tag
+// (Overhead() == 0) or hashNew is nil this function panics.
+func NewTranscriptAEAD(inner cipher.AEAD, hashNew func() hash.Hash) *TranscriptAEAD {
+	if inner.Overhead() == 0 {
+		panic("the AEAD must have an authentication tag")
+	}
+	if hashNew == nil {
+		panic("the hashNew function must not be nil")
+	}
+	t := &TranscriptAEAD{aead: inner}
+	t.seal.init(hashNew())
+	t.open.init(hashNew())
+	return t
+}
+
+// NonceSize returns the nonce size of the wrapped cipher.AEAD.
+func (t *TranscriptAEAD) NonceSize() int { return t.aead.NonceSize() }
+
+// Overhead returns the overhead of the wrapped cipher.AEAD.
+func (t *TranscriptAEAD) Overhead() int { return t.aead.Overhead() }
+
+// Seal encrypts and authenticates the plaintext, the additional data and
+// the seal transcript and appends the result to dst. The tag of the record
+// is added to the seal transcript.
+func (t *TranscriptAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	ret := t.aead.Seal(dst, nonce, plaintext, t.seal.additionalData(additionalData))
+	t.seal.add(ret[len(ret)-t.aead.Overhead():])
+	return ret
+}
+
+// Open decrypts and authenticates the ciphertext, the additional data and
+// the open transcript and appends the plaintext to dst. The tag of the record
+// is only added to the open transcript if the ciphertext is authentic.
+func (t *TranscriptAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	plaintext, err := t.aead.Open(dst, nonce, ciphertext, t.open.additionalData(additionalData))
+	if err != nil {
+		return nil, err
+	}
+	t.open.add(ciphertext[len(ciphertext)-t.aead.Overhead():])
+	return plaintext, nil
+}
+
+func (t *transcript) init(h hash.Hash) {
+	t.hash = h
+	t.digest = h.Sum(nil)
+}
+
+// additionalData returns the transcript hash followed by the additional
+// data. The returned slice is valid until the next call.
+func (t *transcript) additionalData(additionalData []byte) []byte {
+	t.ad = append(append(t.ad[:0], t.digest...), additionalData...)
+	return t.ad
+}
+
+// add folds the tag into the transcript hash.
+func (t *transcript) add(tag []byte) {
+	t.hash.Reset()
+	t.hash.Write(t.digest)
+	t.hash.Write(tag)
+	t.digest = t.hash.Sum(t.digest[:0])
+}
@@ -0,0 +1,73 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"testing"
+)
+
+func TestTranscript(t *testing.T) {
+	sender := NewTranscriptAEAD(newTestEAX(t), sha256.New)
+	nonce, data := make([]byte, sender.NonceSize()), []byte("data")
+
+	msgs := [][]byte{[]byte("first record"), []byte("second record"), []byte("third record")}
+	records := make([][]byte, len(msgs))
+	for i, msg := range msgs {
+		nonce[0] = byte(i)
+		records[i] = sender.Seal(nil, nonce, msg, data)
+	}
+
+	receiver := NewTranscriptAEAD(newTestEAX(t), sha256.New)
+	for i, record := range records {
+		nonce[0] = byte(i)
+		plaintext, err := receiver.Open(nil, nonce, record, data)
+		if err != nil {
+			t.Fatalf("Record %d: Open failed: %s", i, err)
+		}
+		if !bytes.Equal(plaintext, msgs[i]) {
+			t.Fatalf("Record %d: Open returned %q - but expected %q", i, plaintext, msgs[i])
+		}
+	}
+
+	// The nonce is passed with the record, so only
+	// the transcript detects the swapped records.
+	receiver = NewTranscriptAEAD(newTestEAX(t), sha256.New)
+	nonce[0] = 0
+	if _, err := receiver.Open(nil, nonce, records[0], data); err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	nonce[0] = 2
+	if _, err := receiver.Open(nil, nonce, records[2], data); err == nil {
+		t.Fatal("Open accepted the third record before the second")
+	}
+	nonce[0] = 1
+	if _, err := receiver.Open(nil, nonce, records[1], data); err != nil {
+		t.Fatalf("Open failed after rejecting a record: %s", err)
+	}
+
+	receiver = NewTranscriptAEAD(newTestEAX(t), sha256.New)
+	nonce[0] = 1
+	if _, err := receiver.Open(nil, nonce, records[1], data); err == nil {
+		t.Fatal("Open accepted the second record as first record")
+	}
+}
+
+func TestNewTranscriptAEAD(t *testing.T) {
+	noTag, err := NewEAXNoTag(newTestEAX(t).blockCipher)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+	mustFail := func(msg string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Expected error: %s", msg)
+			}
+		}()
+		f()
+	}
+	mustFail("AEAD has no tag", func() { NewTranscriptAEAD(noTag, sha256.New) })
+	mustFail("hashNew is nil", func() { NewTranscriptAEAD(newTestEAX(t), nil) })
+}
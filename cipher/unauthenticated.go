@@ -0,0 +1,33 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import "github.com/enceve/crypto"
+
+// OpenUnauthenticated decrypts the ciphertext created by Seal WITHOUT
+// verifying the tag and appends the plaintext to dst.
+//
+// DANGER: OpenUnauthenticated returns the plaintext of ANY ciphertext -
+// also of forged or modified ones. An attacker flipping a bit of the
+// ciphertext flips the same bit of the plaintext. Only use it for
+// benchmarks and for re-processing ciphertexts, which were already
+// authenticated by Open - never for data from an untrusted source.
+//
+// The additional data is ignored - it is only part of the tag. The tag
+// is stripped from the ciphertext, but not checked. If the nonce size is
+// invalid or the ciphertext is shorter than the tag this function panics.
+func (c *EAX) OpenUnauthenticated(dst, nonce, ciphertext, additionalData []byte) []byte {
+	if n := len(nonce); n != c.nonceSize {
+		panic(crypto.NonceSizeMismatchError{Size: n, Expected: c.nonceSize})
+	}
+	if len(ciphertext) < c.size {
+		panic(ErrCiphertextTooShort)
+	}
+	ciphertext, _, _ = crypto.SplitTag(ciphertext, c.size)
+
+	ret, out := sliceForAppend(dst, len(ciphertext))
+	c.setCtr(nil, c.authNonce(nonce))
+	c.ctrCrypt(out, ciphertext)
+	return ret
+}
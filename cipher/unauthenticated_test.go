@@ -0,0 +1,68 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cipher
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestOpenUnauthenticated(t *testing.T) {
+	c := newTestEAX(t)
+	nonce, data := make([]byte, c.NonceSize()), []byte("data")
+	for _, size := range []int{0, 1, 15, 16, 17, 100} {
+		msg := make([]byte, size)
+		for i := range msg {
+			msg[i] = byte(i)
+		}
+		nonce[0] = byte(size)
+		ciphertext := c.Seal(nil, nonce, msg, data)
+		expected, err := c.Open(nil, nonce, ciphertext, data)
+		if err != nil {
+			t.Fatalf("Size %d: Open failed: %s", size, err)
+		}
+		if plaintext := c.OpenUnauthenticated(nil, nonce, ciphertext, data); !bytes.Equal(plaintext, expected) {
+			t.Fatalf("Size %d: OpenUnauthenticated returned %x - but expected %x", size, plaintext, expected)
+		}
+
+		// the tag is not verified
+		ciphertext[len(ciphertext)-1] ^= 1
+		if plaintext := c.OpenUnauthenticated(nil, nonce, ciphertext, nil); !bytes.Equal(plaintext, expected) {
+			t.Fatalf("Size %d: OpenUnauthenticated returned %x for a modified tag - but expected %x", size, plaintext, expected)
+		}
+	}
+
+	offset, err := NewEAXCTROffset(c.blockCipher, 16, 1)
+	if err != nil {
+		t.Fatalf("Failed to create EAX instance: %s", err)
+	}
+	msg := []byte("with CTR offset")
+	ciphertext := offset.Seal(nil, nonce, msg, nil)
+	if plaintext := offset.OpenUnauthenticated(nil, nonce, ciphertext, nil); !bytes.Equal(plaintext, msg) {
+		t.Fatalf("OpenUnauthenticated returned %q - but expected %q", plaintext, msg)
+	}
+
+	mustFail := func(msg string, f func()) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Expected error: %s", msg)
+			}
+		}()
+		f()
+	}
+	mustFail("nonce size is invalid", func() { c.OpenUnauthenticated(nil, nonce[1:], ciphertext, nil) })
+	mustFail("ciphertext is shorter than the tag", func() { c.OpenUnauthenticated(nil, nonce, ciphertext[:c.Overhead()-1], nil) })
+}
+
+func BenchmarkOpenUnauthenticated_1K(b *testing.B) {
+	c := newTestEAX(b)
+	nonce := make([]byte, c.NonceSize())
+	ciphertext := c.Seal(nil, nonce, make([]byte, 1024), nil)
+	buf := make([]byte, 1024)
+	b.SetBytes(1024)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		c.OpenUnauthenticated(buf[:0], nonce, ciphertext, nil)
+	}
+}
@@ -8,6 +8,8 @@ import (
 	"crypto/aes"
 	"encoding/hex"
 	"testing"
+
+	"github.com/enceve/crypto/internal/testvec"
 )
 
 type testVector struct {
@@ -73,26 +75,11 @@ var vectors = []testVector{
 
 func TestVectors(t *testing.T) {
 	for i, v := range vectors {
-		msg, err := hex.DecodeString(v.msg)
-		if err != nil {
-			t.Fatalf("TestVector %d: Failed to decode hex msg: %s", i, err)
-		}
-		key, err := hex.DecodeString(v.key)
-		if err != nil {
-			t.Fatalf("TestVector %d: Failed to decode hex key: %s", i, err)
-		}
-		nonce, err := hex.DecodeString(v.nonce)
-		if err != nil {
-			t.Fatalf("TestVector %d: Failed to decode hex nonce: %s", i, err)
-		}
-		data, err := hex.DecodeString(v.data)
-		if err != nil {
-			t.Fatalf("TestVector %d: Failed to decode hex data: %s", i, err)
-		}
-		ciphertext, err := hex.DecodeString(v.ciphertext)
-		if err != nil {
-			t.Fatalf("TestVector %d: Failed to decode hex ciphertext: %s", i, err)
-		}
+		msg := testvec.MustDecodeHex(v.msg)
+		key := testvec.MustDecodeHex(v.key)
+		nonce := testvec.MustDecodeHex(v.nonce)
+		data := testvec.MustDecodeHex(v.data)
+		ciphertext := testvec.MustDecodeHex(v.ciphertext)
 		cAES, err := aes.NewCipher(key)
 		if err != nil {
 			t.Fatalf("TestVector %d: Failed to create AES instance: %s", i, err)
@@ -102,14 +89,13 @@ func TestVectors(t *testing.T) {
 			t.Fatalf("TestVector %d: Failed to create EAX instance: %s", i, err)
 		}
 
-		buf := make([]byte, len(ciphertext))
-		buf = eax.Seal(buf, nonce, msg, data)
+		buf := eax.Seal(nil, nonce, msg, data)
 
 		if !bytes.Equal(buf, ciphertext) {
 			t.Fatalf("TestVector %d Seal failed:\nFound   : %s\nExpected: %s", i, hex.EncodeToString(buf), hex.EncodeToString(ciphertext))
 		}
 
-		buf, err = eax.Open(buf, nonce, buf, data)
+		buf, err = eax.Open(buf[:0], nonce, buf, data)
 
 		if err != nil {
 			t.Fatalf("TestVector %d: Open failed: %s", i, err)
@@ -0,0 +1,93 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cmac
+
+import (
+	"container/list"
+	"crypto/cipher"
+	"errors"
+	"sync"
+)
+
+// KeyCache is a bounded LRU cache of the CMac key setup
+// (the block cipher and the subkeys) for recently used keys.
+// A KeyCache is safe for concurrent use.
+type KeyCache struct {
+	newCipher func(key []byte) (cipher.Block, error)
+	size      int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+// The entry of a KeyCache
+type keyCacheEntry struct {
+	key  string
+	hash *Hash
+}
+
+// NewKeyCache returns a new KeyCache holding the key setup of at most size
+// keys. The newCipher function creates the block cipher for a key - e.g.
+// aes.NewCipher. The block ciphers are shared by all Hash instances of one
+// key and must be safe for concurrent use. Notice that the cache keeps the
+// key setup of the cached keys in memory.
+// This function returns a non-nil error if the size is not positive or
+// newCipher is nil.
+func NewKeyCache(size int, newCipher func(key []byte) (cipher.Block, error)) (*KeyCache, error) {
+	if size < 1 {
+		return nil, errors.New("key cache size must be positive")
+	}
+	if newCipher == nil {
+		return nil, errors.New("the newCipher function must not be nil")
+	}
+	return &KeyCache{
+		newCipher: newCipher,
+		size:      size,
+		entries:   make(map[string]*list.Element),
+		lru:       list.New(),
+	}, nil
+}
+
+// NewCachedCMAC returns a *Hash computing the CMac checksum using the given key.
+// If the key setup of the key is cached, neither the block cipher nor the subkeys
+// are computed again. Otherwise the key setup is added to the cache and the least
+// recently used key is evicted if the cache is full. The returned Hash does not
+// share any mutable state with the cache or other instances.
+// This function returns a non-nil error if the block cipher cannot be created
+// from the key or is not supported by CMac.
+func NewCachedCMAC(cache *KeyCache, key []byte) (*Hash, error) {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+
+	if e, ok := cache.entries[string(key)]; ok {
+		cache.lru.MoveToFront(e)
+		return e.Value.(*keyCacheEntry).hash.Clone(), nil
+	}
+
+	c, err := cache.newCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	h, err := New(c)
+	if err != nil {
+		return nil, err
+	}
+
+	if cache.lru.Len() >= cache.size {
+		oldest := cache.lru.Back()
+		cache.lru.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*keyCacheEntry).key)
+	}
+	entry := &keyCacheEntry{key: string(key), hash: h}
+	cache.entries[entry.key] = cache.lru.PushFront(entry)
+	return h.Clone(), nil
+}
+
+// Len returns the number of cached keys.
+func (cache *KeyCache) Len() int {
+	cache.mu.Lock()
+	defer cache.mu.Unlock()
+	return cache.lru.Len()
+}
@@ -0,0 +1,165 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"sync"
+	"testing"
+)
+
+func newTestKeys(n int) [][]byte {
+	keys := make([][]byte, n)
+	for i := range keys {
+		keys[i] = make([]byte, 16)
+		keys[i][0] = byte(i)
+	}
+	return keys
+}
+
+func TestNewKeyCache(t *testing.T) {
+	if _, err := NewKeyCache(0, aes.NewCipher); err == nil {
+		t.Fatal("NewKeyCache accepted size 0")
+	}
+	if _, err := NewKeyCache(1, nil); err == nil {
+		t.Fatal("NewKeyCache accepted nil function")
+	}
+	cache, err := NewKeyCache(1, aes.NewCipher)
+	if err != nil {
+		t.Fatalf("Failed to create KeyCache: %s", err)
+	}
+	if _, err = NewCachedCMAC(cache, make([]byte, 15)); err == nil {
+		t.Fatal("NewCachedCMAC accepted an invalid key")
+	}
+	if n := cache.Len(); n != 0 {
+		t.Fatalf("KeyCache contains %d keys after an error", n)
+	}
+}
+
+func TestNewCachedCMAC(t *testing.T) {
+	cache, err := NewKeyCache(4, aes.NewCipher)
+	if err != nil {
+		t.Fatalf("Failed to create KeyCache: %s", err)
+	}
+	msg := []byte("cached cmac")
+
+	keys := newTestKeys(8)
+	for round := 0; round < 3; round++ {
+		for i, key := range keys {
+			h, err := NewCachedCMAC(cache, key)
+			if err != nil {
+				t.Fatalf("Key %d: NewCachedCMAC failed: %s", i, err)
+			}
+			h.Write(msg)
+
+			c, _ := aes.NewCipher(key)
+			tag, _ := Sum(msg, c)
+			if sum := h.Sum(nil); !bytes.Equal(sum, tag) {
+				t.Fatalf("Key %d: Cached CMac differ from CMac", i)
+			}
+			if n := cache.Len(); n > 4 {
+				t.Fatalf("KeyCache contains %d keys - but the size is 4", n)
+			}
+		}
+	}
+
+	// the cached hash must not be modified by the returned instances
+	h0, _ := NewCachedCMAC(cache, keys[7])
+	h0.Write([]byte("modify the state"))
+	h1, _ := NewCachedCMAC(cache, keys[7])
+	h1.Write(msg)
+	c, _ := aes.NewCipher(keys[7])
+	if tag, _ := Sum(msg, c); !bytes.Equal(h1.Sum(nil), tag) {
+		t.Fatal("The cached CMac shares its state with returned instances")
+	}
+}
+
+func TestKeyCacheLRU(t *testing.T) {
+	calls := 0
+	newCipher := func(key []byte) (cipher.Block, error) {
+		calls++
+		return aes.NewCipher(key)
+	}
+	cache, err := NewKeyCache(2, newCipher)
+	if err != nil {
+		t.Fatalf("Failed to create KeyCache: %s", err)
+	}
+	keys := newTestKeys(3)
+
+	for i, v := range []struct {
+		key   int
+		calls int
+	}{
+		{0, 1}, {1, 2}, {0, 2}, {2, 3}, // evicts key 1
+		{0, 3}, {1, 4}, {2, 5},
+	} {
+		if _, err = NewCachedCMAC(cache, keys[v.key]); err != nil {
+			t.Fatalf("Step %d: NewCachedCMAC failed: %s", i, err)
+		}
+		if calls != v.calls {
+			t.Fatalf("Step %d: %d key setups - but expected %d", i, calls, v.calls)
+		}
+	}
+}
+
+func TestKeyCacheConcurrent(t *testing.T) {
+	cache, err := NewKeyCache(4, aes.NewCipher)
+	if err != nil {
+		t.Fatalf("Failed to create KeyCache: %s", err)
+	}
+	keys := newTestKeys(8)
+	msg := []byte("concurrent")
+
+	var wg sync.WaitGroup
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 64; i++ {
+				key := keys[(g+i)%len(keys)]
+				h, err := NewCachedCMAC(cache, key)
+				if err != nil {
+					t.Errorf("NewCachedCMAC failed: %s", err)
+					return
+				}
+				h.Write(msg)
+				c, _ := aes.NewCipher(key)
+				if tag, _ := Sum(msg, c); !bytes.Equal(h.Sum(nil), tag) {
+					t.Errorf("Cached CMac differ from CMac")
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+}
+
+func BenchmarkRoundRobinNew(b *testing.B) {
+	keys := newTestKeys(8)
+	msg := make([]byte, 64)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		c, _ := aes.NewCipher(keys[i%len(keys)])
+		h, _ := New(c)
+		h.Write(msg)
+		h.Sum(nil)
+	}
+}
+
+func BenchmarkRoundRobinCached(b *testing.B) {
+	keys := newTestKeys(8)
+	msg := make([]byte, 64)
+	cache, err := NewKeyCache(8, aes.NewCipher)
+	if err != nil {
+		b.Fatalf("Failed to create KeyCache: %s", err)
+	}
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		h, _ := NewCachedCMAC(cache, keys[i%len(keys)])
+		h.Write(msg)
+		h.Sum(nil)
+	}
+}
@@ -14,8 +14,11 @@
 //	- 1024 bit (128 byte)
 // Common ciphers like AES, Serpent etc. operate on 128 bit
 // blocks. 256, 512 and 1024 are supported for the Threefish
-// tweakable block cipher. Ciphers with 64 bit blocks are
-// supported, but not recommened.
+// tweakable block cipher. Ciphers with 64 bit blocks (like
+// TDES or Blowfish) are supported, but not recommened.
+// The constant Rb used for the subkey derivation is chosen
+// by the block size (e.g. 0x87 for 128 bit and 0x1b for
+// 64 bit block ciphers) as specified in NIST SP 800-38B.
 // CMac (using AES) is specified in RFC 4493.
 package cmac
 
@@ -23,9 +26,9 @@ import (
 	"crypto/cipher"
 	"crypto/subtle"
 	"errors"
-	"hash"
 
 	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/internal/gf128"
 )
 
 const (
@@ -61,10 +64,10 @@ func Verify(mac, msg []byte, c cipher.Block) bool {
 	return subtle.ConstantTimeCompare(mac, sum) == 1
 }
 
-// New returns a hash.Hash computing the CMac checksum.
+// New returns a *Hash computing the CMac checksum.
 // If the block cipher is not supported by CMac
 // (see package doc), a non-nil error is returned.
-func New(c cipher.Block) (hash.Hash, error) {
+func New(c cipher.Block) (*Hash, error) {
 	if c == nil {
 		return nil, errors.New("the cipher.Block must not be nil")
 	}
@@ -86,7 +89,7 @@ func New(c cipher.Block) (hash.Hash, error) {
 		p = p1024
 	}
 
-	m := &macFunc{
+	m := &Hash{
 		cipher: c,
 		k0:     make([]byte, bs),
 		k1:     make([]byte, bs),
@@ -94,35 +97,69 @@ func New(c cipher.Block) (hash.Hash, error) {
 	}
 	c.Encrypt(m.k0, m.k0)
 
-	v := shift(m.k0, m.k0)
-	m.k0[bs-1] ^= byte(subtle.ConstantTimeSelect(v, p, 0))
-
-	v = shift(m.k1, m.k0)
-	m.k1[bs-1] ^= byte(subtle.ConstantTimeSelect(v, p, 0))
+	double(m.k0, p)
+	copy(m.k1, m.k0)
+	double(m.k1, p)
 
 	return m, nil
 }
 
-// The CMac message auth. function
-type macFunc struct {
+// SubKeys returns the subkeys K1 and K2 derived from the block cipher - e.g.
+// to compare them with the subkeys of another implementation. The subkeys are
+// derived from L = E_K(0): K1 = L * x and K2 = K1 * x in GF(2^n), so a shift by
+// one bit XORed with the constant Rb of the block size (e.g. 0x87 for 128 bit)
+// if the most significant bit was set. The subkeys must be kept secret like
+// the key. If the block cipher is not supported by CMac (see package doc)
+// this function panics.
+func SubKeys(c cipher.Block) (k1, k2 []byte) {
+	h, err := New(c)
+	if err != nil {
+		panic(err)
+	}
+	return h.k0, h.k1
+}
+
+// Hash is the CMac message auth. function
+// implementing hash.Hash.
+type Hash struct {
 	cipher cipher.Block
 	k0, k1 []byte
 	buf    []byte
 	off    int
+
+	// scratch buffer for Sum16 - a local array would
+	// escape to the heap through cipher.Block.Encrypt
+	sum16 [16]byte
+}
+
+// Clone returns a copy of the Hash including the state of
+// the message processed so far. The clone shares the subkeys
+// (which are never modified) with h, so the key setup is not
+// repeated. Both instances can be used independently.
+func (h *Hash) Clone() *Hash {
+	buf := make([]byte, len(h.buf))
+	copy(buf, h.buf)
+	return &Hash{
+		cipher: h.cipher,
+		k0:     h.k0,
+		k1:     h.k1,
+		buf:    buf,
+		off:    h.off,
+	}
 }
 
-func (h *macFunc) Size() int { return h.cipher.BlockSize() }
+func (h *Hash) Size() int { return h.cipher.BlockSize() }
 
-func (h *macFunc) BlockSize() int { return h.cipher.BlockSize() }
+func (h *Hash) BlockSize() int { return h.cipher.BlockSize() }
 
-func (h *macFunc) Reset() {
+func (h *Hash) Reset() {
 	for i := range h.buf {
 		h.buf[i] = 0
 	}
 	h.off = 0
 }
 
-func (h *macFunc) Write(msg []byte) (int, error) {
+func (h *Hash) Write(msg []byte) (int, error) {
 	bs := h.BlockSize()
 	n := len(msg)
 
@@ -160,24 +197,92 @@ func (h *macFunc) Write(msg []byte) (int, error) {
 	return n, nil
 }
 
-func (h *macFunc) Sum(b []byte) []byte {
-	bs := h.cipher.BlockSize()
-
+func (h *Hash) Sum(b []byte) []byte {
 	// Don't change the buffer so the
 	// caller can keep writing and suming.
-	hash := make([]byte, bs)
+	// If b has enough capacity, Sum does not allocate.
+	bs := h.cipher.BlockSize()
+	var ret []byte
+	if total := len(b) + bs; cap(b) >= total {
+		ret = b[:total]
+	} else {
+		ret = make([]byte, total)
+		copy(ret, b)
+	}
+	h.sum(ret[len(b):])
+	return ret
+}
+
+// Sum16 returns the CMac checksum of the processed data as array.
+// Like Sum it does not change the state of the hash, but
+// Sum16 never allocates. If the block size of the cipher
+// is not 16 bytes this function panics.
+func (h *Hash) Sum16() [16]byte {
+	if h.cipher.BlockSize() != 16 {
+		panic("cmac: Sum16 requires a block size of 16 bytes")
+	}
+	h.sum(h.sum16[:])
+	return h.sum16
+}
+
+// SumReset computes the CMac checksum of msg and resets
+// the hash. All data written before SumReset is discarded.
+// SumReset is equal to Reset, Write(msg), Sum(nil) and Reset
+// but avoids the additional allocation of Sum.
+func (h *Hash) SumReset(msg []byte) []byte {
+	h.Reset()
+	h.Write(msg)
+	hash := make([]byte, h.cipher.BlockSize())
+	h.sum(hash)
+	h.Reset()
+	return hash
+}
+
+// BatchSum computes the CMac checksums of all msgs and resets the
+// hash. All data written before BatchSum is discarded. The returned
+// checksums share one allocated buffer.
+func (h *Hash) BatchSum(msgs [][]byte) [][]byte {
+	bs := h.cipher.BlockSize()
+	buf := make([]byte, len(msgs)*bs)
+	sums := make([][]byte, len(msgs))
+	for i, msg := range msgs {
+		h.Reset()
+		h.Write(msg)
+		sums[i] = buf[i*bs : (i+1)*bs : (i+1)*bs]
+		h.sum(sums[i])
+	}
+	h.Reset()
+	return sums
+}
+
+// sum writes the CMac checksum of the processed data to hash.
+func (h *Hash) sum(hash []byte) {
+	bs := h.cipher.BlockSize()
 
 	k := h.k0
 	if h.off < bs {
 		k = h.k1
 	}
 	crypto.XOR(hash, k, h.buf)
-	if h.off < h.cipher.BlockSize() {
+	if h.off < bs {
 		hash[h.off] ^= 0x80
 	}
 
 	h.cipher.Encrypt(hash, hash)
-	return append(b, hash...)
+}
+
+// double multiplies b by x in GF(2^n) with the constant p
+// of the block size in constant time.
+func double(b []byte, p int) {
+	switch len(b) {
+	case 8:
+		gf128.Double64(b)
+	case 16:
+		gf128.Double(b)
+	default:
+		v := shift(b, b)
+		b[len(b)-1] ^= byte(subtle.ConstantTimeSelect(v, p, 0))
+	}
 }
 
 func shift(dst, src []byte) int {
@@ -77,10 +77,7 @@ func TestReset(t *testing.T) {
 	if err != nil {
 		t.Fatalf("Failed to use CMac with the specified cipher")
 	}
-	c, ok := h.(*macFunc)
-	if !ok {
-		t.Fatal("Impossible situation: New returns no CMac struct")
-	}
+	c := h
 	orig := *c // copy
 
 	c.Write(make([]byte, c.BlockSize()+1))
@@ -215,3 +212,155 @@ func benchmarkSum(b *testing.B, nBytes int) {
 		Sum(buf, c)
 	}
 }
+
+func TestSumReset(t *testing.T) {
+	c, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Could not create AES instance: %s", err)
+	}
+	h, err := New(c)
+	if err != nil {
+		t.Fatalf("Failed to create CMac instance: %s", err)
+	}
+
+	msgs := make([][]byte, 64)
+	for i := range msgs {
+		msgs[i] = make([]byte, i)
+		for j := range msgs[i] {
+			msgs[i][j] = byte(i + j)
+		}
+	}
+
+	h.Write([]byte("discarded"))
+	sums := h.BatchSum(msgs)
+	for i, msg := range msgs {
+		tag, err := Sum(msg, c)
+		if err != nil {
+			t.Fatalf("Failed to compute CMac tag: %s", err)
+		}
+		if !bytes.Equal(sums[i], tag) {
+			t.Fatalf("Message %d: BatchSum differ from cmac.Sum\n BatchSum: %s \n cmac.Sum: %s", i, hex.EncodeToString(sums[i]), hex.EncodeToString(tag))
+		}
+		h.Write([]byte("discarded"))
+		if sum := h.SumReset(msg); !bytes.Equal(sum, tag) {
+			t.Fatalf("Message %d: SumReset differ from cmac.Sum\n SumReset: %s \n cmac.Sum: %s", i, hex.EncodeToString(sum), hex.EncodeToString(tag))
+		}
+	}
+	if !bytes.Equal(h.Sum(nil), h.SumReset(nil)) {
+		t.Fatal("SumReset did not reset the hash")
+	}
+}
+
+func BenchmarkSumLoop_1K_16B(b *testing.B) {
+	c, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		b.Fatalf("Failed to create AES instance: %s", err)
+	}
+	h, err := New(c)
+	if err != nil {
+		b.Fatalf("Failed to create CMac instance: %s", err)
+	}
+	msgs := make([][]byte, 1024)
+	for i := range msgs {
+		msgs[i] = make([]byte, 16)
+	}
+	b.SetBytes(int64(len(msgs) * 16))
+	for i := 0; i < b.N; i++ {
+		for _, msg := range msgs {
+			h.Write(msg)
+			h.Sum(nil)
+			h.Reset()
+		}
+	}
+}
+
+func BenchmarkBatchSum_1K_16B(b *testing.B) {
+	c, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		b.Fatalf("Failed to create AES instance: %s", err)
+	}
+	h, err := New(c)
+	if err != nil {
+		b.Fatalf("Failed to create CMac instance: %s", err)
+	}
+	msgs := make([][]byte, 1024)
+	for i := range msgs {
+		msgs[i] = make([]byte, 16)
+	}
+	b.SetBytes(int64(len(msgs) * 16))
+	for i := 0; i < b.N; i++ {
+		h.BatchSum(msgs)
+	}
+}
+
+func TestClone(t *testing.T) {
+	c, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Could not create AES instance: %s", err)
+	}
+	h, err := New(c)
+	if err != nil {
+		t.Fatalf("Failed to create CMac instance: %s", err)
+	}
+
+	h.Write([]byte("shared prefix"))
+	clone := h.Clone()
+	h.Write([]byte(" - original"))
+	clone.Write([]byte(" - clone"))
+
+	for _, v := range []struct {
+		h   *Hash
+		msg string
+	}{
+		{h, "shared prefix - original"},
+		{clone, "shared prefix - clone"},
+	} {
+		tag, err := Sum([]byte(v.msg), c)
+		if err != nil {
+			t.Fatalf("Failed to compute CMac tag: %s", err)
+		}
+		if sum := v.h.Sum(nil); !bytes.Equal(sum, tag) {
+			t.Fatalf("Message %q: Sum returned %s - but expected %s", v.msg, hex.EncodeToString(sum), hex.EncodeToString(tag))
+		}
+	}
+}
+
+func TestSum16(t *testing.T) {
+	c, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Could not create AES instance: %s", err)
+	}
+	h, err := New(c)
+	if err != nil {
+		t.Fatalf("Failed to create CMac instance: %s", err)
+	}
+
+	for _, msg := range []string{"", "short", "exactly 16 bytes", "a message longer than one block"} {
+		h.Reset()
+		h.Write([]byte(msg))
+		sum := h.Sum16()
+		if expected := h.Sum(nil); !bytes.Equal(sum[:], expected) {
+			t.Fatalf("Message %q: Sum16 returned %x - but expected %x", msg, sum, expected)
+		}
+	}
+
+	msg := make([]byte, 256)
+	if n := testing.AllocsPerRun(100, func() {
+		h.Reset()
+		h.Write(msg)
+		h.Sum16()
+	}); n != 0 {
+		t.Fatalf("Sum16 allocates %.1f times - but expected 0", n)
+	}
+
+	h, err = New(dummyCipher(8))
+	if err != nil {
+		t.Fatalf("Failed to create CMac instance: %s", err)
+	}
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Sum16 accepted a block size of 8 bytes")
+		}
+	}()
+	h.Sum16()
+}
@@ -0,0 +1,29 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cmac
+
+import "crypto/aes"
+
+// PRF128 computes the AES-CMAC-PRF-128 of msg using the key
+// as specified in RFC 4615. The key may have any length:
+// a 16 byte key is used directly as AES-128 key. All other
+// keys are compressed to a 16 byte key by computing their
+// AES-CMAC under the all-zero key first.
+// The returned PRF output is 16 bytes long.
+func PRF128(key, msg []byte) ([]byte, error) {
+	if len(key) != aes.BlockSize {
+		c, err := aes.NewCipher(make([]byte, aes.BlockSize))
+		if err != nil {
+			return nil, err
+		}
+		if key, err = Sum(key, c); err != nil {
+			return nil, err
+		}
+	}
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return Sum(msg, c)
+}
@@ -0,0 +1,38 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+func init() {
+	crypto.RegisterSelfTest("AES-CMac", selfTest)
+}
+
+// selfTest checks CMac-AES-128 against the NIST SP 800-38B
+// example 2 (one block message).
+func selfTest() error {
+	key, _ := hex.DecodeString("2b7e151628aed2a6abf7158809cf4f3c")
+	msg, _ := hex.DecodeString("6bc1bee22e409f96e93d7e117393172a")
+	tag, _ := hex.DecodeString("070a16b46b4d4144f79bdd9dd04a287c")
+
+	c, err := aes.NewCipher(key)
+	if err != nil {
+		return err
+	}
+	sum, err := Sum(msg, c)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(sum, tag) {
+		return errors.New("unexpected tag")
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cmac
+
+import (
+	"crypto/cipher"
+	"hash"
+)
+
+// NewTagged returns a hash.Hash computing the CMac checksum of the
+// domain separation block [0, ..., 0, domain] followed by the written
+// data - like the OMAC^t of EAX. So one key can be used for up to 256
+// independent MAC contexts. The tag block is written again on Reset.
+// If the block cipher is not supported by CMac (see package doc),
+// a non-nil error is returned.
+func NewTagged(c cipher.Block, domain byte) (hash.Hash, error) {
+	h, err := New(c)
+	if err != nil {
+		return nil, err
+	}
+	t := &taggedHash{
+		Hash: h,
+		tag:  make([]byte, h.BlockSize()),
+	}
+	t.tag[len(t.tag)-1] = domain
+	t.Reset()
+	return t, nil
+}
+
+// The domain separated CMac
+type taggedHash struct {
+	*Hash
+	tag []byte
+}
+
+func (h *taggedHash) Reset() {
+	h.Hash.Reset()
+	h.Hash.Write(h.tag)
+}
@@ -0,0 +1,52 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cmac
+
+import (
+	"bytes"
+	"crypto/aes"
+	"testing"
+)
+
+func TestNewTagged(t *testing.T) {
+	c, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	msg := []byte("domain separated message")
+
+	sums := make(map[string]byte)
+	for _, domain := range []byte{0, 1, 2, 0xff} {
+		h, err := NewTagged(c, domain)
+		if err != nil {
+			t.Fatalf("Domain %d: NewTagged failed: %s", domain, err)
+		}
+		h.Write(msg)
+		sum := h.Sum(nil)
+
+		block := make([]byte, c.BlockSize())
+		block[len(block)-1] = domain
+		ref, err := Sum(append(block, msg...), c)
+		if err != nil {
+			t.Fatalf("Domain %d: Sum failed: %s", domain, err)
+		}
+		if !bytes.Equal(sum, ref) {
+			t.Fatalf("Domain %d: NewTagged computed %x - but expected %x", domain, sum, ref)
+		}
+		if d, ok := sums[string(sum)]; ok {
+			t.Fatalf("Domain %d and %d produced the same MAC", d, domain)
+		}
+		sums[string(sum)] = domain
+
+		h.Reset()
+		h.Write(msg)
+		if sum = h.Sum(nil); !bytes.Equal(sum, ref) {
+			t.Fatalf("Domain %d: Reset removed the tag block", domain)
+		}
+	}
+
+	if _, err = NewTagged(dummyCipher(24), 1); err == nil {
+		t.Fatal("NewTagged accepted an unsupported block size")
+	}
+}
@@ -6,8 +6,11 @@ package cmac
 import (
 	"bytes"
 	"crypto/aes"
+	"crypto/des"
 	"encoding/hex"
 	"testing"
+
+	"github.com/enceve/crypto/internal/testvec"
 )
 
 // Test vectors for CMac-AES from NIST
@@ -59,22 +62,93 @@ var testVectors = []struct {
 
 func TestVectors(t *testing.T) {
 	for i, v := range testVectors {
-		key, err := hex.DecodeString(v.key)
+		key := testvec.MustDecodeHex(v.key)
+		msg := testvec.MustDecodeHex(v.msg)
+		hash := testvec.MustDecodeHex(v.hash)
+
+		c, err := aes.NewCipher(key)
 		if err != nil {
-			t.Fatalf("Test vector %d: Failed to decode hex key: %s", i, err)
+			t.Fatalf("Test vector %d: Failed to create AES instance: %s", i, err)
 		}
-		msg, err := hex.DecodeString(v.msg)
+		h, err := New(c)
 		if err != nil {
-			t.Fatalf("Test vector %d: Failed to decode hex msg: %s", i, err)
+			t.Fatalf("Test vector %d: Failed to create CMac instance: %s", i, err)
 		}
-		hash, err := hex.DecodeString(v.hash)
+		_, err = h.Write(msg)
 		if err != nil {
-			t.Fatalf("Test vector %d: Failed to decode hex hash: %s", i, err)
+			t.Fatalf("Test vector %d: CMac write failed: %s", i, err)
 		}
+		sum := h.Sum(nil)
+		if !bytes.Equal(sum, hash) {
+			t.Fatalf("Test vector %d : MAC does not match:\nFound:    %v\nExpected: %v", i, hex.EncodeToString(sum), hex.EncodeToString(hash))
+		}
+		if !Verify(hash, msg, c) {
+			t.Fatalf("Test vector %d: verification of MAC failed", i)
+		}
+	}
+}
 
-		c, err := aes.NewCipher(key)
+// Test vectors for CMac-TDEA from NIST
+// http://csrc.nist.gov/publications/nistpubs/800-38B/SP_800-38B.pdf
+// Appendix D
+var tdesTestVectors = []struct {
+	key, msg, hash string
+}{
+	// Three key TDEA vectors
+	{
+		key:  "8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5",
+		msg:  "",
+		hash: "b7a688e122ffaf95",
+	},
+	{
+		key:  "8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5",
+		msg:  "6bc1bee22e409f96",
+		hash: "8e8f293136283797",
+	},
+	{
+		key:  "8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5",
+		msg:  "6bc1bee22e409f96e93d7e117393172aae2d8a57",
+		hash: "743ddbe0ce2dc2ed",
+	},
+	{
+		key: "8aa83bf8cbda10620bc1bf19fbb6cd58bc313d4a371ca8b5",
+		msg: "6bc1bee22e409f96e93d7e117393172a" +
+			"ae2d8a571e03ac9c9eb76fac45af8e51",
+		hash: "33e6b1092400eae5",
+	},
+	// Two key TDEA vectors
+	{
+		key:  "4cf15134a2850dd58a3d10ba80570d384cf15134a2850dd5",
+		msg:  "",
+		hash: "bd2ebf9a3ba00361",
+	},
+	{
+		key:  "4cf15134a2850dd58a3d10ba80570d384cf15134a2850dd5",
+		msg:  "6bc1bee22e409f96",
+		hash: "4ff2ab813c53ce83",
+	},
+	{
+		key:  "4cf15134a2850dd58a3d10ba80570d384cf15134a2850dd5",
+		msg:  "6bc1bee22e409f96e93d7e117393172aae2d8a57",
+		hash: "62dd1b471902bd4e",
+	},
+	{
+		key: "4cf15134a2850dd58a3d10ba80570d384cf15134a2850dd5",
+		msg: "6bc1bee22e409f96e93d7e117393172a" +
+			"ae2d8a571e03ac9c9eb76fac45af8e51",
+		hash: "31b1e431dabc4eb8",
+	},
+}
+
+func TestTDESVectors(t *testing.T) {
+	for i, v := range tdesTestVectors {
+		key := testvec.MustDecodeHex(v.key)
+		msg := testvec.MustDecodeHex(v.msg)
+		hash := testvec.MustDecodeHex(v.hash)
+
+		c, err := des.NewTripleDESCipher(key)
 		if err != nil {
-			t.Fatalf("Test vector %d: Failed to create AES instance: %s", i, err)
+			t.Fatalf("Test vector %d: Failed to create TDES instance: %s", i, err)
 		}
 		h, err := New(c)
 		if err != nil {
@@ -93,3 +167,93 @@ func TestVectors(t *testing.T) {
 		}
 	}
 }
+
+// Test vectors for AES-CMAC-PRF-128 from https://tools.ietf.org/html/rfc4615#section-4
+// The last vector (20 byte key) was computed with OpenSSL.
+var prfTestVectors = []struct {
+	key, msg, prf string
+}{
+	{
+		key: "000102030405060708090a0b0c0d0e0fedcb",
+		msg: "000102030405060708090a0b0c0d0e0f10111213",
+		prf: "84a348a4a45d235babfffc0d2b4da09a",
+	},
+	{
+		key: "000102030405060708090a0b0c0d0e0f",
+		msg: "000102030405060708090a0b0c0d0e0f10111213",
+		prf: "980ae87b5f4c9c5214f5b6a8455e4c2d",
+	},
+	{
+		key: "00010203040506070809",
+		msg: "000102030405060708090a0b0c0d0e0f10111213",
+		prf: "290d9e112edb09ee141fcf64c0b72f3d",
+	},
+	{
+		key: "000102030405060708090a0b0c0d0e0f10111213",
+		msg: "000102030405060708090a0b0c0d0e0f10111213",
+		prf: "33e09d3acbe97b8611bc9e13509dbf0a",
+	},
+}
+
+func TestPRF128Vectors(t *testing.T) {
+	for i, v := range prfTestVectors {
+		key := testvec.MustDecodeHex(v.key)
+		msg := testvec.MustDecodeHex(v.msg)
+		prf := testvec.MustDecodeHex(v.prf)
+
+		sum, err := PRF128(key, msg)
+		if err != nil {
+			t.Fatalf("Test vector %d: PRF128 failed: %s", i, err)
+		}
+		if !bytes.Equal(sum, prf) {
+			t.Fatalf("Test vector %d: PRF128 returned %s - but expected %s", i, hex.EncodeToString(sum), v.prf)
+		}
+	}
+}
+
+// The subkey examples of NIST SP 800-38B (Appendix D.1 and D.3):
+// https://csrc.nist.gov/publications/detail/sp/800-38b/final
+var subKeyVectors = []struct {
+	key, l, k1, k2 string
+}{
+	{
+		key: "2b7e151628aed2a6abf7158809cf4f3c",
+		l:   "7df76b0c1ab899b33e42f047b91b546f",
+		k1:  "fbeed618357133667c85e08f7236a8de",
+		k2:  "f7ddac306ae266ccf90bc11ee46d513b",
+	},
+	{
+		key: "603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4",
+		l:   "e568f68194cf76d6174d4cc04310a854",
+		k1:  "cad1ed03299eedac2e9a99808621502f",
+		k2:  "95a3da06533ddb585d3533010c42a0d9",
+	},
+}
+
+func TestSubKeys(t *testing.T) {
+	for i, v := range subKeyVectors {
+		c, err := aes.NewCipher(testvec.MustDecodeHex(v.key))
+		if err != nil {
+			t.Fatalf("Test vector %d: Failed to create AES instance: %s", i, err)
+		}
+		l := make([]byte, c.BlockSize())
+		c.Encrypt(l, l)
+		if !testvec.EqualHex(l, v.l) {
+			t.Fatalf("Test vector %d: L is %x - but expected %s", i, l, v.l)
+		}
+		k1, k2 := SubKeys(c)
+		if !testvec.EqualHex(k1, v.k1) {
+			t.Fatalf("Test vector %d: K1 is %x - but expected %s", i, k1, v.k1)
+		}
+		if !testvec.EqualHex(k2, v.k2) {
+			t.Fatalf("Test vector %d: K2 is %x - but expected %s", i, k2, v.k2)
+		}
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("Expected error: block size is not supported")
+		}
+	}()
+	SubKeys(dummyCipher(12))
+}
@@ -0,0 +1,29 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto
+
+// IncrementBE increments the big-endian number ctr by one - e.g.
+// the counter block of the CTR mode. The counter wraps around to
+// zero if all bytes are 0xff. The execution time only depends on
+// the length of ctr - a loop stopping at the first byte without
+// carry would reveal the carry pattern of a (secret) counter.
+func IncrementBE(ctr []byte) {
+	carry := uint16(1)
+	for i := len(ctr) - 1; i >= 0; i-- {
+		carry += uint16(ctr[i])
+		ctr[i] = byte(carry)
+		carry >>= 8
+	}
+}
+
+// IncrementLE increments the little-endian number ctr by one.
+// See IncrementBE for details.
+func IncrementLE(ctr []byte) {
+	carry := uint16(1)
+	for i := range ctr {
+		carry += uint16(ctr[i])
+		ctr[i] = byte(carry)
+		carry >>= 8
+	}
+}
@@ -0,0 +1,72 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto_test
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/enceve/crypto"
+)
+
+func reverse(b []byte) []byte {
+	r := make([]byte, len(b))
+	for i := range b {
+		r[len(b)-1-i] = b[i]
+	}
+	return r
+}
+
+func TestIncrement(t *testing.T) {
+	// increment with early exit on the first byte without carry
+	increment := func(ctr []byte) {
+		for k := len(ctr) - 1; k >= 0; k-- {
+			ctr[k]++
+			if ctr[k] != 0 {
+				break
+			}
+		}
+	}
+
+	for _, start := range []string{
+		"00000000000000000000000000000000",
+		"0000000000000000000000000000ff00",
+		"00000000000000000000fffffffff000",
+		"ffffffffffffffffffffffffffffe000",
+		"ffffff",
+	} {
+		ctr0, _ := hex.DecodeString(start)
+		ctr1, _ := hex.DecodeString(start)
+		ctr2 := reverse(ctr1)
+		for i := 0; i < 10000; i++ {
+			increment(ctr0)
+			crypto.IncrementBE(ctr1)
+			crypto.IncrementLE(ctr2)
+			if !bytes.Equal(ctr0, ctr1) {
+				t.Fatalf("Start %s - step %d: IncrementBE returned %x - but expected %x", start, i, ctr1, ctr0)
+			}
+			if !bytes.Equal(ctr0, reverse(ctr2)) {
+				t.Fatalf("Start %s - step %d: IncrementLE returned %x - but expected %x", start, i, ctr2, reverse(ctr0))
+			}
+		}
+	}
+}
+
+func TestIncrementRollover(t *testing.T) {
+	for _, size := range []int{1, 4, 8, 16} {
+		be, le := bytes.Repeat([]byte{0xff}, size), bytes.Repeat([]byte{0xff}, size)
+		crypto.IncrementBE(be)
+		crypto.IncrementLE(le)
+		zero := make([]byte, size)
+		if !bytes.Equal(be, zero) {
+			t.Fatalf("Size %d: IncrementBE returned %x - but expected %x", size, be, zero)
+		}
+		if !bytes.Equal(le, zero) {
+			t.Fatalf("Size %d: IncrementLE returned %x - but expected %x", size, le, zero)
+		}
+	}
+	crypto.IncrementBE(nil)
+	crypto.IncrementLE(nil)
+}
@@ -5,7 +5,10 @@
 // cryptographic functions and types.
 package crypto
 
-import "strconv"
+import (
+	"crypto/subtle"
+	"strconv"
+)
 
 // A KeySizeError indicates, that the size of a given key
 // does not match the expected size.
@@ -23,11 +26,108 @@ func (n NonceSizeError) Error() string {
 	return "invalid nonce size " + strconv.Itoa(int(n))
 }
 
+// A NonceSizeMismatchError indicates, that the size of a given
+// nonce does not match the expected size. Unlike NonceSizeError
+// it also reports the expected size. It wraps the NonceSizeError
+// of the given size - so errors.As(err, &NonceSizeError) works for
+// both types.
+type NonceSizeMismatchError struct {
+	Size     int // the size of the given nonce
+	Expected int // the expected size
+}
+
+func (n NonceSizeMismatchError) Error() string {
+	return "invalid nonce size " + strconv.Itoa(n.Size) + " - expected " + strconv.Itoa(n.Expected)
+}
+
+// Unwrap returns the NonceSizeError of the given nonce size.
+func (n NonceSizeMismatchError) Unwrap() error { return NonceSizeError(n.Size) }
+
 // A AuthenticationError indicates, that an authentication
 // process failed. E.g. the message authentication of a AEAD
-// cipher.
-type AuthenticationError struct{}
+// cipher. Algorithm is the name of the failing algorithm (e.g.
+// "EAX") and may be empty.
+type AuthenticationError struct {
+	Algorithm string
+}
 
 func (a AuthenticationError) Error() string {
-	return "authentication failed"
+	if a.Algorithm == "" {
+		return "authentication failed"
+	}
+	return a.Algorithm + ": authentication failed"
+}
+
+// Is reports whether target is an AuthenticationError of the same
+// algorithm. An AuthenticationError without algorithm matches every
+// AuthenticationError - so errors.Is(err, AuthenticationError{})
+// detects all authentication failures.
+func (a AuthenticationError) Is(target error) bool {
+	t, ok := target.(AuthenticationError)
+	return ok && (t.Algorithm == "" || t.Algorithm == a.Algorithm)
+}
+
+// Equal compares a and b in constant time and returns true if
+// and only if both slices have the same length and content.
+// Only the length of the slices may be leaked by timing - never
+// their content. Equal should be used to verify MACs and
+// authentication tags.
+func Equal(a, b []byte) bool {
+	return subtle.ConstantTimeCompare(a, b) == 1
+}
+
+// CTEqual compares a and b in constant time and returns true if and only
+// if both slices have the same length and content. Unlike Equal, CTEqual
+// does not return early for slices of different lengths: the timing only
+// depends on the length of the longer slice. CTEqual is meant for general
+// comparisons of secret or sensitive data - e.g. ciphertexts or fingerprints
+// of different length - use Equal to verify MACs and authentication tags.
+func CTEqual(a, b []byte) bool {
+	n := len(a)
+	if len(b) > n {
+		n = len(b)
+	}
+	var d byte
+	for i := 0; i < n; i++ {
+		var x, y byte
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		d |= x ^ y
+	}
+	return uint(d)|uint(len(a)^len(b)) == 0
+}
+
+// CMove returns a copy of x if v == 1 and a copy of y if v == 0.
+// The selection is done in constant time - only the length of the
+// slices may be leaked by timing. The behavior is undefined if v
+// takes any other value. If len(x) != len(y) this function panics.
+func CMove(v int, x, y []byte) []byte {
+	if len(x) != len(y) {
+		panic("crypto: slices have different lengths")
+	}
+	out := make([]byte, len(y))
+	copy(out, y)
+	subtle.ConstantTimeCopy(v, out, x)
+	return out
+}
+
+// CSwap swaps the contents of x and y if v == 1 and leaves them
+// unchanged if v == 0. The swap is done in constant time - only
+// the length of the slices may be leaked by timing. The behavior
+// is undefined if v takes any other value.
+// If len(x) != len(y) this function panics.
+func CSwap(v int, x, y []byte) {
+	if len(x) != len(y) {
+		panic("crypto: slices have different lengths")
+	}
+	mask := byte(-v)
+	for i := range x {
+		t := mask & (x[i] ^ y[i])
+		x[i] ^= t
+		y[i] ^= t
+	}
 }
@@ -0,0 +1,134 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestEqual(t *testing.T) {
+	var tests = []struct {
+		a, b  []byte
+		equal bool
+	}{
+		{a: nil, b: nil, equal: true},
+		{a: nil, b: []byte{}, equal: true},
+		{a: []byte{1, 2, 3}, b: []byte{1, 2, 3}, equal: true},
+		{a: []byte{1, 2, 3}, b: []byte{1, 2, 4}, equal: false},
+		{a: []byte{1, 2, 3}, b: []byte{0, 2, 3}, equal: false},
+		{a: []byte{1, 2, 3}, b: []byte{1, 2}, equal: false},
+		{a: []byte{1, 2}, b: []byte{1, 2, 3}, equal: false},
+		{a: nil, b: []byte{0}, equal: false},
+	}
+	for i, v := range tests {
+		if eq := Equal(v.a, v.b); eq != v.equal {
+			t.Fatalf("Test %d: Equal(%v, %v) returned %v - but expected %v", i, v.a, v.b, eq, v.equal)
+		}
+		if eq := CTEqual(v.a, v.b); eq != v.equal {
+			t.Fatalf("Test %d: CTEqual(%v, %v) returned %v - but expected %v", i, v.a, v.b, eq, v.equal)
+		}
+	}
+
+	// a longer slice with a zero suffix must not match
+	if CTEqual([]byte{1, 2}, []byte{1, 2, 0}) || CTEqual([]byte{1, 2, 0}, []byte{1, 2}) {
+		t.Fatal("CTEqual matched slices of different lengths")
+	}
+}
+
+func TestCMove(t *testing.T) {
+	x, y := []byte{1, 2, 3}, []byte{4, 5, 6}
+	if out := CMove(1, x, y); !Equal(out, x) {
+		t.Fatalf("CMove(1, %v, %v) returned %v - but expected %v", x, y, out, x)
+	}
+	if out := CMove(0, x, y); !Equal(out, y) {
+		t.Fatalf("CMove(0, %v, %v) returned %v - but expected %v", x, y, out, y)
+	}
+	out := CMove(1, x, y)
+	out[0] = 0
+	if x[0] != 1 {
+		t.Fatal("CMove returned a slice sharing the memory of x")
+	}
+	if out := CMove(1, nil, nil); len(out) != 0 {
+		t.Fatalf("CMove(1, nil, nil) returned %v", out)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("CMove accepted slices with different lengths")
+		}
+	}()
+	CMove(1, x, y[:2])
+}
+
+func TestCSwap(t *testing.T) {
+	x, y := []byte{1, 2, 3}, []byte{4, 5, 6}
+	CSwap(0, x, y)
+	if !Equal(x, []byte{1, 2, 3}) || !Equal(y, []byte{4, 5, 6}) {
+		t.Fatalf("CSwap(0, ...) changed the slices: %v, %v", x, y)
+	}
+	CSwap(1, x, y)
+	if !Equal(x, []byte{4, 5, 6}) || !Equal(y, []byte{1, 2, 3}) {
+		t.Fatalf("CSwap(1, ...) did not swap the slices: %v, %v", x, y)
+	}
+	CSwap(1, x, x)
+	if !Equal(x, []byte{4, 5, 6}) {
+		t.Fatalf("CSwap(1, x, x) changed x: %v", x)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("CSwap accepted slices with different lengths")
+		}
+	}()
+	CSwap(1, x, y[:2])
+}
+
+func TestErrors(t *testing.T) {
+	for _, v := range []struct {
+		err error
+		msg string
+	}{
+		{NonceSizeError(8), "invalid nonce size 8"},
+		{NonceSizeMismatchError{Size: 8, Expected: 12}, "invalid nonce size 8 - expected 12"},
+		{AuthenticationError{}, "authentication failed"},
+		{AuthenticationError{Algorithm: "EAX"}, "EAX: authentication failed"},
+	} {
+		if msg := v.err.Error(); msg != v.msg {
+			t.Fatalf("Error returned %q - but expected %q", msg, v.msg)
+		}
+	}
+
+	eax := fmt.Errorf("open: %w", AuthenticationError{Algorithm: "EAX"})
+	if !errors.Is(eax, AuthenticationError{}) {
+		t.Fatal("errors.Is does not match an AuthenticationError without algorithm")
+	}
+	if !errors.Is(eax, AuthenticationError{Algorithm: "EAX"}) {
+		t.Fatal("errors.Is does not match an AuthenticationError of the same algorithm")
+	}
+	if errors.Is(eax, AuthenticationError{Algorithm: "SIV"}) {
+		t.Fatal("errors.Is matches an AuthenticationError of a different algorithm")
+	}
+	if errors.Is(AuthenticationError{}, AuthenticationError{Algorithm: "EAX"}) {
+		t.Fatal("errors.Is matches an AuthenticationError without algorithm to a specific algorithm")
+	}
+	var authErr AuthenticationError
+	if !errors.As(eax, &authErr) || authErr.Algorithm != "EAX" {
+		t.Fatalf("errors.As returned %v - but expected the algorithm EAX", authErr)
+	}
+
+	nonce := fmt.Errorf("seal: %w", NonceSizeMismatchError{Size: 8, Expected: 12})
+	var mismatchErr NonceSizeMismatchError
+	if !errors.As(nonce, &mismatchErr) || mismatchErr.Size != 8 || mismatchErr.Expected != 12 {
+		t.Fatalf("errors.As returned %v - but expected sizes 8 and 12", mismatchErr)
+	}
+	var sizeErr NonceSizeError
+	if !errors.As(nonce, &sizeErr) || sizeErr != 8 {
+		t.Fatalf("errors.As returned %v - but expected the NonceSizeError 8", sizeErr)
+	}
+	if !errors.Is(nonce, NonceSizeError(8)) {
+		t.Fatal("errors.Is does not match the wrapped NonceSizeError")
+	}
+}
@@ -0,0 +1,168 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// Package cryptotest implements conformance tests for implementations
+// of the crypto interfaces - e.g. to check an AEAD created from a custom
+// block cipher. The tests only use the public interfaces and do not
+// depend on any specific algorithm.
+package cryptotest
+
+import (
+	"bytes"
+	"crypto/cipher"
+	"testing"
+)
+
+// The plaintext sizes tested by ConformanceTestAEAD
+var aeadSizes = []int{0, 1, 7, 8, 15, 16, 17, 31, 32, 33, 63, 64, 65, 127, 128, 129, 255, 256, 257, 1000, 16*1024 + 3}
+
+// ConformanceTestAEAD checks that the AEAD behaves like a cipher.AEAD:
+//   - Open reverses Seal for many plaintext and additional data sizes
+//   - the ciphertext is Overhead() bytes longer than the plaintext
+//   - Seal and Open append to dst and support in-place operation
+//   - nil and empty plaintexts and additional data are handled equally
+//   - modified ciphertexts, additional data and nonces are rejected
+//
+// The AEAD must be deterministic for a fixed nonce - like all AEADs
+// implementing cipher.AEAD. The modifications are only tested if the
+// Overhead is at least 8 bytes - shorter tags are forged by chance
+// too often.
+func ConformanceTestAEAD(t *testing.T, aead cipher.AEAD) {
+	nonce := make([]byte, aead.NonceSize())
+	for i := range nonce {
+		nonce[i] = byte(i + 1)
+	}
+	overhead := aead.Overhead()
+	if overhead < 0 {
+		t.Fatalf("Overhead returned %d", overhead)
+	}
+
+	for _, size := range aeadSizes {
+		plaintext := make([]byte, size)
+		for i := range plaintext {
+			plaintext[i] = byte(i * 7)
+		}
+		for _, adSize := range []int{0, 1, 16, 100} {
+			data := make([]byte, adSize)
+			for i := range data {
+				data[i] = byte(i * 3)
+			}
+			testRoundTrip(t, aead, nonce, plaintext, data)
+		}
+	}
+
+	testNilInputs(t, aead, nonce)
+	testInPlace(t, aead, nonce)
+	testTampering(t, aead, nonce)
+}
+
+func testRoundTrip(t *testing.T, aead cipher.AEAD, nonce, plaintext, data []byte) {
+	size, adSize := len(plaintext), len(data)
+	ciphertext := aead.Seal(nil, nonce, plaintext, data)
+	if n := len(plaintext) + aead.Overhead(); len(ciphertext) != n {
+		t.Fatalf("Size %d, AD size %d: Seal returned %d bytes - but expected %d", size, adSize, len(ciphertext), n)
+	}
+	if c := aead.Seal(nil, nonce, plaintext, data); !bytes.Equal(c, ciphertext) {
+		t.Fatalf("Size %d, AD size %d: Seal is not deterministic", size, adSize)
+	}
+	decrypted, err := aead.Open(nil, nonce, ciphertext, data)
+	if err != nil {
+		t.Fatalf("Size %d, AD size %d: Open failed: %s", size, adSize, err)
+	}
+	if !bytes.Equal(decrypted, plaintext) {
+		t.Fatalf("Size %d, AD size %d: Open returned a wrong plaintext", size, adSize)
+	}
+
+	// dst must be a prefix of the result
+	prefix := []byte("prefix")
+	dst := aead.Seal(append([]byte{}, prefix...), nonce, plaintext, data)
+	if !bytes.Equal(dst[:len(prefix)], prefix) || !bytes.Equal(dst[len(prefix):], ciphertext) {
+		t.Fatalf("Size %d, AD size %d: Seal does not append to dst", size, adSize)
+	}
+	dst, err = aead.Open(append([]byte{}, prefix...), nonce, ciphertext, data)
+	if err != nil {
+		t.Fatalf("Size %d, AD size %d: Open with dst failed: %s", size, adSize, err)
+	}
+	if !bytes.Equal(dst[:len(prefix)], prefix) || !bytes.Equal(dst[len(prefix):], plaintext) {
+		t.Fatalf("Size %d, AD size %d: Open does not append to dst", size, adSize)
+	}
+}
+
+func testNilInputs(t *testing.T, aead cipher.AEAD, nonce []byte) {
+	ciphertext := aead.Seal(nil, nonce, nil, nil)
+	if c := aead.Seal(nil, nonce, []byte{}, []byte{}); !bytes.Equal(c, ciphertext) {
+		t.Fatal("Seal handles nil and empty inputs differently")
+	}
+	plaintext, err := aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		t.Fatalf("Open failed for an empty plaintext: %s", err)
+	}
+	if len(plaintext) != 0 {
+		t.Fatalf("Open returned %d bytes for an empty plaintext", len(plaintext))
+	}
+	if _, err = aead.Open(nil, nonce, ciphertext, []byte{}); err != nil {
+		t.Fatalf("Open rejected empty instead of nil additional data: %s", err)
+	}
+}
+
+func testInPlace(t *testing.T, aead cipher.AEAD, nonce []byte) {
+	data := []byte("additional data")
+	for _, size := range []int{0, 1, 64, 1000} {
+		plaintext := make([]byte, size)
+		for i := range plaintext {
+			plaintext[i] = byte(i)
+		}
+		want := aead.Seal(nil, nonce, plaintext, data)
+
+		buf := append([]byte{}, plaintext...)
+		buf = append(buf, make([]byte, aead.Overhead())...)[:size]
+		ciphertext := aead.Seal(buf[:0], nonce, buf, data)
+		if !bytes.Equal(ciphertext, want) {
+			t.Fatalf("Size %d: in-place Seal returned a wrong ciphertext", size)
+		}
+		decrypted, err := aead.Open(ciphertext[:0], nonce, ciphertext, data)
+		if err != nil {
+			t.Fatalf("Size %d: in-place Open failed: %s", size, err)
+		}
+		if !bytes.Equal(decrypted, plaintext) {
+			t.Fatalf("Size %d: in-place Open returned a wrong plaintext", size)
+		}
+	}
+}
+
+func testTampering(t *testing.T, aead cipher.AEAD, nonce []byte) {
+	if aead.Overhead() < 8 {
+		return
+	}
+	plaintext, data := []byte("a message, which must not be modified"), []byte("data")
+	ciphertext := aead.Seal(nil, nonce, plaintext, data)
+
+	for i := range ciphertext {
+		modified := append([]byte{}, ciphertext...)
+		modified[i] ^= 0x80
+		if _, err := aead.Open(nil, nonce, modified, data); err == nil {
+			t.Fatalf("Open accepted a ciphertext modified at byte %d", i)
+		}
+	}
+	for i := 1; i <= aead.Overhead(); i++ {
+		if _, err := aead.Open(nil, nonce, ciphertext[:len(ciphertext)-i], data); err == nil {
+			t.Fatalf("Open accepted a ciphertext truncated by %d bytes", i)
+		}
+	}
+	if _, err := aead.Open(nil, nonce, append(ciphertext, 0), data); err == nil {
+		t.Fatal("Open accepted a ciphertext with an appended byte")
+	}
+	if _, err := aead.Open(nil, nonce, ciphertext, []byte("Data")); err == nil {
+		t.Fatal("Open accepted modified additional data")
+	}
+	if _, err := aead.Open(nil, nonce, ciphertext, nil); err == nil {
+		t.Fatal("Open accepted missing additional data")
+	}
+	for i := range nonce {
+		modified := append([]byte{}, nonce...)
+		modified[i] ^= 1
+		if _, err := aead.Open(nil, modified, ciphertext, data); err == nil {
+			t.Fatalf("Open accepted a nonce modified at byte %d", i)
+		}
+	}
+}
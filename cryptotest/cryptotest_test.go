@@ -0,0 +1,91 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cryptotest_test
+
+import (
+	"crypto/aes"
+	"crypto/des"
+	"crypto/sha256"
+	"hash"
+	"testing"
+
+	"github.com/enceve/crypto/blake2/blake2b"
+	"github.com/enceve/crypto/blake2/blake2s"
+	"github.com/enceve/crypto/chacha20"
+	"github.com/enceve/crypto/cipher"
+	"github.com/enceve/crypto/cmac"
+	"github.com/enceve/crypto/cryptotest"
+	"github.com/enceve/crypto/ghash"
+	"github.com/enceve/crypto/polyval"
+	"github.com/enceve/crypto/siphash"
+	"github.com/enceve/crypto/skein"
+	"github.com/enceve/crypto/skein/skein1024"
+	"github.com/enceve/crypto/skein/skein256"
+)
+
+func TestConformanceEAX(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	for _, size := range []int{1, 8, 16} {
+		c, err := cipher.NewEAX(block, size)
+		if err != nil {
+			t.Fatalf("Failed to create AES-128-EAX instance: %s", err)
+		}
+		cryptotest.ConformanceTestAEAD(t, c)
+	}
+}
+
+func TestConformanceChaCha20Poly1305(t *testing.T) {
+	var key [32]byte
+	cryptotest.ConformanceTestAEAD(t, chacha20.NewChaCha20Poly1305(&key))
+}
+
+// The poly1305.Hash does not implement hash.Hash, because a
+// Poly1305 key must not be used for more than one message.
+func TestConformanceHash(t *testing.T) {
+	aes128, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	tdes, err := des.NewTripleDESCipher(make([]byte, 24))
+	if err != nil {
+		t.Fatalf("Failed to create TDES instance: %s", err)
+	}
+	key := make([]byte, 32)
+	var sipKey [16]byte
+	var hashKey [16]byte
+	hashKey[0] = 1
+
+	for name, newHash := range map[string]func() (hash.Hash, error){
+		"SHA256":  func() (hash.Hash, error) { return sha256.New(), nil },
+		"BLAKE2b": func() (hash.Hash, error) { return blake2b.New(64, nil) },
+		"BLAKE2b-MAC": func() (hash.Hash, error) {
+			return blake2b.New(32, &blake2b.Config{Key: key, Salt: key[:16]})
+		},
+		"BLAKE2s":      func() (hash.Hash, error) { return blake2s.New(32, nil) },
+		"BLAKE2s-MAC":  func() (hash.Hash, error) { return blake2s.New(16, &blake2s.Config{Key: key}) },
+		"CMac-AES":     func() (hash.Hash, error) { return cmac.New(aes128) },
+		"CMac-TDES":    func() (hash.Hash, error) { return cmac.New(tdes) },
+		"CMac-Tagged":  func() (hash.Hash, error) { return cmac.NewTagged(aes128, 7) },
+		"GHASH":        func() (hash.Hash, error) { return ghash.New(hashKey), nil },
+		"POLYVAL":      func() (hash.Hash, error) { return polyval.New(hashKey), nil },
+		"SipHash":      func() (hash.Hash, error) { return siphash.New(&sipKey), nil },
+		"Skein512":     func() (hash.Hash, error) { return skein.New512(nil), nil },
+		"Skein256":     func() (hash.Hash, error) { return skein.New256(key), nil },
+		"Skein256-256": func() (hash.Hash, error) { return skein256.New256(nil), nil },
+		"Skein1024":    func() (hash.Hash, error) { return skein1024.New512(key), nil },
+	} {
+		if _, err := newHash(); err != nil {
+			t.Fatalf("%s: Failed to create instance: %s", name, err)
+		}
+		t.Run(name, func(t *testing.T) {
+			cryptotest.ConformanceTestHash(t, func() hash.Hash {
+				h, _ := newHash()
+				return h
+			})
+		})
+	}
+}
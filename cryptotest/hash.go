@@ -0,0 +1,145 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package cryptotest
+
+import (
+	"bytes"
+	"encoding"
+	"hash"
+	"testing"
+)
+
+// ConformanceTestHash checks that the hash.Hash instances returned by
+// newHash behave like a hash.Hash:
+//   - Size and BlockSize are positive and Sum appends Size() bytes
+//   - Write always processes all bytes and never returns an error
+//   - splitting the input into several writes does not change the sum
+//   - Sum does not change the state - so writing can continue after Sum
+//   - Reset restores the state of a new instance
+//   - if the hash.Hash implements encoding.BinaryMarshaler and
+//     encoding.BinaryUnmarshaler, a restored state continues the sum
+//
+// All instances returned by newHash must compute the same function -
+// e.g. a MAC must always use the same key.
+func ConformanceTestHash(t *testing.T, newHash func() hash.Hash) {
+	h := newHash()
+	size, blockSize := h.Size(), h.BlockSize()
+	if size <= 0 || blockSize <= 0 {
+		t.Fatalf("Size and BlockSize returned %d, %d", size, blockSize)
+	}
+	empty := h.Sum(nil)
+	if len(empty) != size {
+		t.Fatalf("Sum returned %d bytes - but Size is %d", len(empty), size)
+	}
+	if sum := newHash().Sum(nil); !bytes.Equal(sum, empty) {
+		t.Fatal("Two new instances return different sums")
+	}
+
+	msg := make([]byte, 4*blockSize+3)
+	for i := range msg {
+		msg[i] = byte(i * 5)
+	}
+	for _, n := range []int{0, 1, blockSize - 1, blockSize, blockSize + 1, 2 * blockSize, len(msg)} {
+		testHashWrites(t, newHash, msg[:n])
+	}
+	testHashSum(t, newHash, msg)
+	testHashReset(t, newHash, msg, empty)
+	testHashMarshal(t, newHash, msg)
+}
+
+func testHashWrites(t *testing.T, newHash func() hash.Hash, msg []byte) {
+	h := newHash()
+	if n, err := h.Write(msg); n != len(msg) || err != nil {
+		t.Fatalf("Size %d: Write returned %d, %v - but expected %d, nil", len(msg), n, err, len(msg))
+	}
+	want := h.Sum(nil)
+
+	// all splits into two writes and byte-wise writes
+	for i := 0; i <= len(msg); i++ {
+		h = newHash()
+		h.Write(msg[:i])
+		h.Write(msg[i:])
+		if sum := h.Sum(nil); !bytes.Equal(sum, want) {
+			t.Fatalf("Size %d: Sum of two writes split at %d differs", len(msg), i)
+		}
+	}
+	h = newHash()
+	for i := range msg {
+		h.Write(msg[i : i+1])
+	}
+	if sum := h.Sum(nil); !bytes.Equal(sum, want) {
+		t.Fatalf("Size %d: Sum of byte-wise writes differs", len(msg))
+	}
+}
+
+func testHashSum(t *testing.T, newHash func() hash.Hash, msg []byte) {
+	h := newHash()
+	h.Write(msg)
+	want := h.Sum(nil)
+
+	h = newHash()
+	half := len(msg) / 2
+	h.Write(msg[:half])
+	h.Sum(nil)
+	h.Write(msg[half:])
+	if sum := h.Sum(nil); !bytes.Equal(sum, want) {
+		t.Fatal("Sum changed the state of the hash")
+	}
+	if sum := h.Sum(nil); !bytes.Equal(sum, want) {
+		t.Fatal("Two calls of Sum return different sums")
+	}
+
+	prefix := []byte("prefix")
+	sum := h.Sum(append([]byte{}, prefix...))
+	if !bytes.Equal(sum[:len(prefix)], prefix) || !bytes.Equal(sum[len(prefix):], want) {
+		t.Fatal("Sum does not append to its argument")
+	}
+}
+
+func testHashReset(t *testing.T, newHash func() hash.Hash, msg, empty []byte) {
+	h := newHash()
+	h.Write(msg)
+	want := h.Sum(nil)
+
+	h.Reset()
+	if sum := h.Sum(nil); !bytes.Equal(sum, empty) {
+		t.Fatal("Reset does not restore the initial state")
+	}
+	h.Write(msg[:3])
+	h.Sum(nil)
+	h.Reset()
+	h.Write(msg)
+	if sum := h.Sum(nil); !bytes.Equal(sum, want) {
+		t.Fatal("Sum after Reset differs")
+	}
+}
+
+func testHashMarshal(t *testing.T, newHash func() hash.Hash, msg []byte) {
+	h := newHash()
+	if _, ok := h.(encoding.BinaryMarshaler); !ok {
+		return
+	}
+	if _, ok := newHash().(encoding.BinaryUnmarshaler); !ok {
+		t.Fatal("The hash implements encoding.BinaryMarshaler but not encoding.BinaryUnmarshaler")
+	}
+	h.Write(msg)
+	want := h.Sum(nil)
+
+	for _, i := range []int{0, 1, len(msg) / 2, len(msg)} {
+		h = newHash()
+		h.Write(msg[:i])
+		state, err := h.(encoding.BinaryMarshaler).MarshalBinary()
+		if err != nil {
+			t.Fatalf("Offset %d: MarshalBinary failed: %s", i, err)
+		}
+		restored := newHash()
+		if err = restored.(encoding.BinaryUnmarshaler).UnmarshalBinary(state); err != nil {
+			t.Fatalf("Offset %d: UnmarshalBinary failed: %s", i, err)
+		}
+		restored.Write(msg[i:])
+		if sum := restored.Sum(nil); !bytes.Equal(sum, want) {
+			t.Fatalf("Offset %d: Sum of the restored hash differs", i)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto
+
+// BestAEADFor exports bestAEAD, so the tests can select
+// both AEADs independent of the CPU.
+var BestAEADFor = bestAEAD
+
+// SelfTestNames returns the names of the registered self-tests.
+func SelfTestNames() []string {
+	selfTests.mu.Lock()
+	defer selfTests.mu.Unlock()
+	names := make([]string, len(selfTests.tests))
+	for i, t := range selfTests.tests {
+		names[i] = t.name
+	}
+	return names
+}
+
+// RunSelfTest runs the given self-test like SelfTest,
+// without registering it.
+func RunSelfTest(name string, test func() error) error {
+	return runSelfTests([]selfTest{{name: name, test: test}})
+}
+
+// RunNoSelfTests runs SelfTest as if no self-test was registered.
+func RunNoSelfTests() error { return runSelfTests(nil) }
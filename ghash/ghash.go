@@ -0,0 +1,110 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// Package ghash implements the GHASH universal hash function
+// used by the GCM mode of operation.
+// GHASH is specified in NIST SP 800-38D.
+//
+// GHASH is NOT a MAC on its own - the hash key H must be secret and
+// the output must be encrypted (e.g. GCM XORs it with a one-time pad)
+// to build an authenticator. A hash key must not be reused for messages
+// of an attacker's choice without such protection.
+package ghash
+
+import "github.com/enceve/crypto/internal/gf128"
+
+// The size of the GHASH checksum in bytes.
+const Size = 16
+
+// The block size of GHASH in bytes.
+const BlockSize = 16
+
+// GHASH computes the GHASH of data using the hash key h.
+// If the length of data is not a multiple of the block size
+// the last block is padded with zeros.
+func GHASH(h [16]byte, data []byte) [16]byte {
+	g := New(h)
+	g.Write(data)
+	var sum [16]byte
+	g.Sum(sum[:0])
+	return sum
+}
+
+// Hasher computes the GHASH incrementally. It implements hash.Hash.
+type Hasher struct {
+	h, y gf128.Element
+	buf  [BlockSize]byte
+	off  int
+}
+
+// New returns a new Hasher computing the GHASH using the hash key h.
+func New(h [16]byte) *Hasher {
+	return &Hasher{h: gf128.FromBytes(h[:])}
+}
+
+// Size returns the size of the GHASH checksum in bytes.
+func (g *Hasher) Size() int { return Size }
+
+// BlockSize returns the block size of GHASH in bytes.
+func (g *Hasher) BlockSize() int { return BlockSize }
+
+// Reset resets the Hasher to its initial state.
+// The hash key is not changed.
+func (g *Hasher) Reset() {
+	g.y = gf128.Element{}
+	g.off = 0
+}
+
+// Write processes the data. Subsequent writes are
+// concatenated - use Pad to separate inputs at block
+// boundaries. Write never returns an error.
+func (g *Hasher) Write(p []byte) (int, error) {
+	n := len(p)
+	if g.off > 0 {
+		k := copy(g.buf[g.off:], p)
+		g.off += k
+		p = p[k:]
+		if g.off < BlockSize {
+			return n, nil
+		}
+		g.update(g.buf[:])
+		g.off = 0
+	}
+	for len(p) >= BlockSize {
+		g.update(p[:BlockSize])
+		p = p[BlockSize:]
+	}
+	if len(p) > 0 {
+		g.off = copy(g.buf[:], p)
+	}
+	return n, nil
+}
+
+// Pad pads the data written so far with zeros to a multiple
+// of the block size. E.g. GCM pads the additional data and the
+// ciphertext separately.
+func (g *Hasher) Pad() {
+	if g.off > 0 {
+		for i := g.off; i < BlockSize; i++ {
+			g.buf[i] = 0
+		}
+		g.update(g.buf[:])
+		g.off = 0
+	}
+}
+
+// Sum appends the GHASH of the data written so far (zero padded
+// to a multiple of the block size) to b. Sum does not change
+// the state of the Hasher.
+func (g *Hasher) Sum(b []byte) []byte {
+	y := *g
+	y.Pad()
+	var sum [Size]byte
+	y.y.Put(sum[:])
+	return append(b, sum[:]...)
+}
+
+// update processes one block: y = (y + block) * h
+func (g *Hasher) update(block []byte) {
+	g.y = gf128.Mul(gf128.Add(g.y, gf128.FromBytes(block)), g.h)
+}
@@ -0,0 +1,165 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package ghash
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/binary"
+	"hash"
+	"testing"
+
+	"github.com/enceve/crypto/internal/testvec"
+)
+
+var _ hash.Hash = (*Hasher)(nil)
+
+// Test vectors from the GCM specification (test cases 2, 3 and 4):
+// http://csrc.nist.gov/groups/ST/toolkit/BCM/documents/proposedmodes/gcm/gcm-revised-spec.pdf
+// The input is A || pad || C || pad || len(A) || len(C).
+var testVectors = []struct {
+	h, data, ciphertext, ghash string
+}{
+	{
+		h:          "66e94bd4ef8a2c3b884cfa59ca342b2e",
+		data:       "",
+		ciphertext: "0388dace60b6a392f328c2b971b2fe78",
+		ghash:      "f38cbb1ad69223dcc3457ae5b6b0f885",
+	},
+	{
+		h:    "b83b533708bf535d0aa6e52980d53b78",
+		data: "",
+		ciphertext: "42831ec2217774244b7221b784d0d49ce3aa212f2c02a4e035c17e2329aca12e" +
+			"21d514b25466931c7d8f6a5aac84aa051ba30b396a0aac973d58e091473f5985",
+		ghash: "7f1b32b81b820d02614f8895ac1d4eac",
+	},
+	{
+		h:    "b83b533708bf535d0aa6e52980d53b78",
+		data: "feedfacedeadbeeffeedfacedeadbeefabaddad2",
+		ciphertext: "42831ec2217774244b7221b784d0d49ce3aa212f2c02a4e035c17e2329aca12e" +
+			"21d514b25466931c7d8f6a5aac84aa051ba30b396a0aac973d58e091",
+		ghash: "698e57f70e6ecc7fd9463b7260a9ae5f",
+	},
+}
+
+func lengthBlock(data, ciphertext []byte) []byte {
+	var l [16]byte
+	binary.BigEndian.PutUint64(l[:], uint64(len(data))*8)
+	binary.BigEndian.PutUint64(l[8:], uint64(len(ciphertext))*8)
+	return l[:]
+}
+
+func pad(b []byte) []byte {
+	if r := len(b) % BlockSize; r > 0 {
+		b = append(b, make([]byte, BlockSize-r)...)
+	}
+	return b
+}
+
+func TestVectors(t *testing.T) {
+	for i, v := range testVectors {
+		var h [16]byte
+		copy(h[:], testvec.MustDecodeHex(v.h))
+		data, ciphertext, expected := testvec.MustDecodeHex(v.data), testvec.MustDecodeHex(v.ciphertext), testvec.MustDecodeHex(v.ghash)
+
+		// one-shot
+		in := append(pad(append([]byte{}, data...)), pad(append([]byte{}, ciphertext...))...)
+		in = append(in, lengthBlock(data, ciphertext)...)
+		if sum := GHASH(h, in); !bytes.Equal(sum[:], expected) {
+			t.Fatalf("Test vector %d: GHASH returned %x - but expected %x", i, sum, expected)
+		}
+
+		// incremental with Pad
+		g := New(h)
+		for _, b := range data {
+			g.Write([]byte{b})
+		}
+		g.Pad()
+		g.Write(ciphertext)
+		g.Pad()
+		g.Write(lengthBlock(data, ciphertext))
+		if sum := g.Sum(nil); !bytes.Equal(sum, expected) {
+			t.Fatalf("Test vector %d: Hasher returned %x - but expected %x", i, sum, expected)
+		}
+	}
+}
+
+// GHASH must be equal to the GHASH computed by crypto/cipher GCM,
+// which is the tag XOR E(K, J0).
+func TestGCM(t *testing.T) {
+	key := make([]byte, 16)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create AES-128-GCM instance: %s", err)
+	}
+	var h [16]byte
+	block.Encrypt(h[:], h[:])
+
+	nonce := make([]byte, gcm.NonceSize())
+	j0 := make([]byte, 16)
+	j0[15] = 1
+	block.Encrypt(j0, j0)
+
+	msg := make([]byte, 100)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	for _, n := range []int{0, 1, 15, 16, 17, 31, 64, 100} {
+		data := msg[:n/2]
+		out := gcm.Seal(nil, nonce, msg[:n], data)
+		ciphertext, tag := out[:n], out[n:]
+
+		g := New(h)
+		g.Write(data)
+		g.Pad()
+		g.Write(ciphertext)
+		g.Pad()
+		g.Write(lengthBlock(data, ciphertext))
+		sum := g.Sum(nil)
+		for i := range sum {
+			sum[i] ^= j0[i]
+		}
+		if !bytes.Equal(sum, tag) {
+			t.Fatalf("Length %d: GHASH differ from GCM", n)
+		}
+	}
+}
+
+func TestHasher(t *testing.T) {
+	var h [16]byte
+	copy(h[:], testvec.MustDecodeHex("66e94bd4ef8a2c3b884cfa59ca342b2e"))
+	msg := make([]byte, 100)
+	for i := range msg {
+		msg[i] = byte(i * 3)
+	}
+	expected := GHASH(h, msg)
+
+	g := New(h)
+	g.Write(msg[:7])
+	if sum := g.Sum(nil); sum == nil {
+		t.Fatal("Sum returned nil")
+	}
+	g.Write(msg[7:40])
+	g.Write(msg[40:])
+	if sum := g.Sum(nil); !bytes.Equal(sum, expected[:]) {
+		t.Fatalf("Hasher returned %x - but expected %x", sum, expected)
+	}
+
+	g.Reset()
+	g.Write(msg)
+	if sum := g.Sum(nil); !bytes.Equal(sum, expected[:]) {
+		t.Fatalf("Hasher returned %x after Reset - but expected %x", sum, expected)
+	}
+	if sum := GHASH(h, nil); sum != [16]byte{} {
+		t.Fatalf("GHASH of the empty string is %x", sum)
+	}
+}
@@ -0,0 +1,30 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package hc128
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+func init() {
+	crypto.RegisterSelfTest("HC-128", selfTest)
+}
+
+// selfTest checks the HC-128 keystream of the zero key and nonce
+// against the test vector of the HC-128 description by Hongjun Wu.
+func selfTest() error {
+	keystream, _ := hex.DecodeString("82001573a003fd3b7fd72ffb0eaf63aac62f12deb629dca72785a66268ec758b")
+
+	var key, nonce [16]byte
+	buf := make([]byte, len(keystream))
+	NewCipher(&nonce, &key).XORKeyStream(buf, buf)
+	if !bytes.Equal(buf, keystream) {
+		return errors.New("unexpected keystream")
+	}
+	return nil
+}
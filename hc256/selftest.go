@@ -0,0 +1,30 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package hc256
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+func init() {
+	crypto.RegisterSelfTest("HC-256", selfTest)
+}
+
+// selfTest checks the HC-256 keystream of the zero key and nonce
+// against the test vector of the HC-256 description by Hongjun Wu.
+func selfTest() error {
+	keystream, _ := hex.DecodeString("5b078985d8f6f30d42c5c02fa6b6795153f06534801f89f24e74248b720b4818")
+
+	var key, nonce [32]byte
+	buf := make([]byte, len(keystream))
+	NewCipher(&nonce, &key).XORKeyStream(buf, buf)
+	if !bytes.Equal(buf, keystream) {
+		return errors.New("unexpected keystream")
+	}
+	return nil
+}
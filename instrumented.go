@@ -0,0 +1,48 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto
+
+import "crypto/cipher"
+
+// NewInstrumentedAEAD returns a cipher.AEAD wrapping the given AEAD, which
+// invokes the callbacks after every Seal and Open call - e.g. to count the
+// operations and bytes for monitoring. The callbacks receive the number of
+// processed input bytes (the plaintext for Seal, the ciphertext including
+// the tag for Open) and the error of the operation. Seal never fails, so
+// onSeal always receives a nil error.
+// The callbacks are called synchronously, so they should be cheap. A nil
+// callback is ignored. The outputs of the wrapped AEAD are not changed.
+func NewInstrumentedAEAD(inner cipher.AEAD, onSeal, onOpen func(n int, err error)) cipher.AEAD {
+	return &instrumentedAEAD{
+		aead:   inner,
+		onSeal: onSeal,
+		onOpen: onOpen,
+	}
+}
+
+// The instrumented AEAD wrapper
+type instrumentedAEAD struct {
+	aead           cipher.AEAD
+	onSeal, onOpen func(n int, err error)
+}
+
+func (c *instrumentedAEAD) NonceSize() int { return c.aead.NonceSize() }
+
+func (c *instrumentedAEAD) Overhead() int { return c.aead.Overhead() }
+
+func (c *instrumentedAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	ciphertext := c.aead.Seal(dst, nonce, plaintext, additionalData)
+	if c.onSeal != nil {
+		c.onSeal(len(plaintext), nil)
+	}
+	return ciphertext
+}
+
+func (c *instrumentedAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	plaintext, err := c.aead.Open(dst, nonce, ciphertext, additionalData)
+	if c.onOpen != nil {
+		c.onOpen(len(ciphertext), err)
+	}
+	return plaintext, err
+}
@@ -0,0 +1,80 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"testing"
+
+	"github.com/enceve/crypto"
+)
+
+func TestInstrumentedAEAD(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	gcm, err := gocipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create AES-GCM instance: %s", err)
+	}
+
+	var sealed, opened, failed, ops int
+	aead := crypto.NewInstrumentedAEAD(gcm,
+		func(n int, err error) {
+			if err != nil {
+				t.Fatalf("onSeal received error: %s", err)
+			}
+			sealed += n
+			ops++
+		},
+		func(n int, err error) {
+			if err != nil {
+				failed++
+			}
+			opened += n
+			ops++
+		},
+	)
+	if aead.NonceSize() != gcm.NonceSize() || aead.Overhead() != gcm.Overhead() {
+		t.Fatal("NonceSize or Overhead differ from the wrapped AEAD")
+	}
+
+	nonce, msg, data := make([]byte, aead.NonceSize()), []byte("instrumented message"), []byte("data")
+	ciphertext := aead.Seal(nil, nonce, msg, data)
+	if !bytes.Equal(ciphertext, gcm.Seal(nil, nonce, msg, data)) {
+		t.Fatal("Seal differs from the wrapped AEAD")
+	}
+	if sealed != len(msg) || ops != 1 {
+		t.Fatalf("onSeal counted %d bytes in %d calls - but expected %d bytes in 1 call", sealed, ops, len(msg))
+	}
+
+	plaintext, err := aead.Open(nil, nonce, ciphertext, data)
+	if err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	if !bytes.Equal(plaintext, msg) {
+		t.Fatalf("Open returned %q - but expected %q", plaintext, msg)
+	}
+	if opened != len(ciphertext) || failed != 0 || ops != 2 {
+		t.Fatalf("onOpen counted %d bytes and %d errors - but expected %d bytes and no errors", opened, failed, len(ciphertext))
+	}
+
+	ciphertext[0] ^= 1
+	if _, err = aead.Open(nil, nonce, ciphertext, data); err == nil {
+		t.Fatal("Open accepted a modified ciphertext")
+	}
+	if failed != 1 || ops != 3 {
+		t.Fatalf("onOpen counted %d errors in %d calls - but expected 1 error in 3 calls", failed, ops)
+	}
+
+	// nil callbacks are ignored
+	aead = crypto.NewInstrumentedAEAD(gcm, nil, nil)
+	ciphertext = aead.Seal(nil, nonce, msg, data)
+	if _, err = aead.Open(nil, nonce, ciphertext, data); err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+}
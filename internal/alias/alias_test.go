@@ -0,0 +1,44 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package alias
+
+import "testing"
+
+var a, b [100]byte
+
+var aliasingTests = []struct {
+	x, y                       []byte
+	anyOverlap, inexactOverlap bool
+}{
+	{a[:], b[:], false, false},
+	{a[:], b[:0], false, false},
+	{a[:], b[:50], false, false},
+	{a[40:50], a[50:60], false, false},
+	{a[40:50], a[60:70], false, false},
+	{a[:51], a[50:], true, true},
+	{a[:], a[:], true, false},
+	{a[:50], a[:60], true, false},
+	{a[:], nil, false, false},
+	{nil, nil, false, false},
+	{a[:], a[:0], false, false},
+	{a[:10], a[:10:20], true, false},
+	{a[:10], a[5:10:20], true, true},
+}
+
+func TestAliasing(t *testing.T) {
+	for i, tt := range aliasingTests {
+		if overlap := AnyOverlap(tt.x, tt.y); overlap != tt.anyOverlap {
+			t.Fatalf("Test %d: AnyOverlap returned %v - but expected %v", i, overlap, tt.anyOverlap)
+		}
+		if overlap := AnyOverlap(tt.y, tt.x); overlap != tt.anyOverlap {
+			t.Fatalf("Test %d: AnyOverlap (swapped) returned %v - but expected %v", i, overlap, tt.anyOverlap)
+		}
+		if inexact := InexactOverlap(tt.x, tt.y); inexact != tt.inexactOverlap {
+			t.Fatalf("Test %d: InexactOverlap returned %v - but expected %v", i, inexact, tt.inexactOverlap)
+		}
+		if inexact := InexactOverlap(tt.y, tt.x); inexact != tt.inexactOverlap {
+			t.Fatalf("Test %d: InexactOverlap (swapped) returned %v - but expected %v", i, inexact, tt.inexactOverlap)
+		}
+	}
+}
@@ -0,0 +1,21 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// Package cpu provides the CPU feature probes used to select
+// an implementation at runtime.
+package cpu
+
+import "golang.org/x/sys/cpu"
+
+// HasAES is true if the CPU implements AES in hardware
+// (e.g. AES-NI on x86). Without hardware support AES is
+// slow and not safe against cache-timing attacks.
+var HasAES = cpu.X86.HasAES || cpu.ARM64.HasAES || cpu.S390X.HasAES
+
+// HasPCLMULQDQ is true if the CPU implements the carry-less
+// multiplication of the PCLMULQDQ instruction (x86).
+var HasPCLMULQDQ = cpu.X86.HasPCLMULQDQ
+
+// HasSSE2 is true if the CPU implements the SSE2
+// vector instructions (x86).
+var HasSSE2 = cpu.X86.HasSSE2
@@ -0,0 +1,90 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// Package gf128 implements arithmetic in the finite field GF(2^128)
+// shared by the universal hash functions and block cipher modes of
+// this repository.
+package gf128
+
+import "encoding/binary"
+
+// Element is an element of GF(2^128) using the bit order of GCM:
+// the most significant bit of the first byte is the coefficient of x^0
+// and the least significant bit of the last byte is the coefficient of
+// x^127. The field polynomial is x^128 + x^7 + x^2 + x + 1.
+type Element struct {
+	Hi, Lo uint64
+}
+
+// FromBytes returns the element encoded by the first 16 bytes of b.
+func FromBytes(b []byte) Element {
+	return Element{
+		Hi: binary.BigEndian.Uint64(b),
+		Lo: binary.BigEndian.Uint64(b[8:]),
+	}
+}
+
+// Put writes the 16 byte encoding of e into b.
+func (e Element) Put(b []byte) {
+	binary.BigEndian.PutUint64(b, e.Hi)
+	binary.BigEndian.PutUint64(b[8:], e.Lo)
+}
+
+// Add returns x + y (which is x XOR y).
+func Add(x, y Element) Element {
+	return Element{Hi: x.Hi ^ y.Hi, Lo: x.Lo ^ y.Lo}
+}
+
+// Mul returns x * y. The execution time of Mul does not
+// depend on the values of x and y.
+func Mul(x, y Element) Element {
+	var z Element
+	v := y
+	for i := 0; i < 128; i++ {
+		// bit i of x (x^i coefficient)
+		var bit uint64
+		if i < 64 {
+			bit = (x.Hi >> uint(63-i)) & 1
+		} else {
+			bit = (x.Lo >> uint(127-i)) & 1
+		}
+		mask := -bit
+		z.Hi ^= v.Hi & mask
+		z.Lo ^= v.Lo & mask
+
+		v = MulX(v)
+	}
+	return z
+}
+
+// MulX returns e * x. The execution time of MulX does
+// not depend on the value of e.
+func MulX(e Element) Element {
+	reduce := -(e.Lo & 1)
+	return Element{
+		Hi: (e.Hi >> 1) ^ (0xe100000000000000 & reduce),
+		Lo: (e.Lo >> 1) | (e.Hi << 63),
+	}
+}
+
+// Double multiplies the 16 byte block b by x in place (the dbl function
+// of CMac, SIV and OCB). Unlike Element, Double uses the big-endian bit
+// order of these modes: b is shifted left by one bit and the constant
+// 0x87 is XORed into the last byte if the most significant bit was set.
+// The execution time of Double does not depend on the value of b.
+func Double(b []byte) {
+	hi, lo := binary.BigEndian.Uint64(b), binary.BigEndian.Uint64(b[8:16])
+	reduce := -(hi >> 63)
+	binary.BigEndian.PutUint64(b, hi<<1|lo>>63)
+	binary.BigEndian.PutUint64(b[8:], lo<<1^(0x87&reduce))
+}
+
+// Double64 multiplies the 8 byte block b by x in GF(2^64) in place
+// like Double, but using the constant 0x1b of 64 bit block ciphers
+// (e.g. for CMac with TDES).
+// The execution time of Double64 does not depend on the value of b.
+func Double64(b []byte) {
+	v := binary.BigEndian.Uint64(b[:8])
+	reduce := -(v >> 63)
+	binary.BigEndian.PutUint64(b, v<<1^(0x1b&reduce))
+}
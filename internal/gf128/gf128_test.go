@@ -0,0 +1,118 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package gf128
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/enceve/crypto/internal/testvec"
+)
+
+func fromHex(s string) Element {
+	return FromBytes(testvec.MustDecodeHex(s))
+}
+
+// Test vectors for the multiplication from the GCM specification
+// (test case 2): X1 = C * H
+var mulVectors = []struct {
+	x, y, z string
+}{
+	{
+		x: "0388dace60b6a392f328c2b971b2fe78",
+		y: "66e94bd4ef8a2c3b884cfa59ca342b2e",
+		z: "5e2ec746917062882c85b0685353deb7",
+	},
+}
+
+func TestMul(t *testing.T) {
+	for i, v := range mulVectors {
+		x, y, z := fromHex(v.x), fromHex(v.y), fromHex(v.z)
+		if r := Mul(x, y); r != z {
+			t.Fatalf("Test vector %d: Mul returned %016x%016x - but expected %s", i, r.Hi, r.Lo, v.z)
+		}
+		if r := Mul(y, x); r != z {
+			t.Fatalf("Test vector %d: Mul is not commutative", i)
+		}
+	}
+
+	one := Element{Hi: 1 << 63}
+	x := fromHex("0388dace60b6a392f328c2b971b2fe78")
+	if r := Mul(x, one); r != x {
+		t.Fatal("x * 1 != x")
+	}
+	if r := Mul(x, Element{}); r != (Element{}) {
+		t.Fatal("x * 0 != 0")
+	}
+
+	// distributivity: x * (y + z) = x * y + x * z
+	y, z := fromHex("66e94bd4ef8a2c3b884cfa59ca342b2e"), fromHex("b83b533708bf535d0aa6e52980d53b78")
+	if Mul(x, Add(y, z)) != Add(Mul(x, y), Mul(x, z)) {
+		t.Fatal("Mul is not distributive")
+	}
+}
+
+func TestPut(t *testing.T) {
+	b := make([]byte, 16)
+	e := fromHex("000102030405060708090a0b0c0d0e0f")
+	e.Put(b)
+	if s := hex.EncodeToString(b); s != "000102030405060708090a0b0c0d0e0f" {
+		t.Fatalf("Put returned %s", s)
+	}
+}
+
+func TestMulX(t *testing.T) {
+	// x is the element with the second most significant bit set
+	xElem := Element{Hi: 1 << 62}
+	for _, v := range []string{
+		"0388dace60b6a392f328c2b971b2fe78",
+		"66e94bd4ef8a2c3b884cfa59ca342b2e",
+		"00000000000000000000000000000001", // x^127 * x is reduced
+	} {
+		e := fromHex(v)
+		if r := MulX(e); r != Mul(e, xElem) {
+			t.Fatalf("MulX(%s) returned %016x%016x - but expected %016x%016x", v, r.Hi, r.Lo, Mul(e, xElem).Hi, Mul(e, xElem).Lo)
+		}
+	}
+}
+
+// Test vectors from RFC 4493 (section 4) and NIST SP 800-38B (D.4):
+// the CMac subkeys K1 = L * x and K2 = K1 * x.
+var doubleVectors = []struct {
+	l, k1, k2 string
+}{
+	{ // AES-128
+		l:  "7df76b0c1ab899b33e42f047b91b546f",
+		k1: "fbeed618357133667c85e08f7236a8de",
+		k2: "f7ddac306ae266ccf90bc11ee46d513b",
+	},
+	{ // TDES (three key)
+		l:  "c8cc74e98a7329a2",
+		k1: "9198e9d314e6535f",
+		k2: "2331d3a629cca6a5",
+	},
+	{ // x^127 * x is reduced
+		l:  "80000000000000000000000000000000",
+		k1: "00000000000000000000000000000087",
+		k2: "0000000000000000000000000000010e",
+	},
+}
+
+func TestDouble(t *testing.T) {
+	for i, v := range doubleVectors {
+		b := testvec.MustDecodeHex(v.l)
+		double := Double
+		if len(b) == 8 {
+			double = Double64
+		}
+		double(b)
+		if s := hex.EncodeToString(b); s != v.k1 {
+			t.Fatalf("Test vector %d: K1 is %s - but expected %s", i, s, v.k1)
+		}
+		double(b)
+		if s := hex.EncodeToString(b); s != v.k2 {
+			t.Fatalf("Test vector %d: K2 is %s - but expected %s", i, s, v.k2)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// Package testvec implements helpers shared by the
+// tests of the algorithms - e.g. parsing of test vectors.
+package testvec
+
+import (
+	"bufio"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// MustDecodeHex returns the bytes represented by the hex string s.
+// Whitespace within s is ignored, so long values can be split across
+// lines. If s is not a valid hex string this function panics.
+func MustDecodeHex(s string) []byte {
+	b, err := hex.DecodeString(strings.Join(strings.Fields(s), ""))
+	if err != nil {
+		panic("testvec: invalid hex string: " + err.Error())
+	}
+	return b
+}
+
+// EqualHex returns true if and only if b is equal to the bytes
+// represented by the hex string s. The comparison is done in
+// constant time. If s is not a valid hex string this function panics.
+func EqualHex(b []byte, s string) bool {
+	return subtle.ConstantTimeCompare(b, MustDecodeHex(s)) == 1
+}
+
+// Vector is one test vector - a set of named values.
+type Vector map[string]string
+
+// Hex returns the value of the given name decoded as hex string.
+// If the vector has no value of this name or the value is not a
+// valid hex string this function panics.
+func (v Vector) Hex(name string) []byte {
+	s, ok := v[name]
+	if !ok {
+		panic("testvec: test vector has no value " + name)
+	}
+	return MustDecodeHex(s)
+}
+
+// Int returns the value of the given name as decimal integer.
+// If the vector has no value of this name or the value is not a
+// valid integer this function panics.
+func (v Vector) Int(name string) int {
+	s, ok := v[name]
+	if !ok {
+		panic("testvec: test vector has no value " + name)
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		panic("testvec: invalid integer: " + err.Error())
+	}
+	return n
+}
+
+// ParseVectors parses test vectors in the format of the NIST CAVP
+// response files:
+//
+//	# comment
+//	Key = 2b7e151628aed2a6abf7158809cf4f3c
+//	Msg =
+//	Mac = bb1d6929e95937287fa37d129b756746
+//
+// Every line contains one "name = value" pair and the vectors are
+// separated by empty lines. Lines starting with '#' or '[' are ignored.
+// ParseVectors returns a non-nil error if a line is malformed or a name
+// is defined twice within one vector.
+func ParseVectors(r io.Reader) ([]Vector, error) {
+	var vectors []Vector
+	var vector Vector
+
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		s := strings.TrimSpace(scanner.Text())
+		if s == "" {
+			if vector != nil {
+				vectors = append(vectors, vector)
+				vector = nil
+			}
+			continue
+		}
+		if s[0] == '#' || s[0] == '[' {
+			continue
+		}
+
+		i := strings.IndexByte(s, '=')
+		if i < 0 {
+			return nil, errors.New("testvec: line " + strconv.Itoa(line) + ": missing '='")
+		}
+		name, value := strings.TrimSpace(s[:i]), strings.TrimSpace(s[i+1:])
+		if name == "" {
+			return nil, errors.New("testvec: line " + strconv.Itoa(line) + ": missing name")
+		}
+		if vector == nil {
+			vector = make(Vector)
+		}
+		if _, ok := vector[name]; ok {
+			return nil, errors.New("testvec: line " + strconv.Itoa(line) + ": " + name + " is already defined")
+		}
+		vector[name] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if vector != nil {
+		vectors = append(vectors, vector)
+	}
+	return vectors, nil
+}
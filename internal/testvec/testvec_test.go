@@ -0,0 +1,100 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package testvec
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func mustPanic(t *testing.T, msg string, f func()) {
+	defer func() {
+		if recover() == nil {
+			t.Fatalf("Expected panic: %s", msg)
+		}
+	}()
+	f()
+}
+
+func TestMustDecodeHex(t *testing.T) {
+	for _, v := range []struct {
+		s        string
+		expected []byte
+	}{
+		{"", []byte{}},
+		{"00ff", []byte{0x00, 0xff}},
+		{"00FF10", []byte{0x00, 0xff, 0x10}},
+		{"0001 0203\n\t0405", []byte{0, 1, 2, 3, 4, 5}},
+	} {
+		if b := MustDecodeHex(v.s); !bytes.Equal(b, v.expected) {
+			t.Fatalf("MustDecodeHex(%q) returned %x - but expected %x", v.s, b, v.expected)
+		}
+	}
+	mustPanic(t, "odd length", func() { MustDecodeHex("abc") })
+	mustPanic(t, "invalid character", func() { MustDecodeHex("zz") })
+}
+
+func TestEqualHex(t *testing.T) {
+	if !EqualHex([]byte{0xde, 0xad}, "DEAD") {
+		t.Fatal("EqualHex returned false for equal values")
+	}
+	if EqualHex([]byte{0xde, 0xad}, "deae") || EqualHex([]byte{0xde}, "dead") || EqualHex(nil, "00") {
+		t.Fatal("EqualHex returned true for different values")
+	}
+	if !EqualHex(nil, "") {
+		t.Fatal("EqualHex returned false for empty values")
+	}
+	mustPanic(t, "invalid hex string", func() { EqualHex(nil, "x") })
+}
+
+const testVectors = `
+# CMAC-AES-128 from NIST SP 800-38B
+[Klen = 16]
+
+Count = 0
+Key = 2b7e151628aed2a6abf7158809cf4f3c
+Msg =
+Mac = bb1d6929e95937287fa37d129b756746
+
+Count = 1
+Key = 2b7e151628aed2a6abf7158809cf4f3c
+Msg = 6bc1bee22e409f96e93d7e117393172a
+Mac = 070a16b46b4d4144f79bdd9dd04a287c
+`
+
+func TestParseVectors(t *testing.T) {
+	vectors, err := ParseVectors(strings.NewReader(testVectors))
+	if err != nil {
+		t.Fatalf("ParseVectors failed: %s", err)
+	}
+	if len(vectors) != 2 {
+		t.Fatalf("ParseVectors returned %d vectors - but expected 2", len(vectors))
+	}
+	for i, v := range vectors {
+		if n := v.Int("Count"); n != i {
+			t.Fatalf("Vector %d: Count is %d", i, n)
+		}
+		if !EqualHex(v.Hex("Key"), "2b7e151628aed2a6abf7158809cf4f3c") {
+			t.Fatalf("Vector %d: unexpected key %s", i, v["Key"])
+		}
+	}
+	if msg := vectors[0].Hex("Msg"); len(msg) != 0 {
+		t.Fatalf("Vector 0: Msg is %x - but expected an empty value", msg)
+	}
+	if !EqualHex(vectors[1].Hex("Mac"), "070a16b46b4d4144f79bdd9dd04a287c") {
+		t.Fatalf("Vector 1: unexpected mac %s", vectors[1]["Mac"])
+	}
+	mustPanic(t, "missing value", func() { vectors[0].Hex("Nonce") })
+	mustPanic(t, "invalid integer", func() { vectors[0].Int("Key") })
+
+	for _, s := range []string{"Key 00", "= 00", "Key = 00\nKey = 01"} {
+		if _, err := ParseVectors(strings.NewReader(s)); err == nil {
+			t.Fatalf("ParseVectors accepted the malformed input %q", s)
+		}
+	}
+	if vectors, err = ParseVectors(strings.NewReader("")); err != nil || len(vectors) != 0 {
+		t.Fatalf("ParseVectors returned %v, %v for an empty input", vectors, err)
+	}
+}
@@ -0,0 +1,95 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto
+
+import (
+	"crypto/rand"
+	"io"
+	"sort"
+)
+
+// The key sizes of the algorithms supported by GenerateKey. The names of the
+// AEADs are the names of the DefaultRegistry. The AES based AEADs support
+// several key sizes - GenerateKey returns AES-256 keys for them.
+var keySizes = map[string]int{
+	"AES-128":            16,
+	"AES-192":            24,
+	"AES-256":            32,
+	"AES-GCM":            32,
+	"AES-EAX":            32,
+	"AES-SIV":            64, // AES-256-SIV
+	"ChaCha20":           32,
+	"ChaCha20-Poly1305":  32,
+	"XChaCha20-Poly1305": 32,
+	"Poly1305":           32,
+	"SipHash":            16,
+}
+
+// An UnknownAlgorithmError indicates, that GenerateKey
+// does not know the key size of an algorithm.
+type UnknownAlgorithmError string
+
+func (u UnknownAlgorithmError) Error() string {
+	return "unknown algorithm " + string(u)
+}
+
+// KeyAlgorithms returns the sorted names of the algorithms
+// supported by GenerateKey.
+func KeyAlgorithms() []string {
+	names := make([]string, 0, len(keySizes))
+	for name := range keySizes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// GenerateKey returns a new key of the correct size for the named algorithm
+// (see KeyAlgorithms) read from rand. The key sizes of the AEADs match the
+// DefaultRegistry - e.g. a key for "ChaCha20-Poly1305" can be passed to
+// DefaultRegistry.New(ChaCha20Poly1305, key). If rand is nil crypto/rand.Reader
+// is used. GenerateKey returns an UnknownAlgorithmError for unknown algorithms
+// and the error of rand if the key cannot be read.
+func GenerateKey(rand io.Reader, algo string) ([]byte, error) {
+	size, ok := keySizes[algo]
+	if !ok {
+		return nil, UnknownAlgorithmError(algo)
+	}
+	key := make([]byte, size)
+	if err := readRandom(rand, key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GenerateChaChaKey returns a new 256 bit key for ChaCha20 and
+// ChaCha20-Poly1305 read from rand. If rand is nil crypto/rand.Reader
+// is used. GenerateChaChaKey returns the error of rand if the key cannot
+// be read.
+func GenerateChaChaKey(rand io.Reader) (*[32]byte, error) {
+	key := new([32]byte)
+	if err := readRandom(rand, key[:]); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// GenerateSipHashKey returns a new 128 bit key for SipHash read from
+// rand. If rand is nil crypto/rand.Reader is used. GenerateSipHashKey
+// returns the error of rand if the key cannot be read.
+func GenerateSipHashKey(rand io.Reader) (*[16]byte, error) {
+	key := new([16]byte)
+	if err := readRandom(rand, key[:]); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+func readRandom(r io.Reader, b []byte) error {
+	if r == nil {
+		r = rand.Reader
+	}
+	_, err := io.ReadFull(r, b)
+	return err
+}
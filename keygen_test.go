@@ -0,0 +1,101 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto_test
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/enceve/crypto"
+)
+
+// countingReader returns the bytes 1, 2, 3, ... and counts them.
+type countingReader struct{ n int }
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	for i := range p {
+		r.n++
+		p[i] = byte(r.n)
+	}
+	return len(p), nil
+}
+
+func TestGenerateKey(t *testing.T) {
+	for _, v := range []struct {
+		algo string
+		size int
+	}{
+		{"AES-128", 16}, {"AES-GCM", 32}, {"AES-EAX", 32}, {"AES-SIV", 64},
+		{"ChaCha20-Poly1305", 32}, {"XChaCha20-Poly1305", 32}, {"SipHash", 16},
+	} {
+		r := new(countingReader)
+		key, err := crypto.GenerateKey(r, v.algo)
+		if err != nil {
+			t.Fatalf("%s: GenerateKey failed: %s", v.algo, err)
+		}
+		if len(key) != v.size || r.n != v.size {
+			t.Fatalf("%s: GenerateKey returned %d bytes and read %d bytes - but expected %d", v.algo, len(key), r.n, v.size)
+		}
+		if key[0] != 1 || key[v.size-1] != byte(v.size) {
+			t.Fatalf("%s: GenerateKey returned %x - but expected the bytes of the reader", v.algo, key)
+		}
+	}
+	for _, algo := range crypto.KeyAlgorithms() {
+		if _, err := crypto.GenerateKey(nil, algo); err != nil {
+			t.Fatalf("%s: GenerateKey failed for crypto/rand.Reader: %s", algo, err)
+		}
+	}
+
+	// the keys of the AEADs are accepted by the DefaultRegistry
+	for id, algo := range map[uint16]string{
+		crypto.AESGCM:           "AES-GCM",
+		crypto.AESEAX:           "AES-EAX",
+		crypto.ChaCha20Poly1305: "ChaCha20-Poly1305",
+	} {
+		if name, _ := crypto.DefaultRegistry.Name(id); name != algo {
+			t.Fatalf("ID %d: the registry name is %q - but expected %q", id, name, algo)
+		}
+		key, _ := crypto.GenerateKey(nil, algo)
+		if _, err := crypto.DefaultRegistry.New(id, key); err != nil {
+			t.Fatalf("%s: the registry rejected the generated key: %s", algo, err)
+		}
+	}
+
+	if _, err := crypto.GenerateKey(nil, "AES-1024"); err != crypto.UnknownAlgorithmError("AES-1024") {
+		t.Fatalf("GenerateKey returned %v - but expected %v", err, crypto.UnknownAlgorithmError("AES-1024"))
+	}
+	if _, err := crypto.GenerateKey(bytes.NewReader(make([]byte, 31)), "ChaCha20"); err != io.ErrUnexpectedEOF {
+		t.Fatalf("GenerateKey returned %v - but expected %v", err, io.ErrUnexpectedEOF)
+	}
+}
+
+func TestGenerateTypedKeys(t *testing.T) {
+	r := new(countingReader)
+	chachaKey, err := crypto.GenerateChaChaKey(r)
+	if err != nil {
+		t.Fatalf("GenerateChaChaKey failed: %s", err)
+	}
+	if r.n != 32 || chachaKey[31] != 32 {
+		t.Fatalf("GenerateChaChaKey read %d bytes and returned %x", r.n, chachaKey)
+	}
+	sipKey, err := crypto.GenerateSipHashKey(r)
+	if err != nil {
+		t.Fatalf("GenerateSipHashKey failed: %s", err)
+	}
+	if r.n != 48 || sipKey[0] != 33 {
+		t.Fatalf("GenerateSipHashKey read %d bytes and returned %x", r.n-32, sipKey)
+	}
+
+	empty := bytes.NewReader(nil)
+	if _, err = crypto.GenerateChaChaKey(empty); err != io.EOF {
+		t.Fatalf("GenerateChaChaKey returned %v - but expected %v", err, io.EOF)
+	}
+	if _, err = crypto.GenerateSipHashKey(empty); err != io.EOF {
+		t.Fatalf("GenerateSipHashKey returned %v - but expected %v", err, io.EOF)
+	}
+	if _, err = crypto.GenerateChaChaKey(nil); err != nil {
+		t.Fatalf("GenerateChaChaKey failed for crypto/rand.Reader: %s", err)
+	}
+}
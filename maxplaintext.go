@@ -0,0 +1,24 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto
+
+import "crypto/cipher"
+
+// MaxPlaintext returns the max. number of plaintext bytes, which can be
+// encrypted safely with one Seal call of the given AEAD - e.g. to split
+// large messages into chunks. The AEADs of this repository - like
+// crypto/cipher.EAX, the ChaCha20-Poly1305 of crypto/chacha20 and the
+// AES-GCM of the DefaultRegistry - report the limit by a MaxPlaintext method:
+//
+//	MaxPlaintext() int64
+//
+// If the AEAD has no MaxPlaintext method this function returns -1.
+func MaxPlaintext(aead cipher.AEAD) int64 {
+	if m, ok := aead.(interface {
+		MaxPlaintext() int64
+	}); ok {
+		return m.MaxPlaintext()
+	}
+	return -1
+}
@@ -0,0 +1,49 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto_test
+
+import (
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"math"
+	"testing"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/chacha20"
+	"github.com/enceve/crypto/cipher"
+)
+
+func TestMaxPlaintext(t *testing.T) {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	eax, err := cipher.NewEAX(block, block.BlockSize())
+	if err != nil {
+		t.Fatalf("Failed to create AES-128-EAX instance: %s", err)
+	}
+	gcm, err := gocipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create AES-128-GCM instance: %s", err)
+	}
+	aesGCM, err := crypto.DefaultRegistry.New(crypto.AESGCM, make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128-GCM instance: %s", err)
+	}
+	var key [32]byte
+
+	for name, v := range map[string]struct {
+		aead gocipher.AEAD
+		max  int64
+	}{
+		"EAX":               {eax, math.MaxInt64}, // 2^128 blocks
+		"ChaCha20-Poly1305": {chacha20.NewChaCha20Poly1305(&key), (1<<32 - 1) * 64},
+		"AES-GCM":           {aesGCM, (1<<32 - 2) * 16},
+		"crypto/cipher GCM": {gcm, -1},
+	} {
+		if max := crypto.MaxPlaintext(v.aead); max != v.max {
+			t.Fatalf("%s: MaxPlaintext returned %d - but expected %d", name, max, v.max)
+		}
+	}
+}
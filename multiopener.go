@@ -0,0 +1,53 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto
+
+import "crypto/cipher"
+
+// MultiOpener opens ciphertexts sealed by any of several AEADs - e.g. the
+// AEADs of the old and the new key during a key rotation.
+type MultiOpener struct {
+	aeads []cipher.AEAD
+}
+
+// NewMultiKeyOpener returns a MultiOpener trying the given AEADs in order.
+// All AEADs must have the same nonce size. If no AEAD is given or the
+// nonce sizes differ this function panics.
+func NewMultiKeyOpener(aeads ...cipher.AEAD) MultiOpener {
+	if len(aeads) == 0 {
+		panic("crypto: no AEAD given")
+	}
+	for _, aead := range aeads[1:] {
+		if aead.NonceSize() != aeads[0].NonceSize() {
+			panic("crypto: AEADs have different nonce sizes")
+		}
+	}
+	m := MultiOpener{aeads: make([]cipher.AEAD, len(aeads))}
+	copy(m.aeads, aeads)
+	return m
+}
+
+// NonceSize returns the nonce size of the AEADs.
+func (m MultiOpener) NonceSize() int { return m.aeads[0].NonceSize() }
+
+// Open decrypts and authenticates the ciphertext and the additional data
+// and appends the plaintext to dst. Open always tries all AEADs - even if
+// one of them already succeeded - so the timing of Open does not reveal
+// which key matched. If more than one AEAD accepts the ciphertext the
+// plaintext of the first one is returned. If no AEAD accepts the ciphertext
+// Open returns an AuthenticationError.
+func (m MultiOpener) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	var plaintext []byte
+	ok := false
+	for _, aead := range m.aeads {
+		p, err := aead.Open(nil, nonce, ciphertext, additionalData)
+		if err == nil && !ok {
+			plaintext, ok = p, true
+		}
+	}
+	if !ok {
+		return nil, AuthenticationError{Algorithm: "MultiOpener"}
+	}
+	return append(dst, plaintext...), nil
+}
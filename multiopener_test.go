@@ -0,0 +1,94 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"testing"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/cipher"
+)
+
+func newGCM(t *testing.T, key byte) gocipher.AEAD {
+	k := make([]byte, 16)
+	k[0] = key
+	block, err := aes.NewCipher(k)
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	gcm, err := gocipher.NewGCM(block)
+	if err != nil {
+		t.Fatalf("Failed to create AES-GCM instance: %s", err)
+	}
+	return gcm
+}
+
+func TestMultiKeyOpener(t *testing.T) {
+	keys := []gocipher.AEAD{newGCM(t, 1), newGCM(t, 2), newGCM(t, 3)}
+	m := crypto.NewMultiKeyOpener(keys...)
+	if m.NonceSize() != 12 {
+		t.Fatalf("NonceSize returned %d - but expected 12", m.NonceSize())
+	}
+
+	nonce, msg, data := make([]byte, 12), []byte("key rotation"), []byte("data")
+	for i, aead := range keys {
+		ciphertext := aead.Seal(nil, nonce, msg, data)
+		plaintext, err := m.Open([]byte("prefix"), nonce, ciphertext, data)
+		if err != nil {
+			t.Fatalf("Key %d: Open failed: %s", i, err)
+		}
+		if expected := append([]byte("prefix"), msg...); !bytes.Equal(plaintext, expected) {
+			t.Fatalf("Key %d: Open returned %q - but expected %q", i, plaintext, expected)
+		}
+		if _, err = m.Open(nil, nonce, ciphertext, []byte("other")); err != (crypto.AuthenticationError{Algorithm: "MultiOpener"}) {
+			t.Fatalf("Key %d: Open returned %v for wrong additional data - but expected %v", i, err, crypto.AuthenticationError{Algorithm: "MultiOpener"})
+		}
+	}
+
+	// the ciphertext of an unknown key is rejected after trying all keys
+	var tried int
+	counting := make([]gocipher.AEAD, len(keys))
+	for i := range keys {
+		counting[i] = crypto.NewInstrumentedAEAD(keys[i], nil, func(int, error) { tried++ })
+	}
+	ciphertext := newGCM(t, 4).Seal(nil, nonce, msg, data)
+	if _, err := crypto.NewMultiKeyOpener(counting...).Open(nil, nonce, ciphertext, data); err == nil {
+		t.Fatal("Open accepted the ciphertext of an unknown key")
+	}
+	if tried != len(keys) {
+		t.Fatalf("Open tried %d keys - but expected %d", tried, len(keys))
+	}
+
+	// all keys are tried even if the first one matches
+	tried = 0
+	ciphertext = keys[0].Seal(nil, nonce, msg, data)
+	if _, err := crypto.NewMultiKeyOpener(counting...).Open(nil, nonce, ciphertext, data); err != nil {
+		t.Fatalf("Open failed: %s", err)
+	}
+	if tried != len(keys) {
+		t.Fatalf("Open tried %d keys - but expected %d", tried, len(keys))
+	}
+
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	eax, err := cipher.NewEAX(block, block.BlockSize())
+	if err != nil {
+		t.Fatalf("Failed to create AES-128-EAX instance: %s", err)
+	}
+	mustFail := func(msg string, aeads ...gocipher.AEAD) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Expected error: %s", msg)
+			}
+		}()
+		crypto.NewMultiKeyOpener(aeads...)
+	}
+	mustFail("no AEAD given")
+	mustFail("nonce sizes differ", newGCM(t, 1), eax)
+}
@@ -151,3 +151,29 @@ func unalignBytes(in []byte) []byte {
 	copy(out, in)
 	return out
 }
+
+func TestWriteFillsBuffer(t *testing.T) {
+	var key [32]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+	msg := make([]byte, 3*TagSize)
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+
+	// a Write completing the buffered block exactly
+	for i := 1; i < TagSize; i++ {
+		for _, n := range []int{TagSize, 2 * TagSize, 3 * TagSize} {
+			var tag0, tag1 [TagSize]byte
+			h := New(&key)
+			h.Write(msg[:i])
+			h.Write(msg[i:n])
+			h.Sum(&tag0)
+			Sum(&tag1, msg[:n], &key)
+			if tag0 != tag1 {
+				t.Fatalf("Writes of %d and %d bytes: Sum differ from poly1305.Sum\n Sum: %s \n poly1305.Sum: %s", i, n-i, hex.EncodeToString(tag0[:]), hex.EncodeToString(tag1[:]))
+			}
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package poly1305
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+func init() {
+	crypto.RegisterSelfTest("Poly1305", selfTest)
+}
+
+// selfTest checks Poly1305 against the
+// test vector from RFC 7539 (section 2.5.2).
+func selfTest() error {
+	key, _ := hex.DecodeString("85d6be7857556d337f4452fe42d506a80103808afb0db2fd4abff6af4149f51b")
+	msg := []byte("Cryptographic Forum Research Group")
+	tag, _ := hex.DecodeString("a8061dc1305136c6c22b8baf0c0127a9")
+
+	var k [32]byte
+	var sum [TagSize]byte
+	copy(k[:], key)
+	Sum(&sum, msg, &k)
+	if !bytes.Equal(sum[:], tag) {
+		return errors.New("unexpected tag")
+	}
+	return nil
+}
@@ -0,0 +1,139 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// Package polyval implements the POLYVAL universal hash function
+// used by the AES-GCM-SIV mode of operation.
+// POLYVAL is specified in RFC 8452.
+//
+// POLYVAL is the little-endian variant of GHASH: both operate in
+// GF(2^128), but POLYVAL uses a different bit order and field
+// representation, which is more efficient on little-endian CPUs.
+// Like GHASH, POLYVAL is NOT a MAC on its own - the hash key H must
+// be secret and the output must be encrypted to build an authenticator.
+package polyval
+
+import (
+	"encoding/binary"
+
+	"github.com/enceve/crypto/internal/gf128"
+)
+
+// The size of the POLYVAL checksum in bytes.
+const Size = 16
+
+// The block size of POLYVAL in bytes.
+const BlockSize = 16
+
+// POLYVAL computes the POLYVAL of data using the hash key h.
+// If the length of data is not a multiple of the block size
+// the last block is padded with zeros.
+func POLYVAL(h [16]byte, data []byte) [16]byte {
+	p := New(h)
+	p.Write(data)
+	var sum [16]byte
+	p.Sum(sum[:0])
+	return sum
+}
+
+// Hasher computes the POLYVAL incrementally. It implements hash.Hash.
+type Hasher struct {
+	h, y [16]byte
+	buf  [BlockSize]byte
+	off  int
+
+	// h as GHASH element for the generic implementation
+	// (see RFC 8452 Appendix A): mulX_GHASH(ByteReverse(h))
+	hGHASH gf128.Element
+}
+
+// New returns a new Hasher computing the POLYVAL using the hash key h.
+func New(h [16]byte) *Hasher {
+	return &Hasher{
+		h:      h,
+		hGHASH: gf128.MulX(fromPOLYVAL(h[:])),
+	}
+}
+
+// Size returns the size of the POLYVAL checksum in bytes.
+func (p *Hasher) Size() int { return Size }
+
+// BlockSize returns the block size of POLYVAL in bytes.
+func (p *Hasher) BlockSize() int { return BlockSize }
+
+// Reset resets the Hasher to its initial state.
+// The hash key is not changed.
+func (p *Hasher) Reset() {
+	p.y = [16]byte{}
+	p.off = 0
+}
+
+// Write processes the data. Subsequent writes are
+// concatenated - use Pad to separate inputs at block
+// boundaries. Write never returns an error.
+func (p *Hasher) Write(b []byte) (int, error) {
+	n := len(b)
+	if p.off > 0 {
+		k := copy(p.buf[p.off:], b)
+		p.off += k
+		b = b[k:]
+		if p.off < BlockSize {
+			return n, nil
+		}
+		p.update(p.buf[:])
+		p.off = 0
+	}
+	for len(b) >= BlockSize {
+		p.update(b[:BlockSize])
+		b = b[BlockSize:]
+	}
+	if len(b) > 0 {
+		p.off = copy(p.buf[:], b)
+	}
+	return n, nil
+}
+
+// Pad pads the data written so far with zeros to a multiple
+// of the block size. E.g. AES-GCM-SIV pads the additional data
+// and the plaintext separately.
+func (p *Hasher) Pad() {
+	if p.off > 0 {
+		for i := p.off; i < BlockSize; i++ {
+			p.buf[i] = 0
+		}
+		p.update(p.buf[:])
+		p.off = 0
+	}
+}
+
+// Sum appends the POLYVAL of the data written so far (zero padded
+// to a multiple of the block size) to b. Sum does not change
+// the state of the Hasher.
+func (p *Hasher) Sum(b []byte) []byte {
+	y := *p
+	y.Pad()
+	return append(b, y.y[:]...)
+}
+
+// update processes one block: y = (y + block) * h * x^-128
+func (p *Hasher) update(block []byte) {
+	for i := range p.y {
+		p.y[i] ^= block[i]
+	}
+	p.mul()
+}
+
+// mulGeneric computes y = y * h * x^-128 using the GHASH
+// multiplication (see RFC 8452 Appendix A).
+func (p *Hasher) mulGeneric() {
+	z := gf128.Mul(fromPOLYVAL(p.y[:]), p.hGHASH)
+	binary.LittleEndian.PutUint64(p.y[:], z.Lo)
+	binary.LittleEndian.PutUint64(p.y[8:], z.Hi)
+}
+
+// fromPOLYVAL returns the GHASH element ByteReverse(b).
+func fromPOLYVAL(b []byte) gf128.Element {
+	return gf128.Element{
+		Hi: binary.LittleEndian.Uint64(b[8:]),
+		Lo: binary.LittleEndian.Uint64(b),
+	}
+}
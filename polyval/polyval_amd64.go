@@ -0,0 +1,23 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// +build amd64,!gccgo,!appengine
+
+package polyval
+
+import "github.com/enceve/crypto/internal/cpu"
+
+var usePCLMULQDQ = cpu.HasPCLMULQDQ
+
+func (p *Hasher) mul() {
+	if usePCLMULQDQ {
+		mulPCLMULQDQ(&(p.y), &(p.h))
+		return
+	}
+	p.mulGeneric()
+}
+
+// mulPCLMULQDQ computes y = y * h * x^-128 using the PCLMULQDQ instruction.
+// This function expects valid values. (no nil ptr etc.)
+//go:noescape
+func mulPCLMULQDQ(y, h *[16]byte)
@@ -0,0 +1,50 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// +build amd64,!gccgo,!appengine
+
+// The multiplication and the Montgomery reduction follow
+// "AES-GCM-SIV: Specification and Analysis" (Gueron, Langley, Lindell).
+
+// func mulPCLMULQDQ(y, h *[16]byte)
+TEXT ·mulPCLMULQDQ(SB),4,$0-16
+	MOVQ y+0(FP), AX
+	MOVQ h+8(FP), BX
+	MOVOU (AX), X0
+	MOVOU (BX), X1
+
+	// 256 bit product: X3:X2 = X0 * X1
+	MOVOU X0, X2
+	PCLMULQDQ $0x00, X1, X2
+	MOVOU X0, X3
+	PCLMULQDQ $0x11, X1, X3
+	MOVOU X0, X4
+	PCLMULQDQ $0x10, X1, X4
+	MOVOU X0, X5
+	PCLMULQDQ $0x01, X1, X5
+	PXOR X5, X4
+	MOVOU X4, X5
+	PSLLDQ $8, X5
+	PSRLDQ $8, X4
+	PXOR X5, X2
+	PXOR X4, X3
+
+	// reduction polynomial: X6 = 0xc2000000000000000000000000000001
+	MOVQ $1, CX
+	MOVQ CX, X6
+	MOVQ $0xc200000000000000, CX
+	PINSRQ $1, CX, X6
+
+	// two folding steps of the lower 128 bit
+	MOVOU X2, X4
+	PCLMULQDQ $0x10, X6, X4
+	PSHUFD $78, X2, X5
+	PXOR X5, X4
+	MOVOU X4, X2
+	PCLMULQDQ $0x10, X6, X2
+	PSHUFD $78, X4, X5
+	PXOR X5, X2
+
+	PXOR X3, X2
+	MOVOU X2, (AX)
+	RET
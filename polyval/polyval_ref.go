@@ -0,0 +1,10 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// +build !amd64 gccgo appengine
+
+package polyval
+
+var usePCLMULQDQ = false
+
+func (p *Hasher) mul() { p.mulGeneric() }
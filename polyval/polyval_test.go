@@ -0,0 +1,127 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package polyval
+
+import (
+	"bytes"
+	"hash"
+	"math/rand"
+	"testing"
+
+	"github.com/enceve/crypto/internal/testvec"
+)
+
+var _ hash.Hash = (*Hasher)(nil)
+
+// Test vectors from RFC 8452 Appendix A and the POLYVAL
+// inputs of the AEAD_AES_128_GCM_SIV vectors in Appendix C.1
+// https://tools.ietf.org/html/rfc8452
+var testVectors = []struct {
+	h, data, sum string
+}{
+	{
+		h:    "25629347589242761d31f826ba4b757b",
+		data: "4f4f95668c83dfb6401762bb2d01a262",
+		sum:  "cedac64537ff50989c16011551086d77",
+	},
+	{
+		h:    "25629347589242761d31f826ba4b757b",
+		data: "4f4f95668c83dfb6401762bb2d01a262d1a24ddd2721d006bbe45f20d3c9f362",
+		sum:  "f7a3b47b846119fae5b7866cf5e5b77e",
+	},
+	{
+		h:    "d9b360279694941ac5dbc6987ada7377",
+		data: "00000000000000000000000000000000",
+		sum:  "00000000000000000000000000000000",
+	},
+	{
+		h:    "d9b360279694941ac5dbc6987ada7377",
+		data: "0100000000000000000000000000000000000000000000004000000000000000",
+		sum:  "eb93b7740962c5e49d2a90a7dc5cec74",
+	},
+}
+
+func TestVectors(t *testing.T) {
+	for i, v := range testVectors {
+		var h [16]byte
+		copy(h[:], testvec.MustDecodeHex(v.h))
+		data := testvec.MustDecodeHex(v.data)
+
+		if sum := POLYVAL(h, data); !testvec.EqualHex(sum[:], v.sum) {
+			t.Fatalf("Test vector %d: POLYVAL returned %x - but expected %s", i, sum, v.sum)
+		}
+
+		// incremental with unaligned writes
+		p := New(h)
+		for j := 0; j < len(data); j += 5 {
+			end := j + 5
+			if end > len(data) {
+				end = len(data)
+			}
+			p.Write(data[j:end])
+		}
+		if sum := p.Sum(nil); !testvec.EqualHex(sum, v.sum) {
+			t.Fatalf("Test vector %d: Hasher returned %x - but expected %s", i, sum, v.sum)
+		}
+		p.Reset()
+		p.Write(data)
+		if sum := p.Sum(nil); !testvec.EqualHex(sum, v.sum) {
+			t.Fatalf("Test vector %d: Hasher returned %x after Reset - but expected %s", i, sum, v.sum)
+		}
+	}
+}
+
+func TestPad(t *testing.T) {
+	var h [16]byte
+	copy(h[:], testvec.MustDecodeHex("25629347589242761d31f826ba4b757b"))
+	a, b := []byte("additional data"), []byte("plaintext")
+
+	p := New(h)
+	p.Write(a)
+	p.Pad()
+	p.Write(b)
+	sum := p.Sum(nil)
+
+	in := append(append(append([]byte{}, a...), 0), b...)
+	if expected := POLYVAL(h, in); !bytes.Equal(sum, expected[:]) {
+		t.Fatalf("Pad: POLYVAL is %x - but expected %x", sum, expected)
+	}
+	if sum0, sum1 := p.Sum(nil), p.Sum(nil); !bytes.Equal(sum0, sum1) {
+		t.Fatal("Sum changed the state of the Hasher")
+	}
+}
+
+func TestMul(t *testing.T) {
+	if !usePCLMULQDQ {
+		t.Skip("No PCLMULQDQ implementation on this platform")
+	}
+	rng := rand.New(rand.NewSource(0))
+	for i := 0; i < 1000; i++ {
+		var h, y [16]byte
+		rng.Read(h[:])
+		rng.Read(y[:])
+		p0, p1 := New(h), New(h)
+		p0.y, p1.y = y, y
+
+		p0.mul()
+		p1.mulGeneric()
+		if p0.y != p1.y {
+			t.Fatalf("Iteration %d: PCLMULQDQ returned %x - but expected %x", i, p0.y, p1.y)
+		}
+	}
+}
+
+func benchmarkWrite(b *testing.B, size int) {
+	p := New([16]byte{1})
+	buf := make([]byte, size)
+	b.SetBytes(int64(size))
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		p.Write(buf)
+	}
+}
+
+func BenchmarkWrite64B(b *testing.B) { benchmarkWrite(b, 64) }
+
+func BenchmarkWrite1K(b *testing.B) { benchmarkWrite(b, 1024) }
@@ -0,0 +1,115 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"strconv"
+	"sync"
+)
+
+// The IDs of the AEAD constructions registered at the DefaultRegistry.
+const (
+	AESGCM           uint16 = 1 // AES-GCM - registered by this package
+	AESEAX           uint16 = 2 // AES-EAX - registered by the crypto/cipher package
+	ChaCha20Poly1305 uint16 = 3 // ChaCha20-Poly1305 - registered by the crypto/chacha20 package
+)
+
+// DefaultRegistry is the Registry of the AEAD constructions implemented
+// by this repository. Like the hash functions of the standard crypto package an
+// AEAD is only available if the package implementing it is imported:
+//
+//	import _ "github.com/enceve/crypto/chacha20"
+//
+// The AES based AEADs select AES-128, AES-192 or AES-256 by the length of
+// the key.
+var DefaultRegistry = NewRegistry()
+
+func init() {
+	DefaultRegistry.Register(AESGCM, "AES-GCM", func(key []byte) (cipher.AEAD, error) {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		return aesGCM{gcm}, nil
+	})
+}
+
+// aesGCM adds the MaxPlaintext method to the AES-GCM of crypto/cipher.
+type aesGCM struct{ cipher.AEAD }
+
+// MaxPlaintext returns the max. number of plaintext bytes, which can be
+// encrypted with one nonce. GCM encrypts at most 2^32 - 2 blocks of 16
+// bytes (NIST SP 800-38D).
+func (c aesGCM) MaxPlaintext() int64 { return (1<<32 - 2) * 16 }
+
+// AEADFactory creates a cipher.AEAD from a key.
+type AEADFactory func(key []byte) (cipher.AEAD, error)
+
+// An UnknownAEADError indicates, that no AEAD
+// is registered for an ID.
+type UnknownAEADError uint16
+
+func (u UnknownAEADError) Error() string {
+	return "unknown AEAD id " + strconv.Itoa(int(u))
+}
+
+// Registry maps (on-wire) IDs to AEAD constructions.
+// A Registry is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[uint16]registryEntry
+}
+
+type registryEntry struct {
+	name    string
+	factory AEADFactory
+}
+
+// NewRegistry returns a new empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[uint16]registryEntry)}
+}
+
+// Register registers the AEADFactory f with the given id and name.
+// If f is nil or the id is already registered this function panics.
+func (r *Registry) Register(id uint16, name string, f AEADFactory) {
+	if f == nil {
+		panic("crypto: AEADFactory is nil")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, ok := r.entries[id]; ok {
+		panic("crypto: AEAD id " + strconv.Itoa(int(id)) + " is already registered")
+	}
+	r.entries[id] = registryEntry{name: name, factory: f}
+}
+
+// New returns a new cipher.AEAD created by the AEADFactory registered
+// with the given id. If no AEAD is registered for the id New returns
+// an UnknownAEADError. The errors of the AEADFactory are returned
+// unchanged.
+func (r *Registry) New(id uint16, key []byte) (cipher.AEAD, error) {
+	r.mu.RLock()
+	e, ok := r.entries[id]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, UnknownAEADError(id)
+	}
+	return e.factory(key)
+}
+
+// Name returns the name of the AEAD registered with the given id.
+// The second return value is false if no AEAD is registered for the id.
+func (r *Registry) Name(id uint16) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	e, ok := r.entries[id]
+	return e.name, ok
+}
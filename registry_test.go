@@ -0,0 +1,88 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"testing"
+
+	"github.com/enceve/crypto"
+	_ "github.com/enceve/crypto/chacha20"
+	_ "github.com/enceve/crypto/cipher"
+)
+
+func TestRegistry(t *testing.T) {
+	r := crypto.NewRegistry()
+	r.Register(7, "AES-GCM", func(key []byte) (gocipher.AEAD, error) {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return gocipher.NewGCM(block)
+	})
+
+	if name, ok := r.Name(7); !ok || name != "AES-GCM" {
+		t.Fatalf("Name returned %q, %v - but expected %q, true", name, ok, "AES-GCM")
+	}
+	if _, ok := r.Name(8); ok {
+		t.Fatal("Name found an unregistered id")
+	}
+	if _, err := r.New(7, make([]byte, 16)); err != nil {
+		t.Fatalf("New failed: %s", err)
+	}
+	if _, err := r.New(7, make([]byte, 15)); err == nil {
+		t.Fatal("New accepted an invalid key")
+	}
+	if _, err := r.New(8, make([]byte, 16)); err != crypto.UnknownAEADError(8) {
+		t.Fatalf("New returned %v - but expected: %v", err, crypto.UnknownAEADError(8))
+	}
+
+	mustFail := func(msg string, id uint16, f crypto.AEADFactory) {
+		defer func() {
+			if recover() == nil {
+				t.Fatalf("Expected error: %s", msg)
+			}
+		}()
+		r.Register(id, "", f)
+	}
+	mustFail("id is already registered", 7, func([]byte) (gocipher.AEAD, error) { return nil, nil })
+	mustFail("factory is nil", 9, nil)
+}
+
+func TestDefaultRegistry(t *testing.T) {
+	for _, v := range []struct {
+		id   uint16
+		name string
+		key  []byte
+	}{
+		{crypto.AESGCM, "AES-GCM", make([]byte, 16)},
+		{crypto.AESGCM, "AES-GCM", make([]byte, 32)},
+		{crypto.AESEAX, "AES-EAX", make([]byte, 16)},
+		{crypto.AESEAX, "AES-EAX", make([]byte, 24)},
+		{crypto.ChaCha20Poly1305, "ChaCha20-Poly1305", make([]byte, 32)},
+	} {
+		if name, ok := crypto.DefaultRegistry.Name(v.id); !ok || name != v.name {
+			t.Fatalf("ID %d: Name returned %q - but expected %q", v.id, name, v.name)
+		}
+		aead, err := crypto.DefaultRegistry.New(v.id, v.key)
+		if err != nil {
+			t.Fatalf("%s: New failed: %s", v.name, err)
+		}
+		if _, err = crypto.DefaultRegistry.New(v.id, v.key[1:]); err == nil {
+			t.Fatalf("%s: New accepted a %d byte key", v.name, len(v.key)-1)
+		}
+
+		nonce, msg, data := make([]byte, aead.NonceSize()), []byte("registry"), []byte("data")
+		ciphertext := aead.Seal(nil, nonce, msg, data)
+		plaintext, err := aead.Open(nil, nonce, ciphertext, data)
+		if err != nil {
+			t.Fatalf("%s: Open failed: %s", v.name, err)
+		}
+		if !bytes.Equal(plaintext, msg) {
+			t.Fatalf("%s: Open returned %q - but expected %q", v.name, plaintext, msg)
+		}
+	}
+}
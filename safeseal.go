@@ -0,0 +1,66 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto
+
+import (
+	"crypto/cipher"
+	"errors"
+	"strings"
+)
+
+// ErrNonceReused is the panic value of an AEAD which detects
+// that a nonce was used before - e.g. the nonce guard of the
+// cipher package.
+var ErrNonceReused = errors.New("nonce was probably used before")
+
+// The prefixes of the panic messages recovered by SafeSeal
+var safeSealPrefixes = []string{
+	"crypto: ",
+	"cipher: ",
+	"chacha20/chacha: ",
+}
+
+// SafeSeal calls the Seal function of the AEAD and converts the panics
+// raised for invalid arguments - e.g. a NonceSizeError or an undersized
+// output buffer - into errors. This is a bridge for code which cannot
+// tolerate panics of library calls.
+// Only the deliberate panics of this repository are recovered: a
+// NonceSizeError or NonceSizeMismatchError, ErrNonceReused and the panic
+// messages starting with "crypto: ", "cipher: " or "chacha20/chacha: ".
+// All other panics - e.g. runtime errors or the errors of other AEAD
+// implementations - are raised again, because SafeSeal cannot know
+// whether they are safe to ignore.
+func SafeSeal(aead cipher.AEAD, dst, nonce, plaintext, additionalData []byte) (out []byte, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			if err = panicToError(r); err == nil {
+				panic(r)
+			}
+			out = nil
+		}
+	}()
+	return aead.Seal(dst, nonce, plaintext, additionalData), nil
+}
+
+// panicToError returns the error for a recovered panic value
+// or nil if the panic must be raised again.
+func panicToError(r interface{}) error {
+	switch v := r.(type) {
+	case NonceSizeError:
+		return v
+	case NonceSizeMismatchError:
+		return v
+	case error:
+		if v == ErrNonceReused {
+			return v
+		}
+	case string:
+		for _, prefix := range safeSealPrefixes {
+			if strings.HasPrefix(v, prefix) {
+				return errors.New(v)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,100 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto_test
+
+import (
+	"bytes"
+	gocipher "crypto/cipher"
+	"errors"
+	"testing"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/chacha20"
+	"github.com/enceve/crypto/chacha20/chacha"
+)
+
+// inPlaceAEAD writes the ciphertext into dst without growing
+// it, so an undersized dst causes a panic of the chacha package.
+type inPlaceAEAD struct{ panicValue interface{} }
+
+func (inPlaceAEAD) NonceSize() int { return 12 }
+
+func (inPlaceAEAD) Overhead() int { return 0 }
+
+func (a inPlaceAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	if a.panicValue != nil {
+		panic(a.panicValue)
+	}
+	var key [32]byte
+	var n [12]byte
+	copy(n[:], nonce)
+	chacha.XORKeyStream(dst, plaintext, &n, &key, 0, 20)
+	return dst[:len(plaintext)]
+}
+
+func (inPlaceAEAD) Open(dst, nonce, ciphertext, additionalData []byte) ([]byte, error) {
+	return nil, crypto.AuthenticationError{}
+}
+
+func TestSafeSeal(t *testing.T) {
+	var key [32]byte
+	var aead gocipher.AEAD = chacha20.NewChaCha20Poly1305(&key)
+	nonce, msg := make([]byte, aead.NonceSize()), []byte("safe seal")
+
+	ciphertext, err := crypto.SafeSeal(aead, nil, nonce, msg, nil)
+	if err != nil {
+		t.Fatalf("SafeSeal failed: %s", err)
+	}
+	if expected := aead.Seal(nil, nonce, msg, nil); !bytes.Equal(ciphertext, expected) {
+		t.Fatalf("SafeSeal returned %x - but expected %x", ciphertext, expected)
+	}
+
+	if _, err = crypto.SafeSeal(aead, nil, nonce[1:], msg, nil); err != (crypto.NonceSizeMismatchError{Size: 11, Expected: 12}) {
+		t.Fatalf("SafeSeal returned %v for an invalid nonce - but expected %v", err, crypto.NonceSizeMismatchError{Size: 11, Expected: 12})
+	}
+	out, err := crypto.SafeSeal(inPlaceAEAD{}, make([]byte, len(msg)-1), nonce, msg, nil)
+	if err == nil || out != nil {
+		t.Fatal("SafeSeal did not return an error for an undersized dst")
+	}
+	if out, err = crypto.SafeSeal(inPlaceAEAD{}, make([]byte, len(msg)), nonce, msg, nil); err != nil || len(out) != len(msg) {
+		t.Fatalf("SafeSeal failed: %v", err)
+	}
+
+	mustPanic := func(msg string, v interface{}) {
+		defer func() {
+			if r := recover(); r != v {
+				t.Fatalf("%s: SafeSeal raised %v - but expected %v", msg, r, v)
+			}
+		}()
+		crypto.SafeSeal(inPlaceAEAD{panicValue: v}, nil, nonce, nil, nil)
+	}
+	mustPanic("unknown panic message", "unexpected")
+	mustPanic("unknown panic value", 42)
+	mustPanic("foreign error", errForeign)
+	mustPanic("crypto/cipher panic message", "crypto/cipher: message too large for GCM")
+
+	if _, err = crypto.SafeSeal(inPlaceAEAD{panicValue: crypto.NonceSizeError(8)}, nil, nonce, nil, nil); err != crypto.NonceSizeError(8) {
+		t.Fatalf("SafeSeal returned %v - but expected %v", err, crypto.NonceSizeError(8))
+	}
+	if _, err = crypto.SafeSeal(inPlaceAEAD{panicValue: crypto.ErrNonceReused}, nil, nonce, nil, nil); err != crypto.ErrNonceReused {
+		t.Fatalf("SafeSeal returned %v for a reused nonce - but expected %v", err, crypto.ErrNonceReused)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("SafeSeal recovered a runtime error")
+		}
+	}()
+	crypto.SafeSeal(nilAEAD{}, nil, nonce, msg, nil)
+}
+
+var errForeign = errors.New("foreign AEAD failure")
+
+// nilAEAD causes a nil pointer dereference in Seal.
+type nilAEAD struct{ inPlaceAEAD }
+
+func (nilAEAD) Seal(dst, nonce, plaintext, additionalData []byte) []byte {
+	var p *[1]byte
+	return append(dst, p[0])
+}
@@ -0,0 +1,127 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package secretbox
+
+import "github.com/enceve/crypto"
+
+// The salsa20 constants "expand 32-byte k"
+const (
+	c0 = uint32(0x61707865)
+	c1 = uint32(0x3320646e)
+	c2 = uint32(0x79622d32)
+	c3 = uint32(0x6b206574)
+)
+
+// hSalsa20 derives a subkey from the key and the 16 byte nonce
+// as specified in https://cr.yp.to/snuffle/xsalsa-20081128.pdf.
+func hSalsa20(out *[32]byte, nonce *[16]byte, key *[32]byte) {
+	var x [16]uint32
+	x[0], x[5], x[10], x[15] = c0, c1, c2, c3
+	for i := 0; i < 4; i++ {
+		x[1+i] = load32(key[4*i:])
+		x[11+i] = load32(key[16+4*i:])
+		x[6+i] = load32(nonce[4*i:])
+	}
+
+	rounds(&x)
+
+	for i, j := range []int{0, 5, 10, 15, 6, 7, 8, 9} {
+		store32(out[4*i:], x[j])
+	}
+}
+
+// xorKeyStream crypts bytes from src to dst using the salsa20 keystream
+// generated from the key, the 8 byte nonce and the block counter.
+func xorKeyStream(dst, src []byte, nonce *[8]byte, key *[32]byte, counter uint64) {
+	var state, x [16]uint32
+	state[0], state[5], state[10], state[15] = c0, c1, c2, c3
+	for i := 0; i < 4; i++ {
+		state[1+i] = load32(key[4*i:])
+		state[11+i] = load32(key[16+4*i:])
+	}
+	state[6] = load32(nonce[0:])
+	state[7] = load32(nonce[4:])
+
+	var block [64]byte
+	for len(src) > 0 {
+		state[8] = uint32(counter)
+		state[9] = uint32(counter >> 32)
+
+		x = state
+		rounds(&x)
+		for i := range x {
+			store32(block[4*i:], x[i]+state[i])
+		}
+
+		n := crypto.XOR(dst, src, block[:])
+		dst, src = dst[n:], src[n:]
+		counter++
+	}
+}
+
+// rounds applies the 20 salsa20 rounds to the state.
+func rounds(x *[16]uint32) {
+	x0, x1, x2, x3, x4, x5, x6, x7 := x[0], x[1], x[2], x[3], x[4], x[5], x[6], x[7]
+	x8, x9, x10, x11, x12, x13, x14, x15 := x[8], x[9], x[10], x[11], x[12], x[13], x[14], x[15]
+
+	for i := 0; i < 20; i += 2 {
+		// column round
+		x4 ^= rotl(x0+x12, 7)
+		x8 ^= rotl(x4+x0, 9)
+		x12 ^= rotl(x8+x4, 13)
+		x0 ^= rotl(x12+x8, 18)
+
+		x9 ^= rotl(x5+x1, 7)
+		x13 ^= rotl(x9+x5, 9)
+		x1 ^= rotl(x13+x9, 13)
+		x5 ^= rotl(x1+x13, 18)
+
+		x14 ^= rotl(x10+x6, 7)
+		x2 ^= rotl(x14+x10, 9)
+		x6 ^= rotl(x2+x14, 13)
+		x10 ^= rotl(x6+x2, 18)
+
+		x3 ^= rotl(x15+x11, 7)
+		x7 ^= rotl(x3+x15, 9)
+		x11 ^= rotl(x7+x3, 13)
+		x15 ^= rotl(x11+x7, 18)
+
+		// row round
+		x1 ^= rotl(x0+x3, 7)
+		x2 ^= rotl(x1+x0, 9)
+		x3 ^= rotl(x2+x1, 13)
+		x0 ^= rotl(x3+x2, 18)
+
+		x6 ^= rotl(x5+x4, 7)
+		x7 ^= rotl(x6+x5, 9)
+		x4 ^= rotl(x7+x6, 13)
+		x5 ^= rotl(x4+x7, 18)
+
+		x11 ^= rotl(x10+x9, 7)
+		x8 ^= rotl(x11+x10, 9)
+		x9 ^= rotl(x8+x11, 13)
+		x10 ^= rotl(x9+x8, 18)
+
+		x12 ^= rotl(x15+x14, 7)
+		x13 ^= rotl(x12+x15, 9)
+		x14 ^= rotl(x13+x12, 13)
+		x15 ^= rotl(x14+x13, 18)
+	}
+
+	x[0], x[1], x[2], x[3], x[4], x[5], x[6], x[7] = x0, x1, x2, x3, x4, x5, x6, x7
+	x[8], x[9], x[10], x[11], x[12], x[13], x[14], x[15] = x8, x9, x10, x11, x12, x13, x14, x15
+}
+
+func rotl(v uint32, n uint) uint32 { return (v << n) | (v >> (32 - n)) }
+
+func load32(b []byte) uint32 {
+	return uint32(b[0]) | uint32(b[1])<<8 | uint32(b[2])<<16 | uint32(b[3])<<24
+}
+
+func store32(b []byte, v uint32) {
+	b[0] = byte(v)
+	b[1] = byte(v >> 8)
+	b[2] = byte(v >> 16)
+	b[3] = byte(v >> 24)
+}
@@ -0,0 +1,102 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+// Package secretbox implements the NaCl secretbox construction
+// (XSalsa20 and Poly1305) to encrypt and authenticate messages
+// with a secret key. Boxes sealed by this package are byte-compatible
+// with NaCl (crypto_secretbox_xsalsa20poly1305) and
+// golang.org/x/crypto/nacl/secretbox.
+//
+// The nonce must be unique for one key for all time. Because of the
+// large (24 byte) nonce, random nonces can be used safely.
+package secretbox
+
+import (
+	"crypto/subtle"
+
+	"github.com/enceve/crypto/poly1305"
+)
+
+// Overhead is the number of bytes of overhead when boxing a message.
+const Overhead = poly1305.TagSize
+
+// Seal appends an encrypted and authenticated copy of message to out,
+// which must not overlap message. The key and nonce pair must be unique
+// for each distinct message and the output will be Overhead bytes longer
+// than message.
+func Seal(out, message []byte, nonce *[24]byte, key *[32]byte) []byte {
+	var subKey, polyKey [32]byte
+	var counterNonce [8]byte
+	setup(&subKey, &counterNonce, &polyKey, nonce, key)
+
+	ret, box := sliceForAppend(out, len(message)+Overhead)
+	xorKeyStream32(box[Overhead:], message, &counterNonce, &subKey)
+
+	var tag [poly1305.TagSize]byte
+	poly1305.Sum(&tag, box[Overhead:], &polyKey)
+	copy(box, tag[:])
+	return ret
+}
+
+// Open authenticates and decrypts a box produced by Seal and appends the
+// message to out, which must not overlap box. The output will be Overhead
+// bytes smaller than box. Open returns false if the box is not authentic.
+func Open(out, box []byte, nonce *[24]byte, key *[32]byte) ([]byte, bool) {
+	if len(box) < Overhead {
+		return nil, false
+	}
+	var subKey, polyKey [32]byte
+	var counterNonce [8]byte
+	setup(&subKey, &counterNonce, &polyKey, nonce, key)
+
+	var tag [poly1305.TagSize]byte
+	poly1305.Sum(&tag, box[Overhead:], &polyKey)
+	if subtle.ConstantTimeCompare(tag[:], box[:Overhead]) != 1 {
+		return nil, false
+	}
+
+	ret, message := sliceForAppend(out, len(box)-Overhead)
+	xorKeyStream32(message, box[Overhead:], &counterNonce, &subKey)
+	return ret, true
+}
+
+// setup derives the XSalsa20 subkey and the poly1305 key
+// from the nonce and the key.
+func setup(subKey *[32]byte, counterNonce *[8]byte, polyKey *[32]byte, nonce *[24]byte, key *[32]byte) {
+	var hNonce [16]byte
+	copy(hNonce[:], nonce[:16])
+	hSalsa20(subKey, &hNonce, key)
+	copy(counterNonce[:], nonce[16:])
+
+	xorKeyStream(polyKey[:], polyKey[:], counterNonce, subKey, 0)
+}
+
+// xorKeyStream32 crypts src to dst using the XSalsa20 keystream
+// starting at byte 32 - the first 32 bytes are the poly1305 key.
+func xorKeyStream32(dst, src []byte, nonce *[8]byte, key *[32]byte) {
+	n := len(src)
+	if n > 32 {
+		n = 32
+	}
+	var block [64]byte
+	copy(block[32:], src[:n])
+	xorKeyStream(block[:], block[:], nonce, key, 0)
+	copy(dst, block[32:32+n])
+	if len(src) > n {
+		xorKeyStream(dst[n:], src[n:], nonce, key, 1)
+	}
+}
+
+// sliceForAppend takes a slice and a requested number of bytes. It returns a
+// slice with the contents of the given slice followed by that many bytes and
+// a second slice that aliases into it and contains only the extra bytes.
+func sliceForAppend(in []byte, n int) (head, tail []byte) {
+	if total := len(in) + n; cap(in) >= total {
+		head = in[:total]
+	} else {
+		head = make([]byte, total)
+		copy(head, in)
+	}
+	tail = head[len(in):]
+	return
+}
@@ -0,0 +1,97 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package secretbox
+
+import (
+	"bytes"
+	"encoding/hex"
+	"testing"
+
+	"github.com/enceve/crypto/internal/testvec"
+)
+
+// Test vectors from "Cryptography in NaCl" (core1.c and secretbox.c)
+// https://cr.yp.to/highspeed/naclcrypto-20090310.pdf
+var (
+	sharedKey = "4a5d9d5ba4ce2de1728e3bf480350f25e07e21c947d19e3376f09b3c1e161742"
+	firstKey  = "1b27556473e985d462cd51197a9a46c76009549eac6474f206c4ee0844f68389"
+	nonce     = "69696ee955b62b73cd62bda875fc73d68219e0036b7a0b37"
+	message   = "be075fc53c81f2d5cf141316ebeb0c7b5228c52a4c62cbd44b66849b64244ffc" +
+		"e5ecbaaf33bd751a1ac728d45e6c61296cdc3c01233561f41db66cce314adb31" +
+		"0e3be8250c46f06dceea3a7fa1348057e2f6556ad6b1318a024a838f21af1fde" +
+		"048977eb48f59ffd4924ca1c60902e52f0a089bc76897040e082f93776384864" +
+		"5e0705"
+	box = "f3ffc7703f9400e52a7dfb4b3d3305d9" +
+		"8e993b9f48681273c29650ba32fc76ce48332ea7164d96a4476fb8c531a1186a" +
+		"c0dfc17c98dce87b4da7f011ec48c97271d2c20f9b928fe2270d6fb863d51738" +
+		"b48eeee314a7cc8ab932164548e526ae90224368517acfeabd6bb3732bc0e9da" +
+		"99832b61ca01b6de56244a9e88d5f9b37973f622a43d14a6599b1f654cb45a74" +
+		"e355a5"
+)
+
+func TestHSalsa20(t *testing.T) {
+	var key, out [32]byte
+	var nonce [16]byte
+	copy(key[:], testvec.MustDecodeHex(sharedKey))
+
+	hSalsa20(&out, &nonce, &key)
+	if !bytes.Equal(out[:], testvec.MustDecodeHex(firstKey)) {
+		t.Fatalf("HSalsa20 produces unexpected subkey:\nFound:    %s\nExpected: %s", hex.EncodeToString(out[:]), firstKey)
+	}
+}
+
+func TestVectors(t *testing.T) {
+	var key [32]byte
+	var n [24]byte
+	copy(key[:], testvec.MustDecodeHex(firstKey))
+	copy(n[:], testvec.MustDecodeHex(nonce))
+	msg, expected := testvec.MustDecodeHex(message), testvec.MustDecodeHex(box)
+
+	sealed := Seal(nil, msg, &n, &key)
+	if !bytes.Equal(sealed, expected) {
+		t.Fatalf("Seal produces unexpected box:\nFound:    %s\nExpected: %s", hex.EncodeToString(sealed), box)
+	}
+
+	opened, ok := Open(nil, sealed, &n, &key)
+	if !ok {
+		t.Fatal("Open failed")
+	}
+	if !bytes.Equal(opened, msg) {
+		t.Fatalf("Open produces unexpected message:\nFound:    %s\nExpected: %s", hex.EncodeToString(opened), message)
+	}
+}
+
+func TestSealOpen(t *testing.T) {
+	var key [32]byte
+	var nonce [24]byte
+	for i := range key {
+		key[i] = byte(i)
+	}
+
+	msg := make([]byte, 200)
+	for i := range msg {
+		sealed := Seal([]byte("prefix"), msg[:i], &nonce, &key)
+		if n := len(sealed); n != len("prefix")+i+Overhead {
+			t.Fatalf("Length %d: Seal returned %d bytes - but expected %d", i, n, len("prefix")+i+Overhead)
+		}
+		sealed = sealed[len("prefix"):]
+
+		opened, ok := Open(nil, sealed, &nonce, &key)
+		if !ok {
+			t.Fatalf("Length %d: Open failed", i)
+		}
+		if !bytes.Equal(opened, msg[:i]) {
+			t.Fatalf("Length %d: Open produces unexpected message", i)
+		}
+
+		sealed[i%len(sealed)] ^= 1
+		if _, ok = Open(nil, sealed, &nonce, &key); ok {
+			t.Fatalf("Length %d: Open accepted a modified box", i)
+		}
+	}
+
+	if _, ok := Open(nil, make([]byte, Overhead-1), &nonce, &key); ok {
+		t.Fatal("Open accepted a too short box")
+	}
+}
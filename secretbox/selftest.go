@@ -0,0 +1,54 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package secretbox
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+func init() {
+	crypto.RegisterSelfTest("XSalsa20-Poly1305", selfTest)
+}
+
+// selfTest checks the secretbox against the test vector of
+// "Cryptography in NaCl" (https://cr.yp.to/highspeed/naclcrypto-20090310.pdf).
+func selfTest() error {
+	key, _ := hex.DecodeString("1b27556473e985d462cd51197a9a46c76009549eac6474f206c4ee0844f68389")
+	nonce, _ := hex.DecodeString("69696ee955b62b73cd62bda875fc73d68219e0036b7a0b37")
+	msg, _ := hex.DecodeString("be075fc53c81f2d5cf141316ebeb0c7b5228c52a4c62cbd44b66849b64244ffc" +
+		"e5ecbaaf33bd751a1ac728d45e6c61296cdc3c01233561f41db66cce314adb31" +
+		"0e3be8250c46f06dceea3a7fa1348057e2f6556ad6b1318a024a838f21af1fde" +
+		"048977eb48f59ffd4924ca1c60902e52f0a089bc76897040e082f93776384864" +
+		"5e0705")
+	box, _ := hex.DecodeString("f3ffc7703f9400e52a7dfb4b3d3305d9" +
+		"8e993b9f48681273c29650ba32fc76ce48332ea7164d96a4476fb8c531a1186a" +
+		"c0dfc17c98dce87b4da7f011ec48c97271d2c20f9b928fe2270d6fb863d51738" +
+		"b48eeee314a7cc8ab932164548e526ae90224368517acfeabd6bb3732bc0e9da" +
+		"99832b61ca01b6de56244a9e88d5f9b37973f622a43d14a6599b1f654cb45a74" +
+		"e355a5")
+
+	var k [32]byte
+	var n [24]byte
+	copy(k[:], key)
+	copy(n[:], nonce)
+	if !bytes.Equal(Seal(nil, msg, &n, &k), box) {
+		return errors.New("unexpected box")
+	}
+	plaintext, ok := Open(nil, box, &n, &k)
+	if !ok {
+		return errors.New("authentic box rejected")
+	}
+	if !bytes.Equal(plaintext, msg) {
+		return errors.New("unexpected plaintext")
+	}
+	box[0] ^= 1
+	if _, ok = Open(nil, box, &n, &k); ok {
+		return errors.New("modified box accepted")
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto
+
+import (
+	"errors"
+	"sync"
+)
+
+// ErrNoSelfTests is returned by SelfTest if no self-test is registered -
+// e.g. because no package of this repository is imported.
+var ErrNoSelfTests = errors.New("crypto: no self-tests registered")
+
+var selfTests struct {
+	mu    sync.Mutex
+	tests []selfTest
+}
+
+type selfTest struct {
+	name string
+	test func() error
+}
+
+// RegisterSelfTest registers a known-answer test of a primitive, which
+// is run by SelfTest. The packages of this repository register the
+// tests of their primitives at init. The test should return a non-nil
+// error if the primitive produces a wrong result.
+// If test is nil this function panics.
+func RegisterSelfTest(name string, test func() error) {
+	if test == nil {
+		panic("crypto: self-test function is nil")
+	}
+	selfTests.mu.Lock()
+	defer selfTests.mu.Unlock()
+	selfTests.tests = append(selfTests.tests, selfTest{name: name, test: test})
+}
+
+// SelfTest runs the known-answer tests of all registered primitives
+// (e.g. as power-on self-test) and returns a non-nil error for the
+// first test which fails. Like the DefaultRegistry only the primitives
+// of the imported packages are tested:
+//
+//	import (
+//		_ "github.com/enceve/crypto/blake2/blake2b"             // BLAKE2b
+//		_ "github.com/enceve/crypto/blake2/blake2s"             // BLAKE2s
+//		_ "github.com/enceve/crypto/camellia"                   // Camellia
+//		_ "github.com/enceve/crypto/chacha20"                   // ChaCha20, ChaCha20-Poly1305
+//		_ "github.com/enceve/crypto/chacha20/xchacha20poly1305" // XChaCha20-Poly1305
+//		_ "github.com/enceve/crypto/cipher"                     // AES-EAX, AES-SIV
+//		_ "github.com/enceve/crypto/cmac"                       // AES-CMac
+//		_ "github.com/enceve/crypto/hc128"                      // HC-128
+//		_ "github.com/enceve/crypto/hc256"                      // HC-256
+//		_ "github.com/enceve/crypto/poly1305"                   // Poly1305
+//		_ "github.com/enceve/crypto/secretbox"                  // XSalsa20-Poly1305
+//		_ "github.com/enceve/crypto/serpent"                    // Serpent
+//		_ "github.com/enceve/crypto/siphash"                    // SipHash
+//		_ "github.com/enceve/crypto/skein"                      // Skein-512
+//	)
+//
+// If no test is registered SelfTest returns ErrNoSelfTests.
+//
+// Every test processes only a few blocks, so SelfTest is cheap
+// enough to run at program start.
+func SelfTest() error {
+	selfTests.mu.Lock()
+	tests := selfTests.tests
+	selfTests.mu.Unlock()
+	return runSelfTests(tests)
+}
+
+func runSelfTests(tests []selfTest) error {
+	if len(tests) == 0 {
+		return ErrNoSelfTests
+	}
+	for _, t := range tests {
+		if err := t.test(); err != nil {
+			return errors.New("crypto: self-test " + t.name + " failed: " + err.Error())
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/enceve/crypto"
+	_ "github.com/enceve/crypto/blake2/blake2b"
+	_ "github.com/enceve/crypto/blake2/blake2s"
+	_ "github.com/enceve/crypto/camellia"
+	_ "github.com/enceve/crypto/chacha20"
+	_ "github.com/enceve/crypto/chacha20/xchacha20poly1305"
+	_ "github.com/enceve/crypto/cipher"
+	_ "github.com/enceve/crypto/cmac"
+	_ "github.com/enceve/crypto/hc128"
+	_ "github.com/enceve/crypto/hc256"
+	_ "github.com/enceve/crypto/poly1305"
+	_ "github.com/enceve/crypto/secretbox"
+	_ "github.com/enceve/crypto/serpent"
+	_ "github.com/enceve/crypto/siphash"
+	_ "github.com/enceve/crypto/skein"
+)
+
+func TestSelfTest(t *testing.T) {
+	if err := crypto.SelfTest(); err != nil {
+		t.Fatalf("SelfTest failed: %s", err)
+	}
+
+	registered := make(map[string]bool)
+	for _, name := range crypto.SelfTestNames() {
+		registered[name] = true
+	}
+	for _, name := range []string{
+		"AES-EAX", "AES-SIV", "AES-CMac", "BLAKE2b", "BLAKE2s", "Camellia",
+		"ChaCha20", "ChaCha20-Poly1305", "XChaCha20-Poly1305", "HC-128", "HC-256",
+		"Poly1305", "XSalsa20-Poly1305", "Serpent", "SipHash", "Skein-512",
+	} {
+		if !registered[name] {
+			t.Fatalf("No self-test registered for %s", name)
+		}
+	}
+
+	if err := crypto.RunNoSelfTests(); err != crypto.ErrNoSelfTests {
+		t.Fatalf("SelfTest without tests returned %v - but expected %v", err, crypto.ErrNoSelfTests)
+	}
+
+	err := crypto.RunSelfTest("broken", func() error { return errors.New("wrong result") })
+	if err == nil {
+		t.Fatal("SelfTest did not report a failing test")
+	}
+	if msg := "crypto: self-test broken failed: wrong result"; err.Error() != msg {
+		t.Fatalf("SelfTest returned %q - but expected %q", err, msg)
+	}
+}
+
+func BenchmarkSelfTest(b *testing.B) {
+	for i := 0; i < b.N; i++ {
+		if err := crypto.SelfTest(); err != nil {
+			b.Fatalf("SelfTest failed: %s", err)
+		}
+	}
+}
@@ -0,0 +1,39 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package serpent
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+func init() {
+	crypto.RegisterSelfTest("Serpent", selfTest)
+}
+
+// selfTest checks Serpent-128 against the verified test vectors
+// of the Serpent authors (Serpent-128-128 set 1, vector 0).
+func selfTest() error {
+	key, _ := hex.DecodeString("80000000000000000000000000000000")
+	plaintext, _ := hex.DecodeString("00000000000000000000000000000000")
+	ciphertext, _ := hex.DecodeString("264E5481EFF42A4606ABDA06C0BFDA3D")
+
+	c, err := NewCipher(key)
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, BlockSize)
+	c.Encrypt(buf, plaintext)
+	if !bytes.Equal(buf, ciphertext) {
+		return errors.New("unexpected ciphertext")
+	}
+	c.Decrypt(buf, buf)
+	if !bytes.Equal(buf, plaintext) {
+		return errors.New("unexpected plaintext")
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package siphash
+
+import (
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+func init() {
+	crypto.RegisterSelfTest("SipHash", selfTest)
+}
+
+// selfTest checks SipHash-2-4 against the test vector
+// of the SipHash paper (https://131002.net/siphash/siphash.pdf).
+func selfTest() error {
+	var key [16]byte
+	msg := make([]byte, 15)
+	for i := range key {
+		key[i] = byte(i)
+	}
+	for i := range msg {
+		msg[i] = byte(i)
+	}
+	if Sum64(msg, &key) != 0xa129ca6149be45e5 {
+		return errors.New("unexpected hash")
+	}
+	return nil
+}
@@ -63,7 +63,7 @@ func (h *hashFunc) Write(p []byte) (int, error) {
 
 	if h.off > 0 {
 		dif := TagSize - h.off
-		if n > dif {
+		if n >= dif {
 			h.off += copy(h.block[h.off:], p[:dif])
 			p = p[dif:]
 			core(&(h.hVal), h.block[:])
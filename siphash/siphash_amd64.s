@@ -1,6 +1,7 @@
 // Use of this source code is governed by a license
 // that can be found in the LICENSE file.
-// +build amd64,!appengine,!gccgo
+
+// +build amd64,!gccgo,!appengine
 
 #define ROUND(v0, v1, v2, v3) \
     ADDQ v1, v0; \
@@ -0,0 +1,32 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package skein
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+
+	"github.com/enceve/crypto"
+)
+
+func init() {
+	crypto.RegisterSelfTest("Skein-512", selfTest)
+}
+
+// selfTest checks Skein-512-512 against a
+// test vector of one 64 byte message block.
+func selfTest() error {
+	msg, _ := hex.DecodeString("FBD17C26B61A82E12E125F0D459B96C91AB4837DFF22B39B78439430CDFC5DC8" +
+		"78BB393A1A5F79BEF30995A85A12923339BA8AB7D8FC6DC5FEC6F4ED22C122BB")
+	hash, _ := hex.DecodeString("02D01535C2DF280FDE92146DF054B0609273C73056C93B94B82F5E7DCC5BE697" +
+		"9978C4BE24331CAA85D892D2E710C6C9B4904CD056A53547B866BEE097C0FB17")
+
+	var sum [64]byte
+	Sum512(&sum, msg, nil)
+	if !bytes.Equal(sum[:], hash) {
+		return errors.New("unexpected hash")
+	}
+	return nil
+}
@@ -14,7 +14,6 @@ type hashFunc struct {
 	tweak         [3]uint64
 	block         [threefish.BlockSize1024]byte
 	off           int
-	hasMsg        bool
 }
 
 func (s *hashFunc) BlockSize() int { return threefish.BlockSize1024 }
@@ -26,7 +25,6 @@ func (s *hashFunc) Reset() {
 		s.block[i] = 0
 	}
 	s.off = 0
-	s.hasMsg = false
 
 	s.hVal = s.hValCpy
 
@@ -35,8 +33,6 @@ func (s *hashFunc) Reset() {
 }
 
 func (s *hashFunc) Write(p []byte) (n int, err error) {
-	s.hasMsg = true
-
 	n = len(p)
 	var block [16]uint64
 
@@ -71,10 +67,9 @@ func (s *hashFunc) Write(p []byte) (n int, err error) {
 
 func (s *hashFunc) Sum(b []byte) []byte {
 	s0 := *s // copy
+	n := len(b)
 
-	if s0.hasMsg {
-		s0.finalizeHash()
-	}
+	s0.finalizeHash()
 
 	var out [threefish.BlockSize1024]byte
 	var ctr uint64
@@ -84,7 +79,7 @@ func (s *hashFunc) Sum(b []byte) []byte {
 		b = append(b, out[:]...)
 	}
 
-	return b[:s0.hashsize]
+	return b[:n+s0.hashsize]
 }
 
 func (s *hashFunc) update(block *[16]uint64) {
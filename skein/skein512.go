@@ -13,7 +13,6 @@ type hashFunc struct {
 	tweak         [3]uint64
 	block         [BlockSize]byte
 	off           int
-	hasMsg        bool
 }
 
 func (s *hashFunc) BlockSize() int { return BlockSize }
@@ -25,7 +24,6 @@ func (s *hashFunc) Reset() {
 		s.block[i] = 0
 	}
 	s.off = 0
-	s.hasMsg = false
 
 	s.hVal = s.hValCpy
 
@@ -34,8 +32,6 @@ func (s *hashFunc) Reset() {
 }
 
 func (s *hashFunc) Write(p []byte) (n int, err error) {
-	s.hasMsg = true
-
 	n = len(p)
 	var block [8]uint64
 
@@ -70,10 +66,9 @@ func (s *hashFunc) Write(p []byte) (n int, err error) {
 
 func (s *hashFunc) Sum(b []byte) []byte {
 	s0 := *s // copy
+	n := len(b)
 
-	if s0.hasMsg {
-		s0.finalizeHash()
-	}
+	s0.finalizeHash()
 
 	var out [BlockSize]byte
 	var ctr uint64
@@ -82,7 +77,7 @@ func (s *hashFunc) Sum(b []byte) []byte {
 		ctr++
 		b = append(b, out[:]...)
 	}
-	return b[:s0.hashsize]
+	return b[:n+s0.hashsize]
 }
 
 func (s *hashFunc) update(block *[8]uint64) {
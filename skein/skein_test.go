@@ -155,3 +155,17 @@ func benchmarkWrite(b *testing.B, size int) {
 
 func BenchmarkWrite_64(b *testing.B) { benchmarkWrite(b, 64) }
 func BenchmarkWrite_1K(b *testing.B) { benchmarkWrite(b, 1024) }
+
+func TestSumEmpty(t *testing.T) {
+	// Skein-512-512 of the empty message
+	const empty = "bc5b4c50925519c290cc634277ae3d6257212395cba733bbad37a4af0fa06af41fca7903d06564fea7a2d3730dbdb80c1f85562dfcc070334ea4d1d9e72cba7a"
+
+	h := New512(nil)
+	if sum := hex.EncodeToString(h.Sum(nil)); sum != empty {
+		t.Fatalf("Sum without Write returned %s - but expected %s", sum, empty)
+	}
+	prefix := []byte("prefix")
+	if sum := h.Sum(prefix); !bytes.Equal(sum[:len(prefix)], prefix) || hex.EncodeToString(sum[len(prefix):]) != empty {
+		t.Fatalf("Sum does not append to its argument: %x", sum)
+	}
+}
@@ -0,0 +1,248 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto
+
+import (
+	"crypto/cipher"
+	"encoding/binary"
+	"errors"
+	"io"
+)
+
+const (
+	streamVersion       = 1
+	streamHeaderSize    = 5
+	streamRecordSize    = 64 * 1024
+	maxStreamRecordSize = 16 * 1024 * 1024
+)
+
+var (
+	errStreamHeader    = errors.New("invalid stream header")
+	errStreamClosed    = errors.New("stream is closed")
+	errStreamExhausted = errors.New("too many records for one stream")
+)
+
+// EncryptStream returns an io.WriteCloser encrypting everything written to
+// it with the AEAD and writing the result to w. The plaintext is split into
+// records of 64 KB and every record is sealed with its own nonce derived
+// from the base nonce, the index of the record and a flag marking the last
+// record (like the STREAM construction):
+//
+//	nonce = base nonce || index (32 bit big-endian) || last flag (1 byte)
+//
+// The stream starts with a header containing a version byte and the record
+// size (32 bit big-endian). The header and the additional data are
+// authenticated as part of every record. The last record is written by
+// Close, so Close must be called to complete the stream - a stream without
+// the last record is rejected by DecryptStream.
+// The base nonce must be 5 bytes shorter than the nonce of the AEAD and must
+// be unique for one key for all time. This function returns a non-nil
+// error if the size of the base nonce is invalid or the header cannot
+// be written.
+func EncryptStream(w io.Writer, aead cipher.AEAD, baseNonce, additionalData []byte) (io.WriteCloser, error) {
+	s, err := newStreamState(aead, baseNonce)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, streamHeaderSize)
+	header[0] = streamVersion
+	binary.BigEndian.PutUint32(header[1:], streamRecordSize)
+	if _, err = w.Write(header); err != nil {
+		return nil, err
+	}
+	s.setAdditionalData(header, additionalData)
+
+	return &streamWriter{
+		w:     w,
+		state: s,
+		buf:   make([]byte, 0, streamRecordSize+aead.Overhead()),
+	}, nil
+}
+
+// DecryptStream returns an io.Reader decrypting a stream created by
+// EncryptStream from r. The AEAD, the base nonce and the additional data
+// must be the same as used for EncryptStream. Read returns the plaintext
+// of a record only after the record is authenticated and returns io.EOF
+// only after the last record. If the stream was modified, reordered or
+// truncated Read returns an AuthenticationError.
+// This function returns a non-nil error if the size of the base nonce
+// is invalid or the header cannot be read.
+func DecryptStream(r io.Reader, aead cipher.AEAD, baseNonce, additionalData []byte) (io.Reader, error) {
+	s, err := newStreamState(aead, baseNonce)
+	if err != nil {
+		return nil, err
+	}
+	header := make([]byte, streamHeaderSize)
+	if _, err = io.ReadFull(r, header); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if header[0] != streamVersion || size == 0 || size > maxStreamRecordSize {
+		return nil, errStreamHeader
+	}
+	s.setAdditionalData(header, additionalData)
+
+	return &streamReader{
+		r:     r,
+		state: s,
+		buf:   make([]byte, int(size)+aead.Overhead()+1),
+		out:   make([]byte, 0, size),
+	}, nil
+}
+
+// streamState holds the AEAD and the nonce of a stream.
+type streamState struct {
+	aead           cipher.AEAD
+	nonce          []byte
+	additionalData []byte
+	index          uint64
+}
+
+func newStreamState(aead cipher.AEAD, baseNonce []byte) (*streamState, error) {
+	n := aead.NonceSize() - 5
+	if n < 0 {
+		return nil, errors.New("nonce size of the AEAD must be at least 5 bytes")
+	}
+	if len(baseNonce) != n {
+		return nil, errors.New("base nonce must be 5 bytes shorter than the AEAD nonce")
+	}
+	s := &streamState{
+		aead:  aead,
+		nonce: make([]byte, aead.NonceSize()),
+	}
+	copy(s.nonce, baseNonce)
+	return s, nil
+}
+
+func (s *streamState) setAdditionalData(header, additionalData []byte) {
+	s.additionalData = make([]byte, len(header)+len(additionalData))
+	copy(s.additionalData, header)
+	copy(s.additionalData[len(header):], additionalData)
+}
+
+// next returns the nonce of the next record and increments the index.
+func (s *streamState) next(last bool) ([]byte, error) {
+	if s.index > 0xffffffff {
+		return nil, errStreamExhausted
+	}
+	n := len(s.nonce)
+	binary.BigEndian.PutUint32(s.nonce[n-5:], uint32(s.index))
+	s.nonce[n-1] = 0
+	if last {
+		s.nonce[n-1] = 1
+	}
+	s.index++
+	return s.nonce, nil
+}
+
+// streamWriter implements io.WriteCloser for EncryptStream.
+type streamWriter struct {
+	w      io.Writer
+	state  *streamState
+	buf    []byte
+	closed bool
+}
+
+func (w *streamWriter) Write(p []byte) (int, error) {
+	if w.closed {
+		return 0, errStreamClosed
+	}
+	written := 0
+	for len(p) > 0 {
+		// a full record is only sealed if more data follows,
+		// because the last record is sealed by Close
+		if len(w.buf) == streamRecordSize {
+			if err := w.seal(false); err != nil {
+				return written, err
+			}
+		}
+		n := copy(w.buf[len(w.buf):streamRecordSize], p)
+		w.buf = w.buf[:len(w.buf)+n]
+		p = p[n:]
+		written += n
+	}
+	return written, nil
+}
+
+// Close seals and writes the last record. Close does not close
+// the underlying io.Writer.
+func (w *streamWriter) Close() error {
+	if w.closed {
+		return errStreamClosed
+	}
+	w.closed = true
+	return w.seal(true)
+}
+
+func (w *streamWriter) seal(last bool) error {
+	nonce, err := w.state.next(last)
+	if err != nil {
+		return err
+	}
+	record := w.state.aead.Seal(w.buf[:0], nonce, w.buf, w.state.additionalData)
+	w.buf = w.buf[:0]
+	_, err = w.w.Write(record)
+	return err
+}
+
+// streamReader implements io.Reader for DecryptStream.
+type streamReader struct {
+	r     io.Reader
+	state *streamState
+	err   error
+
+	// buf holds one record followed by the first byte of
+	// the next record, which is used to detect the last record.
+	buf       []byte
+	buffered  int
+	out       []byte
+	plaintext []byte
+}
+
+func (r *streamReader) Read(p []byte) (int, error) {
+	for len(r.plaintext) == 0 {
+		if r.err != nil {
+			return 0, r.err
+		}
+		r.err = r.open()
+	}
+	n := copy(p, r.plaintext)
+	r.plaintext = r.plaintext[n:]
+	return n, nil
+}
+
+// open reads and authenticates the next record.
+func (r *streamReader) open() error {
+	n, err := io.ReadFull(r.r, r.buf[r.buffered:])
+	n += r.buffered
+	last := false
+	switch err {
+	case nil:
+	case io.EOF, io.ErrUnexpectedEOF:
+		last = true
+	default:
+		return err
+	}
+
+	record := r.buf[:n]
+	if !last {
+		record = r.buf[:n-1]
+	}
+	nonce, err := r.state.next(last)
+	if err != nil {
+		return err
+	}
+	r.plaintext, err = r.state.aead.Open(r.out[:0], nonce, record, r.state.additionalData)
+	if err != nil {
+		return AuthenticationError{Algorithm: "Stream"}
+	}
+	if last {
+		return io.EOF
+	}
+	r.buf[0], r.buffered = r.buf[n-1], 1
+	return nil
+}
@@ -0,0 +1,169 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto_test
+
+import (
+	"bytes"
+	"crypto/aes"
+	gocipher "crypto/cipher"
+	"crypto/sha256"
+	"io"
+	"math/rand"
+	"testing"
+
+	"github.com/enceve/crypto"
+	"github.com/enceve/crypto/chacha20"
+	"github.com/enceve/crypto/cipher"
+)
+
+func newStreamAEADs(t *testing.T) map[string]gocipher.AEAD {
+	block, err := aes.NewCipher(make([]byte, 16))
+	if err != nil {
+		t.Fatalf("Failed to create AES-128 instance: %s", err)
+	}
+	eax, err := cipher.NewEAX(block, block.BlockSize())
+	if err != nil {
+		t.Fatalf("Failed to create AES-128-EAX instance: %s", err)
+	}
+	var key [32]byte
+	return map[string]gocipher.AEAD{
+		"EAX":              eax,
+		"ChaCha20Poly1305": chacha20.NewChaCha20Poly1305(&key),
+	}
+}
+
+func encryptStream(t *testing.T, aead gocipher.AEAD, plaintext, data []byte) []byte {
+	var buf bytes.Buffer
+	w, err := crypto.EncryptStream(&buf, aead, make([]byte, aead.NonceSize()-5), data)
+	if err != nil {
+		t.Fatalf("EncryptStream failed: %s", err)
+	}
+	if _, err = w.Write(plaintext); err != nil {
+		t.Fatalf("Write failed: %s", err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatalf("Close failed: %s", err)
+	}
+	return buf.Bytes()
+}
+
+func decryptStream(aead gocipher.AEAD, ciphertext, data []byte) ([]byte, error) {
+	r, err := crypto.DecryptStream(bytes.NewReader(ciphertext), aead, make([]byte, aead.NonceSize()-5), data)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	_, err = io.Copy(&buf, r)
+	return buf.Bytes(), err
+}
+
+func TestStream(t *testing.T) {
+	data := []byte("data")
+	for name, aead := range newStreamAEADs(t) {
+		for _, size := range []int{0, 1, 64*1024 - 1, 64 * 1024, 64*1024 + 1, 3 * 64 * 1024} {
+			plaintext := make([]byte, size)
+			for i := range plaintext {
+				plaintext[i] = byte(i)
+			}
+			ciphertext := encryptStream(t, aead, plaintext, data)
+			decrypted, err := decryptStream(aead, ciphertext, data)
+			if err != nil {
+				t.Fatalf("%s - %d bytes: DecryptStream failed: %s", name, size, err)
+			}
+			if !bytes.Equal(decrypted, plaintext) {
+				t.Fatalf("%s - %d bytes: DecryptStream returned a wrong plaintext", name, size)
+			}
+
+			if _, err = decryptStream(aead, ciphertext, []byte("other")); err == nil {
+				t.Fatalf("%s - %d bytes: DecryptStream accepted wrong additional data", name, size)
+			}
+		}
+	}
+}
+
+func TestStreamTruncation(t *testing.T) {
+	for name, aead := range newStreamAEADs(t) {
+		record := 64*1024 + aead.Overhead()
+		ciphertext := encryptStream(t, aead, make([]byte, 3*64*1024), nil)
+
+		// cut at record boundaries and within the records
+		for _, n := range []int{5, 5 + record, 5 + 2*record, 5 + 2*record + 100, len(ciphertext) - 1} {
+			if _, err := decryptStream(aead, ciphertext[:n], nil); err == nil {
+				t.Fatalf("%s: DecryptStream accepted a stream truncated to %d bytes", name, n)
+			}
+		}
+		if _, err := decryptStream(aead, ciphertext[:4], nil); err == nil {
+			t.Fatalf("%s: DecryptStream accepted a truncated header", name)
+		}
+
+		// swap the first two records
+		swapped := append([]byte{}, ciphertext...)
+		copy(swapped[5:], ciphertext[5+record:5+2*record])
+		copy(swapped[5+record:], ciphertext[5:5+record])
+		if _, err := decryptStream(aead, swapped, nil); err != (crypto.AuthenticationError{Algorithm: "Stream"}) {
+			t.Fatalf("%s: DecryptStream accepted reordered records", name)
+		}
+
+		// modify the header
+		modified := append([]byte{}, ciphertext...)
+		modified[4] ^= 1
+		if _, err := decryptStream(aead, modified, nil); err == nil {
+			t.Fatalf("%s: DecryptStream accepted a modified header", name)
+		}
+	}
+}
+
+func TestStreamPipe(t *testing.T) {
+	size := 200 * 1024 * 1024
+	if testing.Short() {
+		size = 2 * 1024 * 1024
+	}
+	for name, aead := range newStreamAEADs(t) {
+		rng := rand.New(rand.NewSource(int64(size)))
+		chunk := make([]byte, 100*1024)
+		rng.Read(chunk)
+
+		pr, pw := io.Pipe()
+		defer pr.Close()
+		sum := make(chan []byte, 1)
+		go func() {
+			h := sha256.New()
+			w, err := crypto.EncryptStream(pw, aead, make([]byte, aead.NonceSize()-5), nil)
+			if err != nil {
+				pw.CloseWithError(err)
+				return
+			}
+			for written := 0; written < size; {
+				n := rng.Intn(len(chunk)) + 1
+				if n > size-written {
+					n = size - written
+				}
+				h.Write(chunk[:n])
+				if _, err = w.Write(chunk[:n]); err != nil {
+					pw.CloseWithError(err)
+					return
+				}
+				written += n
+			}
+			sum <- h.Sum(nil)
+			pw.CloseWithError(w.Close())
+		}()
+
+		r, err := crypto.DecryptStream(pr, aead, make([]byte, aead.NonceSize()-5), nil)
+		if err != nil {
+			t.Fatalf("%s: DecryptStream failed: %s", name, err)
+		}
+		h := sha256.New()
+		n, err := io.Copy(h, r)
+		if err != nil {
+			t.Fatalf("%s: Read failed after %d bytes: %s", name, n, err)
+		}
+		if n != int64(size) {
+			t.Fatalf("%s: DecryptStream returned %d bytes - but expected %d", name, n, size)
+		}
+		if !bytes.Equal(h.Sum(nil), <-sum) {
+			t.Fatalf("%s: DecryptStream returned a wrong plaintext", name)
+		}
+	}
+}
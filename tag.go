@@ -0,0 +1,16 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto
+
+// SplitTag splits the ciphertext into the body and the trailing tag of
+// tagsize bytes. Both slices share the memory of the ciphertext.
+// If the ciphertext is shorter than tagsize or tagsize is negative
+// SplitTag returns false.
+func SplitTag(ciphertext []byte, tagsize int) (body, tag []byte, ok bool) {
+	if tagsize < 0 || len(ciphertext) < tagsize {
+		return nil, nil, false
+	}
+	n := len(ciphertext) - tagsize
+	return ciphertext[:n:n], ciphertext[n:], true
+}
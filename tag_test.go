@@ -0,0 +1,41 @@
+// Use of this source code is governed by a license
+// that can be found in the LICENSE file.
+
+package crypto_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/enceve/crypto"
+)
+
+func TestSplitTag(t *testing.T) {
+	ciphertext := []byte("0123456789abcdefg")
+	for _, v := range []struct {
+		length, tagsize int
+		ok              bool
+	}{
+		{0, 0, true},
+		{0, 1, false},
+		{15, 16, false},
+		{16, 16, true},
+		{17, 16, true},
+		{16, 8, true},
+		{16, -1, false},
+	} {
+		body, tag, ok := crypto.SplitTag(ciphertext[:v.length], v.tagsize)
+		if ok != v.ok {
+			t.Fatalf("Length %d, tag size %d: SplitTag returned %v - but expected %v", v.length, v.tagsize, ok, v.ok)
+		}
+		if !ok {
+			if body != nil || tag != nil {
+				t.Fatalf("Length %d, tag size %d: SplitTag returned non-nil slices", v.length, v.tagsize)
+			}
+			continue
+		}
+		if len(tag) != v.tagsize || !bytes.Equal(append(body, tag...), ciphertext[:v.length]) {
+			t.Fatalf("Length %d, tag size %d: SplitTag returned %q, %q", v.length, v.tagsize, body, tag)
+		}
+	}
+}
@@ -1,7 +1,7 @@
 // Use of this source code is governed by a license
 // that can be found in the LICENSE file.
 
-// +build amd64, !cgo, !appengine
+// +build amd64,!gccgo,!appengine
 
 package crypto
 